@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"github.com/redlon23/go-binance/models"
 	"log"
+	"math"
 	"os"
+	"strconv"
+	"strings"
 )
 
 func GetKey(data []byte) string {
@@ -39,3 +42,30 @@ func CheckLogsFolder() {
 		fmt.Println("There is already a logs folder in the current directory!")
 	}
 }
+
+// RoundDownToStep rounds value down to a multiple of step, used with tick size and step size filters.
+// Result is cleaned from float noise, 0.1+0.2 style leftovers break binance precision checks.
+func RoundDownToStep(value, step float64) float64 {
+	if step <= 0 {
+		return value
+	}
+	return roundToPrecision(math.Floor(value/step+1e-9)*step, step)
+}
+
+// RoundUpToStep rounds value up to a multiple of step.
+func RoundUpToStep(value, step float64) float64 {
+	if step <= 0 {
+		return value
+	}
+	return roundToPrecision(math.Ceil(value/step-1e-9)*step, step)
+}
+
+func roundToPrecision(value, step float64) float64 {
+	precision := 0
+	formatted := strconv.FormatFloat(step, 'f', -1, 64)
+	if dot := strings.IndexByte(formatted, '.'); dot >= 0 {
+		precision = len(formatted) - dot - 1
+	}
+	scale := math.Pow(10, float64(precision))
+	return math.Round(value*scale) / scale
+}
@@ -25,6 +25,11 @@ func(ba *BinanceAccess) UseTestNet() {
 	ba.WebSocket.UseTestNet()
 }
 
+// SetOrderMode config switch for order placement, see OrderModeLive, OrderModeTest and OrderModeDryRun
+func (ba *BinanceAccess) SetOrderMode(mode string) error {
+	return ba.Api.SetOrderMode(mode)
+}
+
 func (ba *BinanceAccess) PrepareLoggers() {
 	// Check log folder and create if it doesn't exists
 	CheckLogsFolder()
@@ -46,17 +51,18 @@ func(ba *BinanceAccess) PrepareAccess() {
 }
 
 // Uses http2 client then opens a websocket connection
-// Handles listen key generation and enables user stream
+// Handles listen key generation and enables user stream, the socket is wrapped in a
+// UserStreamManager which keeps the listen key alive and renews it when it expires
 func(ba *BinanceAccess) PrepareAccessWithUserStream() {
 	fmt.Println("HTTP/2.0 Client Preparing...")
 	ba.Api.NewNetClientHTTP2()
 	fmt.Println("Websocket Connection (User Stream) opening...")
-	data, err := ba.Api.GetUserStreamKey()
-	if err != nil {
-		log.Fatal(err)
+	manager, managed := ba.WebSocket.(*UserStreamManager)
+	if !managed {
+		manager = NewUserStreamManager(ba.Api, ba.WebSocket)
+		ba.WebSocket = manager
 	}
-	listenKey := GetKey(data)
-	err = ba.WebSocket.OpenWebSocketConnectionWithUserStream(listenKey)
+	err := manager.Start()
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -66,31 +72,28 @@ func(ba *BinanceAccess) PrepareAccessWithUserStream() {
 
 func (ba *BinanceAccess) TestUserStream(positionChannel, orderChannel, priceChannel chan []byte) {
 	_ = ba.WebSocket.SubscribeSymbolTickerStream("BTCUSDT")
+	dispatcher := NewEventDispatcher()
+	// Only the latest price matters, a slow reader of priceChannel does not stall the connection
+	prices := NewConflatingQueue(MessageKey)
+	dispatcher.On(models.EventSymbolTicker, Deliver(prices, func(message []byte) { priceChannel <- message }))
+	// Order: new, canceled, expired
+	orders, err := NewDeliveryQueue[[]byte](DeliveryBlocking, DefaultDeliveryQueueSize)
+	if err != nil {
+		log.Fatal(err)
+	}
+	dispatcher.On(models.EventOrder, Deliver(orders, func(message []byte) { orderChannel <- message }))
+	// Account: balance, position, funding, adjustment, transfers...
+	// meta.Reason.MessageType tells which one, PositionBook applies all of them
+	accounts, err := NewDeliveryQueue[[]byte](DeliveryBlocking, DefaultDeliveryQueueSize)
+	if err != nil {
+		log.Fatal(err)
+	}
+	dispatcher.On(models.EventAccount, Deliver(accounts, func(message []byte) { positionChannel <- message }))
+	dispatcher.OnUnknown(func(event string, message []byte) { fmt.Println(string(message)) })
+	dispatcher.OnDecodeError(func(err *DecodeError) { log.Println(err) })
 	for {
-		_, message, err := ba.WebSocket.ReadFromConnection()
-		if err != nil {
+		if err := dispatcher.Run(ba.WebSocket); err != nil {
 			log.Println("Error occurred while reading message from the connection", err)
-		} else {
-			fmt.Println(string(message))
-
-			// Unmarshal Meta Information
-			meta, err := ParseMetaInformation(message)
-			if err != nil {
-				log.Println(err)
-				log.Println(meta)
-			}
-
-			if meta.Event == models.EventSymbolTicker {
-				priceChannel <- message
-			} else if meta.Event == models.EventOrder {
-				// Order: new, canceled, expired
-				orderChannel <- message
-			} else if meta.Event == models.EventAccount &&
-				meta.Reason.MessageType == models.ReasonOrder{
-				// Account: balance, position, funding, adjustment (look below)
-				// !!! -> Only order related messages are accepted <- !!!
-				positionChannel <- message
-			}
 		}
 	}
-}
\ No newline at end of file
+}
@@ -32,6 +32,17 @@ const (
 	accountInformationEndpointCoin    = "/dapi/v1/account"
 	positionInformationCoin           = "/dapi/v1/positionRisk"
 	tradeListCoin                     = "/dapi/v1/userTrades"
+	openOrdersEndpointCoin            = "/dapi/v1/openOrders"
+	countdownCancelAllEndpointCoin    = "/dapi/v1/countdownCancelAll"
+	incomeHistoryEndpointCoin         = "/dapi/v1/income"
+	commissionRateEndpointCoin        = "/dapi/v1/commissionRate"
+	adlQuantileEndpointCoin           = "/dapi/v1/adlQuantile"
+	incomeDownloadIdEndpointCoin      = "/dapi/v1/income/asyn"
+	incomeDownloadLinkEndpointCoin    = "/dapi/v1/income/asyn/id"
+	orderDownloadIdEndpointCoin       = "/dapi/v1/order/asyn"
+	orderDownloadLinkEndpointCoin     = "/dapi/v1/order/asyn/id"
+	tradeDownloadIdEndpointCoin       = "/dapi/v1/trade/asyn"
+	tradeDownloadLinkEndpointCoin     = "/dapi/v1/trade/asyn/id"
 )
 
 type BinanceCoinFuturesApi struct {
@@ -40,6 +51,23 @@ type BinanceCoinFuturesApi struct {
 	PublicKey string
 	SecretKey string
 	Logger    *logrus.Logger
+	// OrderMode one of OrderModeLive, OrderModeTest or OrderModeDryRun
+	OrderMode string
+	// ClientOrderIds every order gets a generated newClientOrderId and placements with unknown
+	// status are looked up before they are sent again. The constructor and the net clients install
+	// one tagged DefaultClientOrderIdTag when it is nil, set it to nil afterwards to opt out
+	ClientOrderIds *ClientOrderIdGenerator
+	// Filters optional, orders of test and dry run modes are normalized and validated
+	// against the filters of their symbol, see LoadSymbolFilters
+	Filters map[string]SymbolFilters
+}
+
+func NewBinanceCoinFuturesApi() *BinanceCoinFuturesApi {
+	return &BinanceCoinFuturesApi{
+		BaseUrl:        mainNetBaseURLCoin,
+		Logger:         logrus.New(),
+		ClientOrderIds: NewClientOrderIdGenerator(DefaultClientOrderIdTag),
+	}
 }
 
 func (bcfa *BinanceCoinFuturesApi) PrepareLoggers() {
@@ -73,6 +101,7 @@ func (bcfa *BinanceCoinFuturesApi) NewNetClient() {
 		Timeout:   0,
 		Transport: netTransport,
 	}
+	bcfa.defaultClientOrderIds()
 }
 
 func (bcfa *BinanceCoinFuturesApi) NewNetClientHTTP2() {
@@ -81,6 +110,29 @@ func (bcfa *BinanceCoinFuturesApi) NewNetClientHTTP2() {
 		Timeout:   0,
 		Transport: netTransport,
 	}
+	bcfa.defaultClientOrderIds()
+}
+
+func (bcfa *BinanceCoinFuturesApi) defaultClientOrderIds() {
+	if bcfa.ClientOrderIds == nil {
+		bcfa.ClientOrderIds = NewClientOrderIdGenerator(DefaultClientOrderIdTag)
+	}
+}
+
+// SetOrderMode switches every order call between live and local dry run.
+// Coin-M has no test order endpoint, OrderModeTest behaves like OrderModeDryRun.
+// Unknown modes are rejected and the current mode is kept.
+func (bcfa *BinanceCoinFuturesApi) SetOrderMode(mode string) error {
+	if err := ValidateOrderMode(mode); err != nil {
+		return err
+	}
+	bcfa.OrderMode = mode
+	return nil
+}
+
+// isLive order, cancel and countdown calls reach the exchange
+func (bcfa BinanceCoinFuturesApi) isLive() bool {
+	return isLiveOrderMode(bcfa.OrderMode)
 }
 
 func (bcfa *BinanceCoinFuturesApi) UseMainNet() {
@@ -145,7 +197,8 @@ func (bcfa BinanceCoinFuturesApi) doPublicRequest(httpVerb, endPoint string, par
 		bem := new(BinanceErrorMessage)
 		err = json.Unmarshal(data, &bem)
 		if err != nil {
-			bem = nil
+			// Body is not a binance error, e.g. html of a gateway error
+			bem = &BinanceErrorMessage{Message: string(data)}
 		}
 		err = &RequestError{
 			StatusCode: response.StatusCode,
@@ -188,7 +241,8 @@ func (bcfa BinanceCoinFuturesApi) doSignedRequest(httpVerb, endPoint string, par
 		bem := new(BinanceErrorMessage)
 		err = json.Unmarshal(data, &bem)
 		if err != nil {
-			bem = nil
+			// Body is not a binance error, e.g. html of a gateway error
+			bem = &BinanceErrorMessage{Message: string(data)}
 		}
 		err = &RequestError{
 			StatusCode: response.StatusCode,
@@ -228,6 +282,32 @@ func (bcfa BinanceCoinFuturesApi) GetKlines(symbol, interval string, limit int)
 	return bcfa.doPublicRequest("GET", klinesEndpointCoin, parameters)
 }
 
+// Sends order parameters according to OrderMode, dry run signs the request without sending it.
+func (bcfa BinanceCoinFuturesApi) placeOrder(parameters url.Values) ([]byte, error) {
+	if bcfa.ClientOrderIds != nil && parameters.Get("newClientOrderId") == "" {
+		parameters.Set("newClientOrderId", bcfa.ClientOrderIds.Next())
+	}
+	if !bcfa.isLive() {
+		if err := applySymbolFilters(bcfa.Filters, parameters); err != nil {
+			bcfa.Logger.Warn("order rejected by symbol filters ", parameters.Encode(), " ", err)
+			return nil, err
+		}
+		signature := bcfa.signParameters(&parameters)
+		bcfa.Logger.Info("dry run order ", parameters.Encode(), " signature ", signature)
+		return syntheticOrderResponse(parameters)
+	}
+	if bcfa.ClientOrderIds == nil {
+		return bcfa.doSignedRequest("POST", orderEndPointCoin, parameters)
+	}
+	send := func(parameters url.Values) ([]byte, error) {
+		return bcfa.doSignedRequest("POST", orderEndPointCoin, parameters)
+	}
+	query := func(symbol, clientOrderId string) ([]byte, error) {
+		return bcfa.QueryOrder(symbol, clientOrderId, 0)
+	}
+	return placeIdempotent(bcfa.Logger, parameters, send, query)
+}
+
 // ======================= SIGNED API CALLS ================================
 func (bcfa BinanceCoinFuturesApi) GetUserStreamKey() ([]byte, error) {
 	return bcfa.doSignedRequest("POST", listenKeyEndPointCoin, url.Values{})
@@ -254,7 +334,7 @@ func (bcfa BinanceCoinFuturesApi) PlaceLimitOrder(symbol, side string, price, qt
 	parameters.Add("reduceOnly", strconv.FormatBool(reduceOnly))
 	parameters.Add("quantity", strconv.FormatFloat(qty, 'f', -1, 64))
 	parameters.Add("price", strconv.FormatFloat(price, 'f', -1, 64))
-	return bcfa.doSignedRequest("POST", orderEndPointCoin, parameters)
+	return bcfa.placeOrder(parameters)
 }
 
 func (bcfa BinanceCoinFuturesApi) PlacePostOnlyLimitOrder(symbol, side string, price, qty float64, reduceOnly bool) ([]byte, error) {
@@ -266,7 +346,7 @@ func (bcfa BinanceCoinFuturesApi) PlacePostOnlyLimitOrder(symbol, side string, p
 	parameters.Add("reduceOnly", strconv.FormatBool(reduceOnly))
 	parameters.Add("quantity", strconv.FormatFloat(qty, 'f', -1, 64))
 	parameters.Add("price", strconv.FormatFloat(price, 'f', -1, 64))
-	return bcfa.doSignedRequest("POST", orderEndPointCoin, parameters)
+	return bcfa.placeOrder(parameters)
 }
 
 func (bcfa BinanceCoinFuturesApi) PlaceMarketOrder(symbol, side string, qty float64, reduceOnly bool) ([]byte, error) {
@@ -276,7 +356,19 @@ func (bcfa BinanceCoinFuturesApi) PlaceMarketOrder(symbol, side string, qty floa
 	parameters.Add("type", OrderTypeMarket)
 	parameters.Add("reduceOnly", strconv.FormatBool(reduceOnly))
 	parameters.Add("quantity", strconv.FormatFloat(qty, 'f', -1, 64))
-	return bcfa.doSignedRequest("POST", orderEndPointCoin, parameters)
+	return bcfa.placeOrder(parameters)
+}
+
+// PlaceHedgeMarketOrder positionSide is LONG or SHORT of a hedge mode account, reduceOnly can not be sent there.
+// SELL with LONG or BUY with SHORT closes the position.
+func (bcfa BinanceCoinFuturesApi) PlaceHedgeMarketOrder(symbol, side, positionSide string, qty float64) ([]byte, error) {
+	parameters := url.Values{}
+	parameters.Add("symbol", symbol)
+	parameters.Add("side", side)
+	parameters.Add("positionSide", positionSide)
+	parameters.Add("type", OrderTypeMarket)
+	parameters.Add("quantity", strconv.FormatFloat(qty, 'f', -1, 64))
+	return bcfa.placeOrder(parameters)
 }
 
 // PlaceStopMarketOrder Generally used for trailing profit orders.
@@ -293,10 +385,53 @@ func (bcfa BinanceCoinFuturesApi) PlaceStopMarketOrder(symbol, side string, stop
 	parameters.Add("quantity", strconv.FormatFloat(qty, 'f', -1, 64))
 	parameters.Add("stopPrice", strconv.FormatFloat(stopPrice, 'f', -1, 64))
 
-	return bcfa.doSignedRequest("POST", orderEndPointCoin, parameters)
+	return bcfa.placeOrder(parameters)
+}
+
+// PlaceTakeProfitMarketOrder reduce only, use the opposite side of your position.
+func (bcfa BinanceCoinFuturesApi) PlaceTakeProfitMarketOrder(symbol, side string, stopPrice, qty float64) ([]byte, error) {
+	parameters := url.Values{}
+	parameters.Add("symbol", symbol)
+	parameters.Add("side", side)
+	parameters.Add("type", OrderTypeTakeProfitMarket)
+	parameters.Add("reduceOnly", "true")
+	parameters.Add("quantity", strconv.FormatFloat(qty, 'f', -1, 64))
+	parameters.Add("stopPrice", strconv.FormatFloat(stopPrice, 'f', -1, 64))
+	return bcfa.placeOrder(parameters)
+}
+
+// PlaceClosePositionOrder orderType is either STOP_MARKET or TAKE_PROFIT_MARKET.
+// Closes the whole position when triggered, no quantity is sent.
+func (bcfa BinanceCoinFuturesApi) PlaceClosePositionOrder(symbol, side, orderType string, stopPrice float64) ([]byte, error) {
+	parameters := url.Values{}
+	parameters.Add("symbol", symbol)
+	parameters.Add("side", side)
+	parameters.Add("type", orderType)
+	parameters.Add("closePosition", "true")
+	parameters.Add("stopPrice", strconv.FormatFloat(stopPrice, 'f', -1, 64))
+	return bcfa.placeOrder(parameters)
+}
+
+// QueryOrder either orderId or origClientOrderId must be sent, pass 0 or empty string to skip one.
+// It is sent in every OrderMode, dry run orders are unknown to binance.
+func (bcfa BinanceCoinFuturesApi) QueryOrder(symbol, origClientOrderId string, orderId int64) ([]byte, error) {
+	parameters := url.Values{}
+	parameters.Add("symbol", symbol)
+	if orderId != 0 {
+		parameters.Add("orderId", strconv.FormatInt(orderId, 10))
+	}
+	if origClientOrderId != "" {
+		parameters.Add("origClientOrderId", origClientOrderId)
+	}
+	return bcfa.doSignedRequest("GET", orderEndPointCoin, parameters)
 }
 
+// CancelSingleOrder dry run mode does not cancel anything, a synthetic CANCELED response is returned.
 func (bcfa BinanceCoinFuturesApi) CancelSingleOrder(symbol, origClientOrderId string, orderId int64) ([]byte, error) {
+	if !bcfa.isLive() {
+		bcfa.Logger.Info(bcfa.OrderMode, " cancel ", symbol, " ", origClientOrderId, " ", orderId)
+		return syntheticCancelResponse(symbol, origClientOrderId, orderId)
+	}
 	parameters := url.Values{}
 	parameters.Add("symbol", symbol)
 	parameters.Add("orderId", strconv.FormatInt(orderId, 10))
@@ -305,11 +440,37 @@ func (bcfa BinanceCoinFuturesApi) CancelSingleOrder(symbol, origClientOrderId st
 }
 
 func (bcfa BinanceCoinFuturesApi) CancelAllOrders(symbol string) ([]byte, error) {
+	if !bcfa.isLive() {
+		bcfa.Logger.Info(bcfa.OrderMode, " cancel all ", symbol)
+		return []byte(`{"code":200,"msg":"The operation of cancel all open order is done."}`), nil
+	}
 	parameters := url.Values{}
 	parameters.Add("symbol", symbol)
 	return bcfa.doSignedRequest("DELETE", allOpenOrdersEndPointCoin, parameters)
 }
 
+// GetOpenOrders empty symbol returns open orders of every symbol, which costs much more weight.
+func (bcfa BinanceCoinFuturesApi) GetOpenOrders(symbol string) ([]byte, error) {
+	parameters := url.Values{}
+	if symbol != "" {
+		parameters.Add("symbol", symbol)
+	}
+	return bcfa.doSignedRequest("GET", openOrdersEndpointCoin, parameters)
+}
+
+// SetAutoCancelCountdown dead man's switch, every open order of the symbol is cancelled
+// when the countdown is not renewed within countdownTime milliseconds. 0 disables it.
+func (bcfa BinanceCoinFuturesApi) SetAutoCancelCountdown(symbol string, countdownTime int64) ([]byte, error) {
+	if !bcfa.isLive() {
+		bcfa.Logger.Info(bcfa.OrderMode, " countdown ", symbol, " ", countdownTime)
+		return []byte(fmt.Sprintf(`{"symbol":"%s","countdownTime":"%d"}`, symbol, countdownTime)), nil
+	}
+	parameters := url.Values{}
+	parameters.Add("symbol", symbol)
+	parameters.Add("countdownTime", strconv.FormatInt(countdownTime, 10))
+	return bcfa.doSignedRequest("POST", countdownCancelAllEndpointCoin, parameters)
+}
+
 func (bcfa BinanceCoinFuturesApi) GetAccountBalance() ([]byte, error) {
 	return bcfa.doSignedRequest("GET", futuresAccountBalanceEndpointCoin, url.Values{})
 }
@@ -320,15 +481,69 @@ func (bcfa BinanceCoinFuturesApi) GetAccountInformation() ([]byte, error) {
 
 func (bfa BinanceCoinFuturesApi) GetPositionInformation(symbol string) ([]byte, error) {
 	parameters := url.Values{}
-	parameters.Add("symbol", symbol)
+	if symbol != "" {
+		parameters.Add("symbol", symbol)
+	}
 	return bfa.doSignedRequest("GET", positionInformationCoin, parameters)
 }
 
+// GetTradeList startTime, endTime and limit are optional, pass empty strings to skip them.
+// startTime to endTime may span at most seven days.
 func (bfa BinanceCoinFuturesApi) GetTradeList(symbol, startTime, endTime, limit string) ([]byte, error) {
 	parameters := url.Values{}
 	parameters.Add("symbol", symbol)
-	parameters.Add("startTime", startTime)
-	parameters.Add("endTime", endTime)
-	parameters.Add("limit", limit)
+	if startTime != "" {
+		parameters.Add("startTime", startTime)
+	}
+	if endTime != "" {
+		parameters.Add("endTime", endTime)
+	}
+	if limit != "" {
+		parameters.Add("limit", limit)
+	}
 	return bfa.doSignedRequest("GET", tradeListCoin, parameters)
 }
+
+// GetIncomeHistory symbol and incomeType are optional, pass empty strings to skip them.
+// startTime and endTime are in milliseconds, 0 skips the parameter.
+func (bcfa BinanceCoinFuturesApi) GetIncomeHistory(symbol, incomeType string, startTime, endTime int64, limit int) ([]byte, error) {
+	return bcfa.doSignedRequest("GET", incomeHistoryEndpointCoin, incomeParameters(symbol, incomeType, startTime, endTime, limit))
+}
+
+func (bcfa BinanceCoinFuturesApi) GetCommissionRate(symbol string) ([]byte, error) {
+	parameters := url.Values{}
+	parameters.Add("symbol", symbol)
+	return bcfa.doSignedRequest("GET", commissionRateEndpointCoin, parameters)
+}
+
+func (bcfa BinanceCoinFuturesApi) GetAdlQuantile(symbol string) ([]byte, error) {
+	parameters := url.Values{}
+	if symbol != "" {
+		parameters.Add("symbol", symbol)
+	}
+	return bcfa.doSignedRequest("GET", adlQuantileEndpointCoin, parameters)
+}
+
+func (bcfa BinanceCoinFuturesApi) GetIncomeDownloadId(startTime, endTime int64) ([]byte, error) {
+	return bcfa.doSignedRequest("GET", incomeDownloadIdEndpointCoin, downloadParameters(startTime, endTime))
+}
+
+func (bcfa BinanceCoinFuturesApi) GetIncomeDownloadLink(downloadId string) ([]byte, error) {
+	return bcfa.doSignedRequest("GET", incomeDownloadLinkEndpointCoin, downloadLinkParameters(downloadId))
+}
+
+func (bcfa BinanceCoinFuturesApi) GetOrderDownloadId(startTime, endTime int64) ([]byte, error) {
+	return bcfa.doSignedRequest("GET", orderDownloadIdEndpointCoin, downloadParameters(startTime, endTime))
+}
+
+func (bcfa BinanceCoinFuturesApi) GetOrderDownloadLink(downloadId string) ([]byte, error) {
+	return bcfa.doSignedRequest("GET", orderDownloadLinkEndpointCoin, downloadLinkParameters(downloadId))
+}
+
+func (bcfa BinanceCoinFuturesApi) GetTradeDownloadId(startTime, endTime int64) ([]byte, error) {
+	return bcfa.doSignedRequest("GET", tradeDownloadIdEndpointCoin, downloadParameters(startTime, endTime))
+}
+
+func (bcfa BinanceCoinFuturesApi) GetTradeDownloadLink(downloadId string) ([]byte, error) {
+	return bcfa.doSignedRequest("GET", tradeDownloadLinkEndpointCoin, downloadLinkParameters(downloadId))
+}
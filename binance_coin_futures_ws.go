@@ -7,7 +7,8 @@ import (
 	"github.com/sirupsen/logrus"
 	"log"
 	"os"
-	"strings"
+	"sync"
+	"time"
 )
 
 const (
@@ -20,6 +21,13 @@ type BinanceFuturesCoinWebSocket struct {
 	BaseUrl string
 	SubscribeIdCounter int
 	Logger *logrus.Logger
+	requests streamRequestTracker
+	// ReadTimeout fails a read after this long without any frame, pings included. 0 waits forever
+	ReadTimeout time.Duration
+	// Recorder optional, every frame read is recorded for a StreamReplayer
+	Recorder *StreamRecorder
+	// connectionMu guards Connection, reconnects replace it while requests are written
+	connectionMu sync.RWMutex
 }
 
 func (bfcws *BinanceFuturesCoinWebSocket) PrepareLoggers()  {
@@ -47,6 +55,19 @@ func (bfcws *BinanceFuturesCoinWebSocket) IncrementSubscribeIdCounter() {
 	bfcws.SubscribeIdCounter++
 }
 
+func (bfcws *BinanceFuturesCoinWebSocket) setConnection(connection *websocket.Conn) {
+	bfcws.connectionMu.Lock()
+	bfcws.Connection = connection
+	bfcws.connectionMu.Unlock()
+}
+
+// currentConnection Connection of the latest dial, safe to call while another goroutine reconnects
+func (bfcws *BinanceFuturesCoinWebSocket) currentConnection() *websocket.Conn {
+	bfcws.connectionMu.RLock()
+	defer bfcws.connectionMu.RUnlock()
+	return bfcws.Connection
+}
+
 // Starts a websocket connection with default ping handler.
 func (bfcws *BinanceFuturesCoinWebSocket) OpenWebSocketConnection() error  {
 	connection, _, err := websocket.DefaultDialer.Dial(bfcws.BaseUrl, nil)
@@ -63,7 +84,8 @@ func (bfcws *BinanceFuturesCoinWebSocket) OpenWebSocketConnection() error  {
 
 	// -> Passing nil to SetPingHandler will set default handler on the connection.
 	connection.SetPingHandler(nil)
-	bfcws.Connection = connection
+	applyReadTimeout(connection, bfcws.ReadTimeout)
+	bfcws.setConnection(connection)
 	return nil
 }
 
@@ -84,39 +106,105 @@ func (bfcws *BinanceFuturesCoinWebSocket) OpenWebSocketConnectionWithUserStream(
 
 	// -> Passing nil to SetPingHandler will set default handler on the connection.
 	connection.SetPingHandler(nil)
-	bfcws.Connection = connection
+	applyReadTimeout(connection, bfcws.ReadTimeout)
+	bfcws.setConnection(connection)
 	return nil
 }
 
-// Subscribes to given symbol-stream type over provided connection
-func (bfcws BinanceFuturesCoinWebSocket) SubscribeToStream(symbol, streamType string) error {
-	parameter := fmt.Sprintf("%s@%s", strings.ToLower(symbol), streamType)
-	subscribeMap := models.LiveStream{Method: "SUBSCRIBE", Params: []string{parameter}, Id: bfcws.SubscribeIdCounter}
-	bfcws.IncrementSubscribeIdCounter()
-	err := bfcws.Connection.WriteJSON(subscribeMap)
+// Starts a combined stream connection with default ping handler, streams are names like btcusdt@bookTicker.
+// Every message arrives wrapped as {"stream": ..., "data": ...}, see UnwrapCombinedMessage.
+func (bfcws *BinanceFuturesCoinWebSocket) OpenCombinedStreamConnection(streams []string) error {
+	connection, _, err := websocket.DefaultDialer.Dial(combinedStreamUrl(bfcws.BaseUrl, streams), nil)
 	if err != nil {
-		bfcws.Logger.Println("Error has occurred while sending subscribe message through connection", err)
+		bfcws.Logger.Println("Default Dialer, had an error during initial dial, ", err)
 		return err
 	}
+	connection.SetPingHandler(nil)
+	applyReadTimeout(connection, bfcws.ReadTimeout)
+	bfcws.setConnection(connection)
 	return nil
 }
 
-func (bfcws BinanceFuturesCoinWebSocket) SubscribeLiquidationStream(symbol string) error {
+// Subscribes to given symbol-stream type over provided connection, does not wait for the response.
+func (bfcws *BinanceFuturesCoinWebSocket) SubscribeToStream(symbol, streamType string) error {
+	_, err := bfcws.SendStreamRequest(StreamMethodSubscribe, []interface{}{StreamName(symbol, streamType)}, 0)
+	return err
+}
+
+// SubscribeToStreamAndWait waits for the acknowledgement of the subscription.
+func (bfcws *BinanceFuturesCoinWebSocket) SubscribeToStreamAndWait(symbol, streamType string, timeout time.Duration) error {
+	_, err := bfcws.SendStreamRequest(StreamMethodSubscribe, []interface{}{StreamName(symbol, streamType)}, timeout)
+	return err
+}
+
+func (bfcws *BinanceFuturesCoinWebSocket) UnsubscribeFromStream(symbol, streamType string) error {
+	_, err := bfcws.SendStreamRequest(StreamMethodUnsubscribe, []interface{}{StreamName(symbol, streamType)}, 0)
+	return err
+}
+
+// ListSubscriptions the response arrives through the connection, so it has to be read meanwhile.
+func (bfcws *BinanceFuturesCoinWebSocket) ListSubscriptions(timeout time.Duration) ([]string, error) {
+	response, err := bfcws.SendStreamRequest(StreamMethodListSubscriptions, nil, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return streamNames(response.Result)
+}
+
+// SetCombinedProperty switches the connection between raw and combined (enveloped) payloads.
+func (bfcws *BinanceFuturesCoinWebSocket) SetCombinedProperty(combined bool, timeout time.Duration) error {
+	_, err := bfcws.SendStreamRequest(StreamMethodSetProperty, []interface{}{streamPropertyCombined, combined}, timeout)
+	return err
+}
+
+func (bfcws *BinanceFuturesCoinWebSocket) GetCombinedProperty(timeout time.Duration) (bool, error) {
+	response, err := bfcws.SendStreamRequest(StreamMethodGetProperty, []interface{}{streamPropertyCombined}, timeout)
+	if err != nil {
+		return false, err
+	}
+	return streamProperty(response.Result)
+}
+
+// SendStreamRequest sends a control request with a unique id and waits up to timeout for its response.
+// Timeout 0 does not wait and returns a nil response, a rejection then only arrives as message read
+// from the connection. Rejected requests return a StreamRequestError.
+func (bfcws *BinanceFuturesCoinWebSocket) SendStreamRequest(method string, params []interface{}, timeout time.Duration) (*models.StreamResponse, error) {
+	id, response, err := bfcws.requests.send(bfcws.currentConnection(), &bfcws.SubscribeIdCounter, method, params, timeout > 0)
+	if err != nil {
+		bfcws.Logger.Println("Error has occurred while sending "+method+" message through connection", err)
+		return nil, err
+	}
+	return bfcws.requests.wait(id, response, timeout)
+}
+
+func (bfcws *BinanceFuturesCoinWebSocket) SubscribeLiquidationStream(symbol string) error {
 	return bfcws.SubscribeToStream(symbol, liquidationStreamName)
 }
 
-func (bfcws BinanceFuturesCoinWebSocket) SubscribeBookTickerStream(symbol string) error {
+func (bfcws *BinanceFuturesCoinWebSocket) SubscribeBookTickerStream(symbol string) error {
 	return bfcws.SubscribeToStream(symbol, bookTickerSteamName)
 }
 
-func (bfcws BinanceFuturesCoinWebSocket) SubscribeSymbolTickerStream(symbol string) error {
+func (bfcws *BinanceFuturesCoinWebSocket) SubscribeSymbolTickerStream(symbol string) error {
 	return bfcws.SubscribeToStream(symbol, symbolTickerName)
 }
 
-func (bfcws BinanceFuturesCoinWebSocket) ReadFromConnection() (messageType int, p []byte, err error) {
-	return bfcws.Connection.ReadMessage()
+// ReadFromConnection responses to control requests are handed to their waiters and returned as well.
+func (bfcws *BinanceFuturesCoinWebSocket) ReadFromConnection() (messageType int, p []byte, err error) {
+	connection := bfcws.currentConnection()
+	messageType, p, err = connection.ReadMessage()
+	if err == nil {
+		bfcws.requests.resolve(p)
+		if bfcws.ReadTimeout > 0 {
+			_ = connection.SetReadDeadline(time.Now().Add(bfcws.ReadTimeout))
+		}
+		if bfcws.Recorder != nil {
+			_ = bfcws.Recorder.Record(messageType, p, time.Now())
+		}
+	}
+	return messageType, p, err
 }
 
 func (bfcws *BinanceFuturesCoinWebSocket) CloseConnection() error  {
-	return bfcws.Connection.Close()
+	return bfcws.currentConnection().Close()
 }
\ No newline at end of file
@@ -26,6 +26,7 @@ const (
 	ticker24HrEndPoint            = "/fapi/v1/ticker/24hr"
 	listenKeyEndPoint             = "/fapi/v1/listenKey"
 	orderEndPoint                 = "/fapi/v1/order"
+	testOrderEndPoint             = "/fapi/v1/order/test"
 	exchangeInformationEndPoint   = "/fapi/v1/exchangeInfo"
 	orderBookEndpoint             = "/fapi/v1/depth"
 	klinesEndpoint                = "/fapi/v1/klines"
@@ -34,6 +35,17 @@ const (
 	allOpenOrdersEndPoint         = "/fapi/v1/allOpenOrders"
 	positionInformation           = "/fapi/v2/positionRisk"
 	tradeList                     = "/fapi/v1/userTrades"
+	openOrdersEndpoint            = "/fapi/v1/openOrders"
+	countdownCancelAllEndpoint    = "/fapi/v1/countdownCancelAll"
+	incomeHistoryEndpoint         = "/fapi/v1/income"
+	commissionRateEndpoint        = "/fapi/v1/commissionRate"
+	adlQuantileEndpoint           = "/fapi/v1/adlQuantile"
+	incomeDownloadIdEndpoint      = "/fapi/v1/income/asyn"
+	incomeDownloadLinkEndpoint    = "/fapi/v1/income/asyn/id"
+	orderDownloadIdEndpoint       = "/fapi/v1/order/asyn"
+	orderDownloadLinkEndpoint     = "/fapi/v1/order/asyn/id"
+	tradeDownloadIdEndpoint       = "/fapi/v1/trade/asyn"
+	tradeDownloadLinkEndpoint     = "/fapi/v1/trade/asyn/id"
 
 	// ====== Parameter Types ======
 	SideBuy  = "BUY"
@@ -46,8 +58,30 @@ const (
 	OrderTypeStopMarket = "STOP_MARKET"
 	OrderTypeStop       = "STOP"
 
+	OrderTypeTakeProfitMarket = "TAKE_PROFIT_MARKET"
+
 	DefaultOrderBookLimit = 500
 	DefaultKlineLimit     = 500
+	DefaultIncomeLimit    = 100
+	MaxIncomeLimit        = 1000
+	MaxTradeListLimit     = 1000
+
+	IncomeTypeTransfer            = "TRANSFER"
+	IncomeTypeWelcomeBonus        = "WELCOME_BONUS"
+	IncomeTypeRealizedPnl         = "REALIZED_PNL"
+	IncomeTypeFundingFee          = "FUNDING_FEE"
+	IncomeTypeCommission          = "COMMISSION"
+	IncomeTypeInsuranceClear      = "INSURANCE_CLEAR"
+	IncomeTypeReferralKickback    = "REFERRAL_KICKBACK"
+	IncomeTypeCommissionRebate    = "COMMISSION_REBATE"
+	IncomeTypeApiRebate           = "API_REBATE"
+	IncomeTypeContestReward       = "CONTEST_REWARD"
+	IncomeTypeInternalTransfer    = "INTERNAL_TRANSFER"
+	IncomeTypeAutoExchange        = "AUTO_EXCHANGE"
+	IncomeTypeDeliveredSettlement = "DELIVERED_SETTELMENT"
+
+	DownloadStatusCompleted  = "completed"
+	DownloadStatusProcessing = "processing"
 
 	KlineInterval1Min   = "1m"
 	KlineInterval3Min   = "3m"
@@ -71,6 +105,23 @@ type BinanceFuturesApi struct {
 	PublicKey string
 	SecretKey string
 	Logger    *logrus.Logger
+	// OrderMode one of OrderModeLive, OrderModeTest or OrderModeDryRun
+	OrderMode string
+	// ClientOrderIds every order gets a generated newClientOrderId and placements with unknown
+	// status are looked up before they are sent again. The constructor and the net clients install
+	// one tagged DefaultClientOrderIdTag when it is nil, set it to nil afterwards to opt out
+	ClientOrderIds *ClientOrderIdGenerator
+	// Filters optional, orders of test and dry run modes are normalized and validated
+	// against the filters of their symbol, see LoadSymbolFilters
+	Filters map[string]SymbolFilters
+}
+
+func NewBinanceFuturesApi() *BinanceFuturesApi {
+	return &BinanceFuturesApi{
+		BaseUrl:        mainNetBaseURL,
+		Logger:         logrus.New(),
+		ClientOrderIds: NewClientOrderIdGenerator(DefaultClientOrderIdTag),
+	}
 }
 
 func (bfa *BinanceFuturesApi) PrepareLoggers() {
@@ -104,6 +155,7 @@ func (bfa *BinanceFuturesApi) NewNetClient() {
 		Timeout:   0,
 		Transport: netTransport,
 	}
+	bfa.defaultClientOrderIds()
 }
 
 func (bfa *BinanceFuturesApi) NewNetClientHTTP2() {
@@ -112,6 +164,28 @@ func (bfa *BinanceFuturesApi) NewNetClientHTTP2() {
 		Timeout:   0,
 		Transport: netTransport,
 	}
+	bfa.defaultClientOrderIds()
+}
+
+func (bfa *BinanceFuturesApi) defaultClientOrderIds() {
+	if bfa.ClientOrderIds == nil {
+		bfa.ClientOrderIds = NewClientOrderIdGenerator(DefaultClientOrderIdTag)
+	}
+}
+
+// SetOrderMode switches every order call between live, test endpoint and local dry run.
+// Unknown modes are rejected and the current mode is kept.
+func (bfa *BinanceFuturesApi) SetOrderMode(mode string) error {
+	if err := ValidateOrderMode(mode); err != nil {
+		return err
+	}
+	bfa.OrderMode = mode
+	return nil
+}
+
+// isLive order, modify, cancel and countdown calls reach the exchange
+func (bfa BinanceFuturesApi) isLive() bool {
+	return isLiveOrderMode(bfa.OrderMode)
 }
 
 func (bfa *BinanceFuturesApi) UseMainNet() {
@@ -123,15 +197,19 @@ func (bfa *BinanceFuturesApi) UseTestNet() {
 
 // Adds timestamp and creates a signature according to binance rules
 func (bfa BinanceFuturesApi) signParameters(parameters *url.Values) string {
-	tonce := strconv.FormatInt(time.Now().UnixNano(), 10)[0:13]
-	parameters.Add("timestamp", tonce)
+	parameters.Add("timestamp", requestTimestamp())
 	signature, _ := bfa.getSha256Signature(parameters.Encode())
 	return signature
 }
 
 // Signs given parameter values
 func (bfa BinanceFuturesApi) getSha256Signature(parameters string) (string, error) {
-	mac := hmac.New(sha256.New, []byte(bfa.SecretKey))
+	return sha256Signature(bfa.SecretKey, parameters)
+}
+
+// sha256Signature HMAC SHA256 of the encoded parameters, shared by the REST and websocket api
+func sha256Signature(secretKey, parameters string) (string, error) {
+	mac := hmac.New(sha256.New, []byte(secretKey))
 	_, err := mac.Write([]byte(parameters))
 	if err != nil {
 		return "", err
@@ -139,6 +217,11 @@ func (bfa BinanceFuturesApi) getSha256Signature(parameters string) (string, erro
 	return hex.EncodeToString(mac.Sum(nil)), nil
 }
 
+// requestTimestamp milliseconds timestamp parameter of signed requests
+func requestTimestamp() string {
+	return strconv.FormatInt(time.Now().UnixNano(), 10)[0:13]
+}
+
 func (bfa BinanceFuturesApi) parseResponseBody(body io.ReadCloser) ([]byte, error) {
 	data, err := ioutil.ReadAll(body)
 	if err != nil {
@@ -176,7 +259,8 @@ func (bfa BinanceFuturesApi) doPublicRequest(httpVerb, endPoint string, paramete
 		bem := new(BinanceErrorMessage)
 		err = json.Unmarshal(data, &bem)
 		if err != nil {
-			bem = nil
+			// Body is not a binance error, e.g. html of a gateway error
+			bem = &BinanceErrorMessage{Message: string(data)}
 		}
 		err = &RequestError{
 			StatusCode: response.StatusCode,
@@ -219,7 +303,8 @@ func (bfa BinanceFuturesApi) doSignedRequest(httpVerb, endPoint string, paramete
 		bem := new(BinanceErrorMessage)
 		err = json.Unmarshal(data, &bem)
 		if err != nil {
-			bem = nil
+			// Body is not a binance error, e.g. html of a gateway error
+			bem = &BinanceErrorMessage{Message: string(data)}
 		}
 		err = &RequestError{
 			StatusCode: response.StatusCode,
@@ -232,6 +317,117 @@ func (bfa BinanceFuturesApi) doSignedRequest(httpVerb, endPoint string, paramete
 	return data, nil
 }
 
+// Sends order parameters according to OrderMode, dry run signs the request without sending it.
+// Test and dry run modes return a synthetic order response with status NEW.
+func (bfa BinanceFuturesApi) placeOrder(parameters url.Values) ([]byte, error) {
+	if bfa.ClientOrderIds != nil && parameters.Get("newClientOrderId") == "" {
+		parameters.Set("newClientOrderId", bfa.ClientOrderIds.Next())
+	}
+	if !bfa.isLive() {
+		if err := applySymbolFilters(bfa.Filters, parameters); err != nil {
+			bfa.Logger.Warn("order rejected by symbol filters ", parameters.Encode(), " ", err)
+			return nil, err
+		}
+		if bfa.OrderMode == OrderModeTest {
+			if _, err := bfa.doSignedRequest("POST", testOrderEndPoint, parameters); err != nil {
+				return nil, err
+			}
+			bfa.Logger.Info("test order accepted ", parameters.Encode())
+			return syntheticOrderResponse(parameters)
+		}
+		signature := bfa.signParameters(&parameters)
+		bfa.Logger.Info("dry run order ", parameters.Encode(), " signature ", signature)
+		return syntheticOrderResponse(parameters)
+	}
+	if bfa.ClientOrderIds == nil {
+		return bfa.doSignedRequest("POST", orderEndPoint, parameters)
+	}
+	send := func(parameters url.Values) ([]byte, error) {
+		return bfa.doSignedRequest("POST", orderEndPoint, parameters)
+	}
+	query := func(symbol, clientOrderId string) ([]byte, error) {
+		return bfa.QueryOrder(symbol, clientOrderId, 0)
+	}
+	return placeIdempotent(bfa.Logger, parameters, send, query)
+}
+
+// ====== Order parameters, shared by the REST and websocket api ======
+
+func limitOrderParameters(symbol, side, timeInForce string, price, qty float64, reduceOnly bool) url.Values {
+	parameters := url.Values{}
+	parameters.Add("symbol", symbol)
+	parameters.Add("side", side)
+	parameters.Add("type", OrderTypeLimit)
+	parameters.Add("timeInForce", timeInForce)
+	parameters.Add("reduceOnly", strconv.FormatBool(reduceOnly))
+	parameters.Add("quantity", strconv.FormatFloat(qty, 'f', -1, 64))
+	parameters.Add("price", strconv.FormatFloat(price, 'f', -1, 64))
+	return parameters
+}
+
+func marketOrderParameters(symbol, side string, qty float64, reduceOnly bool) url.Values {
+	parameters := url.Values{}
+	parameters.Add("symbol", symbol)
+	parameters.Add("side", side)
+	parameters.Add("type", OrderTypeMarket)
+	parameters.Add("reduceOnly", strconv.FormatBool(reduceOnly))
+	parameters.Add("quantity", strconv.FormatFloat(qty, 'f', -1, 64))
+	return parameters
+}
+
+func hedgeMarketOrderParameters(symbol, side, positionSide string, qty float64) url.Values {
+	parameters := url.Values{}
+	parameters.Add("symbol", symbol)
+	parameters.Add("side", side)
+	parameters.Add("positionSide", positionSide)
+	parameters.Add("type", OrderTypeMarket)
+	parameters.Add("quantity", strconv.FormatFloat(qty, 'f', -1, 64))
+	return parameters
+}
+
+// triggerOrderParameters reduce only STOP_MARKET and TAKE_PROFIT_MARKET orders
+func triggerOrderParameters(symbol, side, orderType string, stopPrice, qty float64) url.Values {
+	parameters := url.Values{}
+	parameters.Add("symbol", symbol)
+	parameters.Add("side", side)
+	parameters.Add("type", orderType)
+	parameters.Add("reduceOnly", "true")
+	parameters.Add("quantity", strconv.FormatFloat(qty, 'f', -1, 64))
+	parameters.Add("stopPrice", strconv.FormatFloat(stopPrice, 'f', -1, 64))
+	return parameters
+}
+
+func closePositionParameters(symbol, side, orderType string, stopPrice float64) url.Values {
+	parameters := url.Values{}
+	parameters.Add("symbol", symbol)
+	parameters.Add("side", side)
+	parameters.Add("type", orderType)
+	parameters.Add("closePosition", "true")
+	parameters.Add("stopPrice", strconv.FormatFloat(stopPrice, 'f', -1, 64))
+	return parameters
+}
+
+// orderReferenceParameters identifies an order by orderId or origClientOrderId, zero values are skipped
+func orderReferenceParameters(symbol, origClientOrderId string, orderId int64) url.Values {
+	parameters := url.Values{}
+	parameters.Add("symbol", symbol)
+	if orderId != 0 {
+		parameters.Add("orderId", strconv.FormatInt(orderId, 10))
+	}
+	if origClientOrderId != "" {
+		parameters.Add("origClientOrderId", origClientOrderId)
+	}
+	return parameters
+}
+
+func modifyOrderParameters(symbol, side, origClientOrderId string, orderId int64, price, qty float64) url.Values {
+	parameters := orderReferenceParameters(symbol, origClientOrderId, orderId)
+	parameters.Add("side", side)
+	parameters.Add("quantity", strconv.FormatFloat(qty, 'f', -1, 64))
+	parameters.Add("price", strconv.FormatFloat(price, 'f', -1, 64))
+	return parameters
+}
+
 // ======================= PUBLIC API CALLS ================================
 
 //	Contains weighted average price (vwap)
@@ -282,37 +478,21 @@ func (bfa BinanceFuturesApi) DeleteUserStream() ([]byte, error) {
 }
 
 func (bfa BinanceFuturesApi) PlaceLimitOrder(symbol, side string, price, qty float64, reduceOnly bool) ([]byte, error) {
-	parameters := url.Values{}
-	parameters.Add("symbol", symbol)
-	parameters.Add("side", side)
-	parameters.Add("type", OrderTypeLimit)
-	parameters.Add("timeInForce", GoodTillCancel)
-	parameters.Add("reduceOnly", strconv.FormatBool(reduceOnly))
-	parameters.Add("quantity", strconv.FormatFloat(qty, 'f', -1, 64))
-	parameters.Add("price", strconv.FormatFloat(price, 'f', -1, 64))
-	return bfa.doSignedRequest("POST", orderEndPoint, parameters)
+	return bfa.placeOrder(limitOrderParameters(symbol, side, GoodTillCancel, price, qty, reduceOnly))
 }
 
 func (bfa BinanceFuturesApi) PlacePostOnlyLimitOrder(symbol, side string, price, qty float64, reduceOnly bool) ([]byte, error) {
-	parameters := url.Values{}
-	parameters.Add("symbol", symbol)
-	parameters.Add("side", side)
-	parameters.Add("type", OrderTypeLimit)
-	parameters.Add("timeInForce", GoodTillCrossing)
-	parameters.Add("reduceOnly", strconv.FormatBool(reduceOnly))
-	parameters.Add("quantity", strconv.FormatFloat(qty, 'f', -1, 64))
-	parameters.Add("price", strconv.FormatFloat(price, 'f', -1, 64))
-	return bfa.doSignedRequest("POST", orderEndPoint, parameters)
+	return bfa.placeOrder(limitOrderParameters(symbol, side, GoodTillCrossing, price, qty, reduceOnly))
 }
 
 func (bfa BinanceFuturesApi) PlaceMarketOrder(symbol, side string, qty float64, reduceOnly bool) ([]byte, error) {
-	parameters := url.Values{}
-	parameters.Add("symbol", symbol)
-	parameters.Add("side", side)
-	parameters.Add("type", OrderTypeMarket)
-	parameters.Add("reduceOnly", strconv.FormatBool(reduceOnly))
-	parameters.Add("quantity", strconv.FormatFloat(qty, 'f', -1, 64))
-	return bfa.doSignedRequest("POST", orderEndPoint, parameters)
+	return bfa.placeOrder(marketOrderParameters(symbol, side, qty, reduceOnly))
+}
+
+// PlaceHedgeMarketOrder positionSide is LONG or SHORT of a hedge mode account, reduceOnly can not be sent there.
+// SELL with LONG or BUY with SHORT closes the position.
+func (bfa BinanceFuturesApi) PlaceHedgeMarketOrder(symbol, side, positionSide string, qty float64) ([]byte, error) {
+	return bfa.placeOrder(hedgeMarketOrderParameters(symbol, side, positionSide, qty))
 }
 
 // PlaceStopMarketOrder Generally used for trailing profit orders.
@@ -321,18 +501,42 @@ func (bfa BinanceFuturesApi) PlaceMarketOrder(symbol, side string, qty float64,
 // In order to use this method as take profit tool
 // use the same side as your position side.
 func (bfa BinanceFuturesApi) PlaceStopMarketOrder(symbol, side string, stopPrice, qty float64) ([]byte, error) {
-	parameters := url.Values{}
-	parameters.Add("symbol", symbol)
-	parameters.Add("side", side)
-	parameters.Add("type", OrderTypeStopMarket)
-	parameters.Add("reduceOnly", "true")
-	parameters.Add("quantity", strconv.FormatFloat(qty, 'f', -1, 64))
-	parameters.Add("stopPrice", strconv.FormatFloat(stopPrice, 'f', -1, 64))
+	return bfa.placeOrder(triggerOrderParameters(symbol, side, OrderTypeStopMarket, stopPrice, qty))
+}
+
+// PlaceTakeProfitMarketOrder reduce only, use the opposite side of your position.
+func (bfa BinanceFuturesApi) PlaceTakeProfitMarketOrder(symbol, side string, stopPrice, qty float64) ([]byte, error) {
+	return bfa.placeOrder(triggerOrderParameters(symbol, side, OrderTypeTakeProfitMarket, stopPrice, qty))
+}
+
+// PlaceClosePositionOrder orderType is either STOP_MARKET or TAKE_PROFIT_MARKET.
+// Closes the whole position when triggered, no quantity is sent.
+func (bfa BinanceFuturesApi) PlaceClosePositionOrder(symbol, side, orderType string, stopPrice float64) ([]byte, error) {
+	return bfa.placeOrder(closePositionParameters(symbol, side, orderType, stopPrice))
+}
 
-	return bfa.doSignedRequest("POST", orderEndPoint, parameters)
+// QueryOrder either orderId or origClientOrderId must be sent, pass 0 or empty string to skip one.
+// It is sent in every OrderMode, orders of test and dry run modes are unknown to binance.
+func (bfa BinanceFuturesApi) QueryOrder(symbol, origClientOrderId string, orderId int64) ([]byte, error) {
+	return bfa.doSignedRequest("GET", orderEndPoint, orderReferenceParameters(symbol, origClientOrderId, orderId))
 }
 
+// ModifyOrder changes price and quantity of an open limit order, the side must stay the same.
+// Either orderId or origClientOrderId must be sent. Test and dry run modes return a synthetic response.
+func (bfa BinanceFuturesApi) ModifyOrder(symbol, side, origClientOrderId string, orderId int64, price, qty float64) ([]byte, error) {
+	if !bfa.isLive() {
+		bfa.Logger.Info(bfa.OrderMode, " modify ", symbol, " ", origClientOrderId, " ", orderId)
+		return syntheticModifyResponse(symbol, side, origClientOrderId, orderId, price, qty)
+	}
+	return bfa.doSignedRequest("PUT", orderEndPoint, modifyOrderParameters(symbol, side, origClientOrderId, orderId, price, qty))
+}
+
+// CancelSingleOrder test and dry run modes do not cancel anything, a synthetic CANCELED response is returned.
 func (bfa BinanceFuturesApi) CancelSingleOrder(symbol, origClientOrderId string, orderId int64) ([]byte, error) {
+	if !bfa.isLive() {
+		bfa.Logger.Info(bfa.OrderMode, " cancel ", symbol, " ", origClientOrderId, " ", orderId)
+		return syntheticCancelResponse(symbol, origClientOrderId, orderId)
+	}
 	parameters := url.Values{}
 	parameters.Add("symbol", symbol)
 	parameters.Add("orderId", strconv.FormatInt(orderId, 10))
@@ -341,11 +545,37 @@ func (bfa BinanceFuturesApi) CancelSingleOrder(symbol, origClientOrderId string,
 }
 
 func (bfa BinanceFuturesApi) CancelAllOrders(symbol string) ([]byte, error) {
+	if !bfa.isLive() {
+		bfa.Logger.Info(bfa.OrderMode, " cancel all ", symbol)
+		return []byte(`{"code":200,"msg":"The operation of cancel all open order is done."}`), nil
+	}
 	parameters := url.Values{}
 	parameters.Add("symbol", symbol)
 	return bfa.doSignedRequest("DELETE", allOpenOrdersEndPoint, parameters)
 }
 
+// GetOpenOrders empty symbol returns open orders of every symbol, which costs much more weight.
+func (bfa BinanceFuturesApi) GetOpenOrders(symbol string) ([]byte, error) {
+	parameters := url.Values{}
+	if symbol != "" {
+		parameters.Add("symbol", symbol)
+	}
+	return bfa.doSignedRequest("GET", openOrdersEndpoint, parameters)
+}
+
+// SetAutoCancelCountdown dead man's switch, every open order of the symbol is cancelled
+// when the countdown is not renewed within countdownTime milliseconds. 0 disables it.
+func (bfa BinanceFuturesApi) SetAutoCancelCountdown(symbol string, countdownTime int64) ([]byte, error) {
+	if !bfa.isLive() {
+		bfa.Logger.Info(bfa.OrderMode, " countdown ", symbol, " ", countdownTime)
+		return []byte(fmt.Sprintf(`{"symbol":"%s","countdownTime":"%d"}`, symbol, countdownTime)), nil
+	}
+	parameters := url.Values{}
+	parameters.Add("symbol", symbol)
+	parameters.Add("countdownTime", strconv.FormatInt(countdownTime, 10))
+	return bfa.doSignedRequest("POST", countdownCancelAllEndpoint, parameters)
+}
+
 func (bfa BinanceFuturesApi) GetAccountBalance() ([]byte, error) {
 	return bfa.doSignedRequest("GET", futuresAccountBalanceEndpoint, url.Values{})
 }
@@ -354,17 +584,76 @@ func (bfa BinanceFuturesApi) GetAccountInformation() ([]byte, error) {
 	return bfa.doSignedRequest("GET", accountInformationEndpoint, url.Values{})
 }
 
+// GetPositionInformation empty symbol returns positions of every symbol
 func (bfa BinanceFuturesApi) GetPositionInformation(symbol string) ([]byte, error) {
 	parameters := url.Values{}
-	parameters.Add("symbol", symbol)
+	if symbol != "" {
+		parameters.Add("symbol", symbol)
+	}
 	return bfa.doSignedRequest("GET", positionInformation, parameters)
 }
 
+// GetTradeList startTime, endTime and limit are optional, pass empty strings to skip them.
+// startTime to endTime may span at most seven days.
 func (bfa BinanceFuturesApi) GetTradeList(symbol, startTime, endTime, limit string) ([]byte, error) {
 	parameters := url.Values{}
 	parameters.Add("symbol", symbol)
-	parameters.Add("startTime", startTime)
-	parameters.Add("endTime", endTime)
-	parameters.Add("limit", limit)
+	if startTime != "" {
+		parameters.Add("startTime", startTime)
+	}
+	if endTime != "" {
+		parameters.Add("endTime", endTime)
+	}
+	if limit != "" {
+		parameters.Add("limit", limit)
+	}
 	return bfa.doSignedRequest("GET", tradeList, parameters)
 }
+
+// GetIncomeHistory symbol and incomeType are optional, pass empty strings to skip them.
+// startTime and endTime are in milliseconds, 0 skips the parameter.
+// Use GetAllIncomeHistory for time paging over larger windows.
+func (bfa BinanceFuturesApi) GetIncomeHistory(symbol, incomeType string, startTime, endTime int64, limit int) ([]byte, error) {
+	return bfa.doSignedRequest("GET", incomeHistoryEndpoint, incomeParameters(symbol, incomeType, startTime, endTime, limit))
+}
+
+func (bfa BinanceFuturesApi) GetCommissionRate(symbol string) ([]byte, error) {
+	parameters := url.Values{}
+	parameters.Add("symbol", symbol)
+	return bfa.doSignedRequest("GET", commissionRateEndpoint, parameters)
+}
+
+// GetAdlQuantile symbol is optional, empty string returns every symbol with an open position.
+func (bfa BinanceFuturesApi) GetAdlQuantile(symbol string) ([]byte, error) {
+	parameters := url.Values{}
+	if symbol != "" {
+		parameters.Add("symbol", symbol)
+	}
+	return bfa.doSignedRequest("GET", adlQuantileEndpoint, parameters)
+}
+
+// GetIncomeDownloadId Binance limits the window between startTime and endTime to one year.
+// Use WaitForDownloadLink with GetIncomeDownloadLink to receive the file.
+func (bfa BinanceFuturesApi) GetIncomeDownloadId(startTime, endTime int64) ([]byte, error) {
+	return bfa.doSignedRequest("GET", incomeDownloadIdEndpoint, downloadParameters(startTime, endTime))
+}
+
+func (bfa BinanceFuturesApi) GetIncomeDownloadLink(downloadId string) ([]byte, error) {
+	return bfa.doSignedRequest("GET", incomeDownloadLinkEndpoint, downloadLinkParameters(downloadId))
+}
+
+func (bfa BinanceFuturesApi) GetOrderDownloadId(startTime, endTime int64) ([]byte, error) {
+	return bfa.doSignedRequest("GET", orderDownloadIdEndpoint, downloadParameters(startTime, endTime))
+}
+
+func (bfa BinanceFuturesApi) GetOrderDownloadLink(downloadId string) ([]byte, error) {
+	return bfa.doSignedRequest("GET", orderDownloadLinkEndpoint, downloadLinkParameters(downloadId))
+}
+
+func (bfa BinanceFuturesApi) GetTradeDownloadId(startTime, endTime int64) ([]byte, error) {
+	return bfa.doSignedRequest("GET", tradeDownloadIdEndpoint, downloadParameters(startTime, endTime))
+}
+
+func (bfa BinanceFuturesApi) GetTradeDownloadLink(downloadId string) ([]byte, error) {
+	return bfa.doSignedRequest("GET", tradeDownloadLinkEndpoint, downloadLinkParameters(downloadId))
+}
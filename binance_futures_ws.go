@@ -7,7 +7,8 @@ import (
 	"github.com/sirupsen/logrus"
 	"log"
 	"os"
-	"strings"
+	"sync"
+	"time"
 )
 
 const (
@@ -23,6 +24,13 @@ type BinanceFuturesWebSocket struct {
 	BaseUrl string
 	SubscribeIdCounter int
 	Logger *logrus.Logger
+	requests streamRequestTracker
+	// ReadTimeout fails a read after this long without any frame, pings included. 0 waits forever
+	ReadTimeout time.Duration
+	// Recorder optional, every frame read is recorded for a StreamReplayer
+	Recorder *StreamRecorder
+	// connectionMu guards Connection, reconnects replace it while requests are written
+	connectionMu sync.RWMutex
 }
 
 func (bfws *BinanceFuturesWebSocket) PrepareLoggers()  {
@@ -50,6 +58,19 @@ func (bfws *BinanceFuturesWebSocket) IncrementSubscribeIdCounter() {
 	bfws.SubscribeIdCounter++
 }
 
+func (bfws *BinanceFuturesWebSocket) setConnection(connection *websocket.Conn) {
+	bfws.connectionMu.Lock()
+	bfws.Connection = connection
+	bfws.connectionMu.Unlock()
+}
+
+// currentConnection Connection of the latest dial, safe to call while another goroutine reconnects
+func (bfws *BinanceFuturesWebSocket) currentConnection() *websocket.Conn {
+	bfws.connectionMu.RLock()
+	defer bfws.connectionMu.RUnlock()
+	return bfws.Connection
+}
+
 // Starts a websocket connection with default ping handler.
 func (bfws *BinanceFuturesWebSocket) OpenWebSocketConnection() error  {
 	connection, _, err := websocket.DefaultDialer.Dial(bfws.BaseUrl, nil)
@@ -66,7 +87,8 @@ func (bfws *BinanceFuturesWebSocket) OpenWebSocketConnection() error  {
 
 	// -> Passing nil to SetPingHandler will set default handler on the connection.
 	connection.SetPingHandler(nil)
-	bfws.Connection = connection
+	applyReadTimeout(connection, bfws.ReadTimeout)
+	bfws.setConnection(connection)
 	return nil
 }
 
@@ -87,39 +109,105 @@ func (bfws *BinanceFuturesWebSocket) OpenWebSocketConnectionWithUserStream(liste
 
 	// -> Passing nil to SetPingHandler will set default handler on the connection.
 	connection.SetPingHandler(nil)
-	bfws.Connection = connection
+	applyReadTimeout(connection, bfws.ReadTimeout)
+	bfws.setConnection(connection)
 	return nil
 }
 
-// Subscribes to given symbol-stream type over provided connection
-func (bfws BinanceFuturesWebSocket) SubscribeToStream(symbol, streamType string) error {
-	parameter := fmt.Sprintf("%s@%s", strings.ToLower(symbol), streamType)
-	subscribeMap := models.LiveStream{Method: "SUBSCRIBE", Params: []string{parameter}, Id: bfws.SubscribeIdCounter}
-	bfws.IncrementSubscribeIdCounter()
-	err := bfws.Connection.WriteJSON(subscribeMap)
+// Starts a combined stream connection with default ping handler, streams are names like btcusdt@bookTicker.
+// Every message arrives wrapped as {"stream": ..., "data": ...}, see UnwrapCombinedMessage.
+func (bfws *BinanceFuturesWebSocket) OpenCombinedStreamConnection(streams []string) error {
+	connection, _, err := websocket.DefaultDialer.Dial(combinedStreamUrl(bfws.BaseUrl, streams), nil)
 	if err != nil {
-		bfws.Logger.Println("Error has occurred while sending subscribe message through connection", err)
+		bfws.Logger.Println("Default Dialer, had an error during initial dial, ", err)
 		return err
 	}
+	connection.SetPingHandler(nil)
+	applyReadTimeout(connection, bfws.ReadTimeout)
+	bfws.setConnection(connection)
 	return nil
 }
 
-func (bfws BinanceFuturesWebSocket) SubscribeLiquidationStream(symbol string) error {
+// Subscribes to given symbol-stream type over provided connection, does not wait for the response.
+func (bfws *BinanceFuturesWebSocket) SubscribeToStream(symbol, streamType string) error {
+	_, err := bfws.SendStreamRequest(StreamMethodSubscribe, []interface{}{StreamName(symbol, streamType)}, 0)
+	return err
+}
+
+// SubscribeToStreamAndWait waits for the acknowledgement of the subscription.
+func (bfws *BinanceFuturesWebSocket) SubscribeToStreamAndWait(symbol, streamType string, timeout time.Duration) error {
+	_, err := bfws.SendStreamRequest(StreamMethodSubscribe, []interface{}{StreamName(symbol, streamType)}, timeout)
+	return err
+}
+
+func (bfws *BinanceFuturesWebSocket) UnsubscribeFromStream(symbol, streamType string) error {
+	_, err := bfws.SendStreamRequest(StreamMethodUnsubscribe, []interface{}{StreamName(symbol, streamType)}, 0)
+	return err
+}
+
+// ListSubscriptions the response arrives through the connection, so it has to be read meanwhile.
+func (bfws *BinanceFuturesWebSocket) ListSubscriptions(timeout time.Duration) ([]string, error) {
+	response, err := bfws.SendStreamRequest(StreamMethodListSubscriptions, nil, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return streamNames(response.Result)
+}
+
+// SetCombinedProperty switches the connection between raw and combined (enveloped) payloads.
+func (bfws *BinanceFuturesWebSocket) SetCombinedProperty(combined bool, timeout time.Duration) error {
+	_, err := bfws.SendStreamRequest(StreamMethodSetProperty, []interface{}{streamPropertyCombined, combined}, timeout)
+	return err
+}
+
+func (bfws *BinanceFuturesWebSocket) GetCombinedProperty(timeout time.Duration) (bool, error) {
+	response, err := bfws.SendStreamRequest(StreamMethodGetProperty, []interface{}{streamPropertyCombined}, timeout)
+	if err != nil {
+		return false, err
+	}
+	return streamProperty(response.Result)
+}
+
+// SendStreamRequest sends a control request with a unique id and waits up to timeout for its response.
+// Timeout 0 does not wait and returns a nil response, a rejection then only arrives as message read
+// from the connection. Rejected requests return a StreamRequestError.
+func (bfws *BinanceFuturesWebSocket) SendStreamRequest(method string, params []interface{}, timeout time.Duration) (*models.StreamResponse, error) {
+	id, response, err := bfws.requests.send(bfws.currentConnection(), &bfws.SubscribeIdCounter, method, params, timeout > 0)
+	if err != nil {
+		bfws.Logger.Println("Error has occurred while sending "+method+" message through connection", err)
+		return nil, err
+	}
+	return bfws.requests.wait(id, response, timeout)
+}
+
+func (bfws *BinanceFuturesWebSocket) SubscribeLiquidationStream(symbol string) error {
 	return bfws.SubscribeToStream(symbol, liquidationStreamName)
 }
 
-func (bfws BinanceFuturesWebSocket) SubscribeBookTickerStream(symbol string) error {
+func (bfws *BinanceFuturesWebSocket) SubscribeBookTickerStream(symbol string) error {
 	return bfws.SubscribeToStream(symbol, bookTickerSteamName)
 }
 
-func (bfws BinanceFuturesWebSocket) SubscribeSymbolTickerStream(symbol string) error {
+func (bfws *BinanceFuturesWebSocket) SubscribeSymbolTickerStream(symbol string) error {
 	return bfws.SubscribeToStream(symbol, symbolTickerName)
 }
 
-func (bfws BinanceFuturesWebSocket) ReadFromConnection() (messageType int, p []byte, err error) {
-	return bfws.Connection.ReadMessage()
+// ReadFromConnection responses to control requests are handed to their waiters and returned as well.
+func (bfws *BinanceFuturesWebSocket) ReadFromConnection() (messageType int, p []byte, err error) {
+	connection := bfws.currentConnection()
+	messageType, p, err = connection.ReadMessage()
+	if err == nil {
+		bfws.requests.resolve(p)
+		if bfws.ReadTimeout > 0 {
+			_ = connection.SetReadDeadline(time.Now().Add(bfws.ReadTimeout))
+		}
+		if bfws.Recorder != nil {
+			_ = bfws.Recorder.Record(messageType, p, time.Now())
+		}
+	}
+	return messageType, p, err
 }
 
 func (bfws *BinanceFuturesWebSocket) CloseConnection() error  {
-	return bfws.Connection.Close()
+	return bfws.currentConnection().Close()
 }
\ No newline at end of file
@@ -0,0 +1,443 @@
+package go_binance
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/redlon23/go-binance/models"
+	"github.com/sirupsen/logrus"
+	"math"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	BracketStatePending  = "PENDING"
+	BracketStateActive   = "ACTIVE"
+	BracketStateClosed   = "CLOSED"
+	BracketStateCanceled = "CANCELED"
+	BracketStateFailed   = "FAILED"
+
+	bracketLegEntry      = "ENTRY"
+	bracketLegTakeProfit = "TAKE_PROFIT"
+	bracketLegStopLoss   = "STOP_LOSS"
+
+	// Exit quantities are rounded to this without filters of the symbol
+	defaultBracketQuantityStep = 1e-8
+
+	DefaultBracketExitAttempts  = 3
+	DefaultBracketExitRetryWait = 200 * time.Millisecond
+)
+
+// BracketOrder entry order with linked take profit and stop loss.
+// EntryPrice 0 places a market entry. With ClosePosition exits are sent with closePosition=true,
+// otherwise they are reduce only and resized with every fill of the entry.
+type BracketOrder struct {
+	Symbol          string
+	Side            string
+	Quantity        float64
+	EntryPrice      float64
+	TakeProfitPrice float64
+	StopLossPrice   float64
+	ClosePosition   bool
+}
+
+type Bracket struct {
+	Id         int64
+	Order      BracketOrder
+	State      string
+	Entry      ManagedOrder
+	TakeProfit *ManagedOrder
+	StopLoss   *ManagedOrder
+	// Last error returned while managing the bracket, set together with BracketStateFailed
+	Err error
+	// Filled quantity of exits which were replaced during resizing
+	replacedExitFilled float64
+}
+
+func (b Bracket) exitSide() string {
+	if b.Order.Side == SideBuy {
+		return SideSell
+	}
+	return SideBuy
+}
+
+func (b Bracket) exitFilledQuantity() float64 {
+	filled := b.replacedExitFilled
+	if b.TakeProfit != nil {
+		filled += b.TakeProfit.FilledQuantity
+	}
+	if b.StopLoss != nil {
+		filled += b.StopLoss.FilledQuantity
+	}
+	return filled
+}
+
+type bracketLeg struct {
+	bracket *Bracket
+	leg     string
+}
+
+// BracketManager places and maintains client side OCO brackets.
+// Feed it with every ORDER_TRADE_UPDATE of the user stream. Brackets live in memory,
+// after a user stream reconnect call Reconcile to catch up on missed events.
+type BracketManager struct {
+	Api BinanceFutures
+	// Optional, exit quantities are rounded down to the step size of the symbol when present
+	Filters map[string]SymbolFilters
+	// Placements of an exit before the bracket fails, a filled entry is unprotected meanwhile
+	ExitAttempts  int
+	ExitRetryWait time.Duration
+	Logger        *logrus.Logger
+
+	mu       sync.Mutex
+	idCount  int64
+	brackets map[int64]*Bracket
+	legs     map[string]bracketLeg
+}
+
+func NewBracketManager(api BinanceFutures) *BracketManager {
+	return &BracketManager{
+		Api:           api,
+		ExitAttempts:  DefaultBracketExitAttempts,
+		ExitRetryWait: DefaultBracketExitRetryWait,
+		Logger:        logrus.New(),
+		brackets:      make(map[int64]*Bracket),
+		legs:          make(map[string]bracketLeg),
+	}
+}
+
+func (bm *BracketManager) PrepareLoggers() {
+	bm.Logger = logrus.New()
+	bm.Logger.Formatter = new(logrus.JSONFormatter)
+
+	bracketLogs, err := os.OpenFile("logs/binance_bracket.log", os.O_CREATE|os.O_WRONLY, 0666)
+	if err == nil {
+		bm.Logger.SetOutput(bracketLogs)
+	} else {
+		fmt.Println("Failed to log to file for bracket orders, using default stderr")
+	}
+}
+
+// Place sends the entry order, exits are placed after the entry gets its first fill.
+func (bm *BracketManager) Place(order BracketOrder) (Bracket, error) {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+
+	var data []byte
+	var err error
+	if order.EntryPrice == 0 {
+		data, err = bm.Api.PlaceMarketOrder(order.Symbol, order.Side, order.Quantity, false)
+	} else {
+		data, err = bm.Api.PlaceLimitOrder(order.Symbol, order.Side, order.EntryPrice, order.Quantity, false)
+	}
+	if err != nil {
+		return Bracket{}, err
+	}
+	response := new(models.OrderResponse)
+	if err := json.Unmarshal(data, response); err != nil {
+		return Bracket{}, err
+	}
+
+	bm.idCount++
+	bracket := &Bracket{
+		Id:    bm.idCount,
+		Order: order,
+		State: BracketStatePending,
+		Entry: *managedOrderFromResponse(*response),
+	}
+	bm.brackets[bracket.Id] = bracket
+	bm.legs[response.ClientOrderId] = bracketLeg{bracket: bracket, leg: bracketLegEntry}
+	bm.Logger.Info("bracket ", bracket.Id, " entry placed ", response.ClientOrderId)
+	bm.step(bracket)
+	return bm.snapshot(bracket), nil
+}
+
+// HandleMessage accepts raw user stream messages, anything other than ORDER_TRADE_UPDATE is ignored.
+func (bm *BracketManager) HandleMessage(message []byte) error {
+	meta, err := ParseMetaInformation(message)
+	if err != nil {
+		return err
+	}
+	if meta.Event != models.EventOrder {
+		return nil
+	}
+	update := new(models.StreamOrderUpdate)
+	if err := json.Unmarshal(message, update); err != nil {
+		return err
+	}
+	bm.HandleOrderUpdate(*update)
+	return nil
+}
+
+func (bm *BracketManager) HandleOrderUpdate(update models.StreamOrderUpdate) {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	bm.applyLeg(managedOrderFromUpdate(update))
+}
+
+// Reconcile queries every leg of the open brackets over REST and applies the result.
+func (bm *BracketManager) Reconcile() error {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	var lastErr error
+	for clientOrderId, leg := range bm.legs {
+		data, err := bm.Api.QueryOrder(leg.bracket.Order.Symbol, clientOrderId, 0)
+		if err != nil {
+			bm.Logger.Error("bracket ", leg.bracket.Id, " reconcile failed for ", clientOrderId, err)
+			lastErr = err
+			continue
+		}
+		response := new(models.OrderResponse)
+		if err := json.Unmarshal(data, response); err != nil {
+			lastErr = err
+			continue
+		}
+		bm.applyLeg(managedOrderFromResponse(*response))
+	}
+	return lastErr
+}
+
+// Cancel cancels the entry and both exits, an already opened position is left untouched.
+func (bm *BracketManager) Cancel(id int64) error {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	bracket, exists := bm.brackets[id]
+	if !exists {
+		return fmt.Errorf("bracket %d does not exist", id)
+	}
+	var lastErr error
+	for _, order := range []*ManagedOrder{&bracket.Entry, bracket.TakeProfit, bracket.StopLoss} {
+		if err := bm.cancelLeg(order); err != nil {
+			lastErr = err
+		}
+	}
+	bm.finish(bracket, BracketStateCanceled)
+	return lastErr
+}
+
+func (bm *BracketManager) Get(id int64) (Bracket, bool) {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	bracket, exists := bm.brackets[id]
+	if !exists {
+		return Bracket{}, false
+	}
+	return bm.snapshot(bracket), true
+}
+
+func (bm *BracketManager) Brackets() []Bracket {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	brackets := make([]Bracket, 0, len(bm.brackets))
+	for _, bracket := range bm.brackets {
+		brackets = append(brackets, bm.snapshot(bracket))
+	}
+	return brackets
+}
+
+func (bm *BracketManager) snapshot(bracket *Bracket) Bracket {
+	copied := *bracket
+	if bracket.TakeProfit != nil {
+		takeProfit := *bracket.TakeProfit
+		copied.TakeProfit = &takeProfit
+	}
+	if bracket.StopLoss != nil {
+		stopLoss := *bracket.StopLoss
+		copied.StopLoss = &stopLoss
+	}
+	return copied
+}
+
+// Updates of replaced or unknown orders are ignored
+func (bm *BracketManager) applyLeg(update *ManagedOrder) {
+	leg, exists := bm.legs[update.ClientOrderId]
+	if !exists {
+		return
+	}
+	bracket := leg.bracket
+	var current *ManagedOrder
+	switch leg.leg {
+	case bracketLegEntry:
+		current = &bracket.Entry
+	case bracketLegTakeProfit:
+		current = bracket.TakeProfit
+	case bracketLegStopLoss:
+		current = bracket.StopLoss
+	}
+	if current == nil || !isNewerOrderState(current, update) {
+		return
+	}
+	update.Acknowledged = true
+	*current = *update
+	bm.step(bracket)
+}
+
+// step moves the bracket forward from the current state of its legs
+func (bm *BracketManager) step(bracket *Bracket) {
+	if bracket.State != BracketStatePending && bracket.State != BracketStateActive {
+		return
+	}
+
+	// One exit done, the other one is cancelled
+	for _, exit := range [][2]*ManagedOrder{{bracket.TakeProfit, bracket.StopLoss}, {bracket.StopLoss, bracket.TakeProfit}} {
+		if exit[0] != nil && exit[0].Status == models.OrderStatusFilled {
+			if err := bm.cancelLeg(exit[1]); err != nil {
+				bm.cancelFailed(bracket, err)
+				return
+			}
+			bm.Logger.Info("bracket ", bracket.Id, " closed by ", exit[0].ClientOrderId)
+			bm.finish(bracket, BracketStateClosed)
+			return
+		}
+	}
+
+	entry := bracket.Entry
+	if entry.FilledQuantity == 0 {
+		if !entry.IsOpen() {
+			bm.finish(bracket, BracketStateCanceled)
+		}
+		return
+	}
+	bracket.State = BracketStateActive
+
+	if bracket.Order.ClosePosition {
+		if bracket.TakeProfit == nil && bracket.StopLoss == nil {
+			bm.placeExits(bracket, 0)
+		}
+		return
+	}
+
+	// Exits always cover the filled part of the entry which is not closed yet
+	step := bm.quantityStep(bracket.Order.Symbol)
+	remaining := RoundDownToStep(entry.FilledQuantity-bracket.exitFilledQuantity(), step)
+	if remaining <= 0 {
+		return
+	}
+	for _, exit := range []*ManagedOrder{bracket.TakeProfit, bracket.StopLoss} {
+		if exit != nil && exit.IsOpen() && math.Abs(exit.RemainingQuantity()-remaining) < step/2 {
+			continue
+		}
+		if exit != nil && exit.IsOpen() {
+			bm.Logger.Info("bracket ", bracket.Id, " resizing exits to ", remaining)
+		}
+		if err := bm.cancelLeg(bracket.TakeProfit); err != nil {
+			bm.cancelFailed(bracket, err)
+			return
+		}
+		if err := bm.cancelLeg(bracket.StopLoss); err != nil {
+			bm.cancelFailed(bracket, err)
+			return
+		}
+		bm.placeExits(bracket, remaining)
+		return
+	}
+}
+
+// quantityStep step size of the symbol, quantities are compared within half of it
+func (bm *BracketManager) quantityStep(symbol string) float64 {
+	if filters, exists := bm.Filters[symbol]; exists && filters.StepSize > 0 {
+		return filters.StepSize
+	}
+	return defaultBracketQuantityStep
+}
+
+// placeExits the stop loss goes first, it protects the filled entry. When a leg can not be placed
+// the legs placed already are cancelled, no exit is left on the exchange of a failed bracket.
+func (bm *BracketManager) placeExits(bracket *Bracket, quantity float64) {
+	bracket.replacedExitFilled = bracket.exitFilledQuantity()
+	bracket.TakeProfit, bracket.StopLoss = nil, nil
+	var err error
+	bracket.StopLoss, err = bm.placeLeg(bracket, bracketLegStopLoss, OrderTypeStopMarket, bracket.Order.StopLossPrice, quantity)
+	if err == nil {
+		bracket.TakeProfit, err = bm.placeLeg(bracket, bracketLegTakeProfit, OrderTypeTakeProfitMarket, bracket.Order.TakeProfitPrice, quantity)
+	}
+	if err != nil {
+		bm.cancelExits(bracket)
+		bm.fail(bracket, err)
+	}
+}
+
+// placeLeg sends an exit up to ExitAttempts times
+func (bm *BracketManager) placeLeg(bracket *Bracket, leg, orderType string, stopPrice, quantity float64) (*ManagedOrder, error) {
+	side, symbol := bracket.exitSide(), bracket.Order.Symbol
+	var err error
+	for attempt := 1; ; attempt++ {
+		var data []byte
+		switch {
+		case bracket.Order.ClosePosition:
+			data, err = bm.Api.PlaceClosePositionOrder(symbol, side, orderType, stopPrice)
+		case orderType == OrderTypeStopMarket:
+			data, err = bm.Api.PlaceStopMarketOrder(symbol, side, stopPrice, quantity)
+		default:
+			data, err = bm.Api.PlaceTakeProfitMarketOrder(symbol, side, stopPrice, quantity)
+		}
+		if err == nil {
+			return bm.registerLeg(bracket, leg, data)
+		}
+		if attempt >= bm.ExitAttempts {
+			return nil, err
+		}
+		bm.Logger.Warn("bracket ", bracket.Id, " ", leg, " attempt ", attempt, " failed, retrying ", err)
+		time.Sleep(bm.ExitRetryWait)
+	}
+}
+
+// cancelExits legs which were filled or cancelled meanwhile are skipped
+func (bm *BracketManager) cancelExits(bracket *Bracket) {
+	for _, exit := range []*ManagedOrder{bracket.StopLoss, bracket.TakeProfit} {
+		if err := bm.cancelLeg(exit); err != nil && !isOrderNotOpen(err) {
+			bm.Logger.Error("bracket ", bracket.Id, " could not cancel exit ", exit.ClientOrderId, " ", err)
+		}
+	}
+}
+
+func (bm *BracketManager) registerLeg(bracket *Bracket, leg string, data []byte) (*ManagedOrder, error) {
+	response := new(models.OrderResponse)
+	if err := json.Unmarshal(data, response); err != nil {
+		return nil, err
+	}
+	bm.legs[response.ClientOrderId] = bracketLeg{bracket: bracket, leg: leg}
+	bm.Logger.Info("bracket ", bracket.Id, " ", leg, " placed ", response.ClientOrderId)
+	return managedOrderFromResponse(*response), nil
+}
+
+// cancelLeg forgets the order, so its CANCELED event is not mistaken for an exchange side change
+func (bm *BracketManager) cancelLeg(order *ManagedOrder) error {
+	if order == nil || !order.IsOpen() {
+		return nil
+	}
+	_, err := bm.Api.CancelSingleOrder(order.Symbol, order.ClientOrderId, order.OrderId)
+	if err != nil {
+		bm.Logger.Error("cancel failed for ", order.ClientOrderId, err)
+		return err
+	}
+	delete(bm.legs, order.ClientOrderId)
+	order.Status = models.OrderStatusCanceled
+	return nil
+}
+
+// cancelFailed a leg which was filled or cancelled in the meantime can not be cancelled, its update
+// is on the way and moves the bracket on. Any other failure fails the bracket.
+func (bm *BracketManager) cancelFailed(bracket *Bracket, err error) {
+	if isOrderNotOpen(err) {
+		bm.Logger.Warn("bracket ", bracket.Id, " leg is not open anymore, waiting for its update ", err)
+		return
+	}
+	bm.fail(bracket, err)
+}
+
+func (bm *BracketManager) fail(bracket *Bracket, err error) {
+	bm.Logger.Error("bracket ", bracket.Id, " failed ", err)
+	bracket.Err = err
+	bm.finish(bracket, BracketStateFailed)
+}
+
+func (bm *BracketManager) finish(bracket *Bracket, state string) {
+	bracket.State = state
+	for clientOrderId, leg := range bm.legs {
+		if leg.bracket == bracket {
+			delete(bm.legs, clientOrderId)
+		}
+	}
+}
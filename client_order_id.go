@@ -0,0 +1,175 @@
+package go_binance
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/redlon23/go-binance/models"
+	"github.com/sirupsen/logrus"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	MaxClientOrderIdLength       = 36
+	DefaultPlacementAttempts     = 3
+	DefaultPlacementLookupDelay  = time.Second
+	DefaultPlacementLookups      = 3
+	DefaultClientOrderIdTag      = "go-binance"
+	clientOrderIdSeparator       = "-"
+	clientOrderIdReplacementChar = "_"
+)
+
+// ClientOrderIdGenerator builds ids as tag-timestamp-sequence, timestamp in milliseconds and
+// sequence are base 36. The tag is shortened so the id stays within MaxClientOrderIdLength,
+// characters binance does not accept are replaced with underscores.
+type ClientOrderIdGenerator struct {
+	Tag      string
+	sequence int64
+}
+
+func NewClientOrderIdGenerator(tag string) *ClientOrderIdGenerator {
+	return &ClientOrderIdGenerator{Tag: sanitizeClientOrderIdTag(tag)}
+}
+
+// binance accepts ^[\.A-Z\:/a-z0-9_-]{1,36}$
+func sanitizeClientOrderIdTag(tag string) string {
+	var builder strings.Builder
+	for _, character := range tag {
+		allowed := (character >= 'a' && character <= 'z') || (character >= 'A' && character <= 'Z') ||
+			(character >= '0' && character <= '9') || strings.ContainsRune(".:/_-", character)
+		if allowed {
+			builder.WriteRune(character)
+		} else {
+			builder.WriteString(clientOrderIdReplacementChar)
+		}
+	}
+	return builder.String()
+}
+
+func (coig *ClientOrderIdGenerator) Next() string {
+	sequence := atomic.AddInt64(&coig.sequence, 1)
+	timestamp := time.Now().UnixNano() / int64(time.Millisecond)
+	suffix := clientOrderIdSeparator + strconv.FormatInt(timestamp, 36) +
+		clientOrderIdSeparator + strconv.FormatInt(sequence, 36)
+	tag := coig.Tag
+	if len(tag)+len(suffix) > MaxClientOrderIdLength {
+		tag = tag[:MaxClientOrderIdLength-len(suffix)]
+	}
+	return tag + suffix
+}
+
+// ParseClientOrderId reverses Next, ok is false for ids of other formats.
+func ParseClientOrderId(clientOrderId string) (tag string, timestamp, sequence int64, ok bool) {
+	sequenceStart := strings.LastIndex(clientOrderId, clientOrderIdSeparator)
+	if sequenceStart <= 0 {
+		return "", 0, 0, false
+	}
+	timestampStart := strings.LastIndex(clientOrderId[:sequenceStart], clientOrderIdSeparator)
+	if timestampStart < 0 {
+		return "", 0, 0, false
+	}
+	timestamp, err := strconv.ParseInt(clientOrderId[timestampStart+1:sequenceStart], 36, 64)
+	if err != nil {
+		return "", 0, 0, false
+	}
+	sequence, err = strconv.ParseInt(clientOrderId[sequenceStart+1:], 36, 64)
+	if err != nil {
+		return "", 0, 0, false
+	}
+	return clientOrderId[:timestampStart], timestamp, sequence, true
+}
+
+// ClientOrderIdTag strategy tag of a generated id, fits TradeLedger.TagResolver.
+func ClientOrderIdTag(clientOrderId string) string {
+	tag, _, _, _ := ParseClientOrderId(clientOrderId)
+	return tag
+}
+
+// isUnknownOrderStatus errors after which the order may or may not have been placed
+func isUnknownOrderStatus(err error) bool {
+	var netError net.Error
+	if errors.As(err, &netError) && netError.Timeout() {
+		return true
+	}
+	// The websocket api request may have been executed without its response arriving
+	if errors.Is(err, ErrWsApiTimeout) || errors.Is(err, ErrWsApiDisconnected) {
+		return true
+	}
+	var requestError *RequestError
+	if errors.As(err, &requestError) {
+		return requestError.Message.Code == UnexpectedResponse || requestError.Message.Code == RequestTimeout ||
+			requestError.StatusCode == http.StatusServiceUnavailable
+	}
+	return false
+}
+
+func copyParameters(parameters url.Values) url.Values {
+	copied := make(url.Values, len(parameters))
+	for key, values := range parameters {
+		copied[key] = append([]string(nil), values...)
+	}
+	return copied
+}
+
+// placeIdempotent sends the order and, when its status is unknown, looks it up by client order id.
+// An existing order is returned as the placement result. A missing one is looked up again with
+// a growing delay, an order still in flight can be unknown to the first lookups, and only sent
+// again with the same id when every lookup agrees it does not exist.
+func placeIdempotent(logger *logrus.Logger, parameters url.Values, send func(url.Values) ([]byte, error),
+	query func(symbol, clientOrderId string) ([]byte, error)) ([]byte, error) {
+	symbol, clientOrderId := parameters.Get("symbol"), parameters.Get("newClientOrderId")
+	var err error
+	for attempt := 0; attempt < DefaultPlacementAttempts; attempt++ {
+		var data []byte
+		data, err = send(copyParameters(parameters))
+		if err == nil || !isUnknownOrderStatus(err) {
+			return data, err
+		}
+		logger.Warn("order status unknown for ", clientOrderId, ", looking it up: ", err)
+
+		data, placed, known := lookupPlacedOrder(logger, symbol, clientOrderId, query)
+		if !known {
+			// Sending again without knowing is how orders get doubled
+			return nil, err
+		}
+		if placed {
+			logger.Info("order ", clientOrderId, " was placed, returning existing order")
+			return data, nil
+		}
+		logger.Info("order ", clientOrderId, " was not placed, sending again")
+	}
+	return nil, err
+}
+
+// lookupPlacedOrder known is false when the lookups failed or found an order with another id,
+// placed tells whether the order exists when known.
+func lookupPlacedOrder(logger *logrus.Logger, symbol, clientOrderId string,
+	query func(symbol, clientOrderId string) ([]byte, error)) (data []byte, placed, known bool) {
+	delay := DefaultPlacementLookupDelay
+	for lookup := 0; lookup < DefaultPlacementLookups; lookup++ {
+		time.Sleep(delay)
+		delay *= 2
+
+		data, err := query(symbol, clientOrderId)
+		var requestError *RequestError
+		switch {
+		case err == nil:
+			existing := new(models.OrderResponse)
+			if json.Unmarshal(data, existing) == nil && existing.ClientOrderId == clientOrderId {
+				return data, true, true
+			}
+			return nil, false, false
+		case errors.As(err, &requestError) && requestError.Message.Code == OrderDoesNotExist:
+			logger.Info("order ", clientOrderId, " not found by lookup ", lookup+1, " of ", DefaultPlacementLookups)
+		default:
+			logger.Error("lookup of ", clientOrderId, " failed: ", err)
+			return nil, false, false
+		}
+	}
+	return nil, false, true
+}
@@ -0,0 +1,54 @@
+package go_binance
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/redlon23/go-binance/models"
+	"strings"
+)
+
+const (
+	rawStreamPath      = "ws/"
+	combinedStreamPath = "stream?streams="
+)
+
+// StreamName binance stream name of a symbol, e.g. btcusdt@bookTicker.
+// All market streams have no symbol, StreamName("", "!ticker@arr") is !ticker@arr.
+func StreamName(symbol, streamType string) string {
+	if symbol == "" {
+		return streamType
+	}
+	return fmt.Sprintf("%s@%s", strings.ToLower(symbol), streamType)
+}
+
+// combinedStreamUrl builds the combined endpoint from the raw stream base url,
+// wss://fstream.binance.com/ws/ becomes wss://fstream.binance.com/stream?streams=a/b/c
+func combinedStreamUrl(baseUrl string, streams []string) string {
+	return strings.TrimSuffix(baseUrl, rawStreamPath) + combinedStreamPath + strings.Join(streams, "/")
+}
+
+// UnwrapCombinedMessage returns the stream name and payload of a combined stream envelope.
+// Messages without an envelope, like user stream events and subscription results,
+// are returned unchanged with an empty stream name.
+func UnwrapCombinedMessage(message []byte) (stream string, data []byte, err error) {
+	if !strings.Contains(string(message), `"stream"`) {
+		return "", message, nil
+	}
+	envelope := new(models.CombinedStreamMessage)
+	if err := json.Unmarshal(message, envelope); err != nil {
+		return "", nil, err
+	}
+	if envelope.Stream == "" || len(envelope.Data) == 0 {
+		return "", message, nil
+	}
+	return envelope.Stream, envelope.Data, nil
+}
+
+// ReadStreamMessage reads the next message of the socket and unwraps it.
+func ReadStreamMessage(socket BinanceFutureSocket) (stream string, data []byte, err error) {
+	_, message, err := socket.ReadFromConnection()
+	if err != nil {
+		return "", nil, err
+	}
+	return UnwrapCombinedMessage(message)
+}
@@ -0,0 +1,245 @@
+package go_binance
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+const (
+	// DeliveryBlocking the producer waits while the queue is full, a slow consumer stalls the reader
+	DeliveryBlocking = "BLOCKING"
+	// DeliveryDropOldest a full queue discards its oldest message to make room
+	DeliveryDropOldest = "DROP_OLDEST"
+	// DeliveryDropNewest a full queue discards the incoming message
+	DeliveryDropNewest = "DROP_NEWEST"
+	// DeliveryConflate only the latest message of every key is kept, at the position of the first
+	DeliveryConflate = "CONFLATE"
+
+	DefaultDeliveryQueueSize = 1000
+)
+
+// DeliveryStats counters of a DeliveryQueue, MaxQueued is the high water mark for sizing the queue.
+type DeliveryStats struct {
+	Pushed    int64
+	Delivered int64
+	Dropped   int64
+	Conflated int64
+	Queued    int
+	MaxQueued int
+}
+
+type deliverySlot[T any] struct {
+	key   string
+	value T
+}
+
+// DeliveryQueue decouples a stream reader from a slow consumer with one of the Delivery policies.
+// Size bounds the queue, for DeliveryConflate it bounds the number of keys and 0 is unbounded.
+// Key gives the conflation key of a message, messages with an empty key are never conflated.
+// An unbounded conflating queue still holds at most DefaultDeliveryQueueSize messages without
+// key, further ones are dropped.
+type DeliveryQueue[T any] struct {
+	Policy string
+	Size   int
+	Key    func(T) string
+
+	mu       sync.Mutex
+	ready    *sync.Cond
+	space    *sync.Cond
+	slots    []*deliverySlot[T]
+	byKey    map[string]*deliverySlot[T]
+	keyless  int
+	stats    DeliveryStats
+	closed   bool
+	draining bool
+}
+
+// NewDeliveryQueue policy is one of the Delivery constants, anything else is rejected.
+func NewDeliveryQueue[T any](policy string, size int) (*DeliveryQueue[T], error) {
+	switch policy {
+	case DeliveryBlocking, DeliveryDropOldest, DeliveryDropNewest, DeliveryConflate:
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownDeliveryPolicy, policy)
+	}
+	return newDeliveryQueue[T](policy, size), nil
+}
+
+func newDeliveryQueue[T any](policy string, size int) *DeliveryQueue[T] {
+	dq := &DeliveryQueue[T]{Policy: policy, Size: size, byKey: make(map[string]*deliverySlot[T])}
+	dq.ready = sync.NewCond(&dq.mu)
+	dq.space = sync.NewCond(&dq.mu)
+	return dq
+}
+
+// NewConflatingQueue keeps the latest message of every key, e.g. per symbol for bookTicker.
+func NewConflatingQueue[T any](key func(T) string) *DeliveryQueue[T] {
+	dq := newDeliveryQueue[T](DeliveryConflate, 0)
+	dq.Key = key
+	return dq
+}
+
+// capacity bound of the queue, drop and blocking policies hold at least one message
+func (dq *DeliveryQueue[T]) capacity() int {
+	if dq.Policy == DeliveryConflate {
+		return dq.Size
+	}
+	if dq.Size <= 0 {
+		return 1
+	}
+	return dq.Size
+}
+
+// Push queues a message according to the policy, false when it was dropped or the queue is closed.
+func (dq *DeliveryQueue[T]) Push(value T) bool {
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+	if dq.closed {
+		return false
+	}
+	dq.stats.Pushed++
+
+	key := ""
+	if dq.Policy == DeliveryConflate && dq.Key != nil {
+		key = dq.Key(value)
+	}
+	if slot, exists := dq.byKey[key]; key != "" && exists {
+		slot.value = value
+		dq.stats.Conflated++
+		return true
+	}
+	if key == "" && dq.Policy == DeliveryConflate && dq.Size <= 0 && dq.keyless >= DefaultDeliveryQueueSize {
+		// Keys bound an unbounded conflating queue, messages without key are not bounded by them
+		dq.stats.Dropped++
+		return false
+	}
+
+	capacity := dq.capacity()
+	if capacity > 0 && len(dq.slots) >= capacity {
+		switch dq.Policy {
+		case DeliveryBlocking:
+			for len(dq.slots) >= capacity && !dq.closed {
+				dq.space.Wait()
+			}
+			if dq.closed {
+				return false
+			}
+		case DeliveryDropOldest, DeliveryConflate:
+			dq.removeFirst()
+			dq.stats.Dropped++
+		default:
+			// DeliveryDropNewest, and policies set on the field without validation
+			dq.stats.Dropped++
+			return false
+		}
+	}
+
+	slot := &deliverySlot[T]{key: key, value: value}
+	dq.slots = append(dq.slots, slot)
+	if key != "" {
+		dq.byKey[key] = slot
+	} else {
+		dq.keyless++
+	}
+	if len(dq.slots) > dq.stats.MaxQueued {
+		dq.stats.MaxQueued = len(dq.slots)
+	}
+	dq.ready.Signal()
+	return true
+}
+
+func (dq *DeliveryQueue[T]) removeFirst() *deliverySlot[T] {
+	slot := dq.slots[0]
+	dq.slots[0] = nil
+	dq.slots = dq.slots[1:]
+	if slot.key != "" {
+		delete(dq.byKey, slot.key)
+	} else {
+		dq.keyless--
+	}
+	dq.space.Signal()
+	return slot
+}
+
+// Pop blocks until a message is queued, false once the queue is closed and empty.
+func (dq *DeliveryQueue[T]) Pop() (T, bool) {
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+	for len(dq.slots) == 0 && !dq.closed {
+		dq.ready.Wait()
+	}
+	if len(dq.slots) == 0 {
+		var empty T
+		return empty, false
+	}
+	dq.stats.Delivered++
+	return dq.removeFirst().value, true
+}
+
+func (dq *DeliveryQueue[T]) Len() int {
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+	return len(dq.slots)
+}
+
+func (dq *DeliveryQueue[T]) Stats() DeliveryStats {
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+	stats := dq.stats
+	stats.Queued = len(dq.slots)
+	return stats
+}
+
+// Close wakes blocked producers and consumers, queued messages can still be popped.
+func (dq *DeliveryQueue[T]) Close() {
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+	dq.closed = true
+	dq.ready.Broadcast()
+	dq.space.Broadcast()
+}
+
+// Deliver runs handler on its own goroutine for every message of the queue and returns the
+// producer side, which fits the EventDispatcher handlers:
+// dispatcher.OnBookTicker(Deliver(NewConflatingQueue(func(ticker models.BookTicker) string {
+// return ticker.Symbol }), slowHandler))
+func Deliver[T any](queue *DeliveryQueue[T], handler func(T)) func(T) {
+	queue.mu.Lock()
+	if !queue.draining {
+		queue.draining = true
+		go func() {
+			for {
+				value, ok := queue.Pop()
+				if !ok {
+					return
+				}
+				handler(value)
+			}
+		}()
+	}
+	queue.mu.Unlock()
+	return func(value T) { queue.Push(value) }
+}
+
+// MessageKey conflation key of a raw or combined stream message, the stream name when it is
+// enveloped and event@symbol otherwise. Messages without symbol have an empty key.
+func MessageKey(message []byte) string {
+	stream, data, err := UnwrapCombinedMessage(message)
+	if err != nil {
+		return ""
+	}
+	if stream != "" {
+		return stream
+	}
+	// Both cases declared, encoding/json would match E and S to the lowercase fields
+	var identity struct {
+		Event     string          `json:"e"`
+		EventTime json.RawMessage `json:"E"`
+		Symbol    string          `json:"s"`
+		Side      json.RawMessage `json:"S"`
+	}
+	if json.Unmarshal(data, &identity) != nil || identity.Symbol == "" {
+		return ""
+	}
+	return identity.Event + "@" + identity.Symbol
+}
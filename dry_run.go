@@ -0,0 +1,123 @@
+package go_binance
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/redlon23/go-binance/models"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// OrderModeLive orders are placed on the exchange, default
+	OrderModeLive = ""
+	// OrderModeTest orders are sent to the test order endpoint, binance validates them without placing
+	OrderModeTest = "TEST"
+	// OrderModeDryRun orders are built and signed but never leave the process
+	OrderModeDryRun = "DRY_RUN"
+
+	dryRunClientIdStart = "dryrun-"
+)
+
+var dryRunOrderCounter int64
+
+// ValidateOrderMode rejects anything but OrderModeLive, OrderModeTest and OrderModeDryRun
+func ValidateOrderMode(mode string) error {
+	switch mode {
+	case OrderModeLive, OrderModeTest, OrderModeDryRun:
+		return nil
+	}
+	return fmt.Errorf("%w: %q", ErrUnknownOrderMode, mode)
+}
+
+// isLiveOrderMode only OrderModeLive reaches the exchange, an unknown mode set directly on
+// the OrderMode field is treated as dry run so a typo never places real orders.
+func isLiveOrderMode(mode string) bool {
+	return mode == OrderModeLive
+}
+
+// applySymbolFilters normalizes price, stop price and quantity of an order that is not placed and
+// validates the result, so test and dry runs reject what binance would. Unknown symbols pass unchanged.
+func applySymbolFilters(filters map[string]SymbolFilters, parameters url.Values) error {
+	symbolFilters, exists := filters[parameters.Get("symbol")]
+	if !exists {
+		return nil
+	}
+	normalize := func(key string, round func(float64) float64) float64 {
+		value, _ := strconv.ParseFloat(parameters.Get(key), 64)
+		if value != 0 {
+			value = round(value)
+			parameters.Set(key, strconv.FormatFloat(value, 'f', -1, 64))
+		}
+		return value
+	}
+	price := normalize("price", symbolFilters.NormalizePrice)
+	normalize("stopPrice", symbolFilters.NormalizePrice)
+	if closePosition, _ := strconv.ParseBool(parameters.Get("closePosition")); closePosition {
+		return nil
+	}
+	market := parameters.Get("type") != OrderTypeLimit
+	quantity := normalize("quantity", func(quantity float64) float64 {
+		return symbolFilters.NormalizeQuantity(quantity, market)
+	})
+	reduceOnly, _ := strconv.ParseBool(parameters.Get("reduceOnly"))
+	return symbolFilters.Validate(quantity, price, market, reduceOnly)
+}
+
+// syntheticOrderResponse OrderResponse shaped answer for an order that was not placed.
+// Order ids are negative so they can never collide with real ones.
+func syntheticOrderResponse(parameters url.Values) ([]byte, error) {
+	orderId := -atomic.AddInt64(&dryRunOrderCounter, 1)
+	clientOrderId := parameters.Get("newClientOrderId")
+	if clientOrderId == "" {
+		clientOrderId = fmt.Sprintf("%s%d", dryRunClientIdStart, -orderId)
+	}
+	price, _ := strconv.ParseFloat(parameters.Get("price"), 64)
+	quantity, _ := strconv.ParseFloat(parameters.Get("quantity"), 64)
+	stopPrice, _ := strconv.ParseFloat(parameters.Get("stopPrice"), 64)
+	reduceOnly, _ := strconv.ParseBool(parameters.Get("reduceOnly"))
+	if closePosition, _ := strconv.ParseBool(parameters.Get("closePosition")); closePosition {
+		reduceOnly = true
+	}
+	return json.Marshal(models.OrderResponse{
+		OrderId:       orderId,
+		Symbol:        parameters.Get("symbol"),
+		Side:          parameters.Get("side"),
+		Price:         price,
+		Quantity:      quantity,
+		ClientOrderId: clientOrderId,
+		Status:        models.OrderStatusNew,
+		Type:          parameters.Get("type"),
+		StopPrice:     stopPrice,
+		ReduceOnly:    reduceOnly,
+		UpdateTime:    time.Now().UnixNano() / int64(time.Millisecond),
+	})
+}
+
+// syntheticCancelResponse answer for a cancel that was not sent in dry run and test modes
+func syntheticCancelResponse(symbol, origClientOrderId string, orderId int64) ([]byte, error) {
+	return json.Marshal(models.OrderResponse{
+		OrderId:       orderId,
+		Symbol:        symbol,
+		ClientOrderId: origClientOrderId,
+		Status:        models.OrderStatusCanceled,
+		UpdateTime:    time.Now().UnixNano() / int64(time.Millisecond),
+	})
+}
+
+// syntheticModifyResponse answer for a modify that was not sent in dry run and test modes
+func syntheticModifyResponse(symbol, side, origClientOrderId string, orderId int64, price, qty float64) ([]byte, error) {
+	return json.Marshal(models.OrderResponse{
+		OrderId:       orderId,
+		Symbol:        symbol,
+		Side:          side,
+		Price:         price,
+		Quantity:      qty,
+		ClientOrderId: origClientOrderId,
+		Status:        models.OrderStatusNew,
+		Type:          OrderTypeLimit,
+		UpdateTime:    time.Now().UnixNano() / int64(time.Millisecond),
+	})
+}
@@ -1,17 +1,40 @@
 package go_binance
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
 
 const (
+	UnexpectedResponse = -1006
+	RequestTimeout = -1007
 	TimestampWrong = -1021
 	SignatureWrong = -1022
 	ParameterValueWrong = -1102
 	PrecisionWrong = -1111
 	SymbolWrong = -1121
+	ListenKeyDoesNotExist = -1125
+	CancelRejected = -2011
+	OrderDoesNotExist = -2013
+	OrderWouldTrigger = -2021
 	ApiKeyWrong = -2014
 	GreaterThanMaxQuantity = -4005
 )
 
+var (
+	ErrDownloadTimeout = errors.New("download link was not ready before timeout")
+	ErrStartTimeRequired = errors.New("startTime is required for paging from the beginning")
+	ErrConnectionExpired = errors.New("connection reached its maximum age")
+	ErrConnectionClosed = errors.New("connection was closed")
+	ErrStreamAckTimeout = errors.New("no response to stream request before timeout")
+	ErrStreamStale = errors.New("no message within the expected interval")
+	ErrWsApiTimeout = errors.New("no websocket api response before timeout")
+	ErrWsApiDisconnected = errors.New("websocket api connection was lost before the response")
+	ErrUnknownOrderMode = errors.New("unknown order mode")
+	ErrUnknownDeliveryPolicy = errors.New("unknown delivery policy")
+	ErrRecorderFull = errors.New("recorder buffer is full, frame dropped")
+)
+
 type BinanceErrorMessage struct {
 	Code int `json:"code"`
 	Message string `json:"msg"`
@@ -31,3 +54,32 @@ func (re *RequestError) Error() string {
 	return fmt.Sprintf("Status Code: %d - url used: %s - Code: %d Reason: %s", re.StatusCode,
 		re.UrlUsed, re.Message.Code, re.Message.Message)
 }
+
+// RiskError returned by RiskGuard when an order is blocked before reaching the network
+type RiskError struct {
+	Rule   string
+	Symbol string
+	Reason string
+}
+
+func (re *RiskError) Error() string {
+	return fmt.Sprintf("Order blocked by risk rule %s - symbol: %s - Reason: %s", re.Rule, re.Symbol, re.Reason)
+}
+
+// StreamRequestError websocket control request rejected by binance
+type StreamRequestError struct {
+	Id      int
+	Code    int
+	Message string
+}
+
+func (sre *StreamRequestError) Error() string {
+	return fmt.Sprintf("Stream request %d rejected - Code: %d Reason: %s", sre.Id, sre.Code, sre.Message)
+}
+
+// isOrderNotOpen cancel failures of orders which were filled, cancelled or triggered meanwhile
+func isOrderNotOpen(err error) bool {
+	var requestError *RequestError
+	return errors.As(err, &requestError) &&
+		(requestError.Message.Code == CancelRejected || requestError.Message.Code == OrderDoesNotExist)
+}
@@ -0,0 +1,344 @@
+package go_binance
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/redlon23/go-binance/models"
+	"sync"
+)
+
+// DecodeError a frame was read but could not be decoded, the connection itself is fine.
+type DecodeError struct {
+	Event   string
+	Stream  string
+	Message []byte
+	Err     error
+}
+
+func (de *DecodeError) Error() string {
+	return fmt.Sprintf("could not decode %s message of stream %s: %v", de.Event, de.Stream, de.Err)
+}
+
+func (de *DecodeError) Unwrap() error {
+	return de.Err
+}
+
+type eventRoute struct {
+	decode   func([]byte) (interface{}, error)
+	handlers []func(interface{})
+}
+
+// EventDispatcher decodes every frame once and routes it by event type to typed handlers.
+// Combined stream envelopes are unwrapped and array payloads of all market streams are
+// dispatched element by element. Handlers run on the goroutine calling Dispatch or Run.
+type EventDispatcher struct {
+	mu        sync.RWMutex
+	routes    map[string]*eventRoute
+	responses []func(models.StreamResponse)
+	fallbacks []func(event string, message []byte)
+	decodeErr []func(*DecodeError)
+}
+
+func NewEventDispatcher() *EventDispatcher {
+	return &EventDispatcher{routes: make(map[string]*eventRoute)}
+}
+
+// ChannelHandler handler which sends to a new buffered channel, for consumers preferring channels:
+// bookTickers, handler := ChannelHandler[models.BookTicker](100); dispatcher.OnBookTicker(handler)
+func ChannelHandler[T any](size int) (<-chan T, func(T)) {
+	channel := make(chan T, size)
+	return channel, func(value T) { channel <- value }
+}
+
+func (ed *EventDispatcher) route(event string, decode func([]byte) (interface{}, error), handler func(interface{})) {
+	ed.mu.Lock()
+	defer ed.mu.Unlock()
+	route, exists := ed.routes[event]
+	if !exists {
+		route = &eventRoute{decode: decode}
+		ed.routes[event] = route
+	}
+	route.handlers = append(route.handlers, handler)
+}
+
+// On registers a handler for the raw message of an event type, without decoding.
+func (ed *EventDispatcher) On(event string, handler func([]byte)) {
+	ed.route("raw:"+event, func(message []byte) (interface{}, error) { return message, nil },
+		func(value interface{}) { handler(value.([]byte)) })
+}
+
+// OnUnknown receives messages of event types without handlers.
+func (ed *EventDispatcher) OnUnknown(handler func(event string, message []byte)) {
+	ed.mu.Lock()
+	defer ed.mu.Unlock()
+	ed.fallbacks = append(ed.fallbacks, handler)
+}
+
+// OnDecodeError receives frames which could not be decoded, Run keeps reading after them.
+func (ed *EventDispatcher) OnDecodeError(handler func(*DecodeError)) {
+	ed.mu.Lock()
+	defer ed.mu.Unlock()
+	ed.decodeErr = append(ed.decodeErr, handler)
+}
+
+// OnStreamResponse receives responses to subscribe and other control requests.
+func (ed *EventDispatcher) OnStreamResponse(handler func(models.StreamResponse)) {
+	ed.mu.Lock()
+	defer ed.mu.Unlock()
+	ed.responses = append(ed.responses, handler)
+}
+
+func (ed *EventDispatcher) OnBookTicker(handler func(models.BookTicker)) {
+	ed.route(models.EventBookTicker, func(message []byte) (interface{}, error) {
+		value := models.BookTicker{}
+		err := json.Unmarshal(message, &value)
+		return value, err
+	}, func(value interface{}) { handler(value.(models.BookTicker)) })
+}
+
+func (ed *EventDispatcher) OnAggTrade(handler func(models.AggTrade)) {
+	ed.route(models.EventAggTrade, func(message []byte) (interface{}, error) {
+		value := models.AggTrade{}
+		err := json.Unmarshal(message, &value)
+		return value, err
+	}, func(value interface{}) { handler(value.(models.AggTrade)) })
+}
+
+func (ed *EventDispatcher) OnMarkPrice(handler func(models.MarkPriceUpdate)) {
+	ed.route(models.EventMarkPrice, func(message []byte) (interface{}, error) {
+		value := models.MarkPriceUpdate{}
+		err := json.Unmarshal(message, &value)
+		return value, err
+	}, func(value interface{}) { handler(value.(models.MarkPriceUpdate)) })
+}
+
+func (ed *EventDispatcher) OnDepthUpdate(handler func(models.DepthUpdate)) {
+	ed.route(models.EventDepth, func(message []byte) (interface{}, error) {
+		value := models.DepthUpdate{}
+		err := json.Unmarshal(message, &value)
+		return value, err
+	}, func(value interface{}) { handler(value.(models.DepthUpdate)) })
+}
+
+func (ed *EventDispatcher) OnKline(handler func(models.KlineUpdate)) {
+	ed.route(models.EventKline, func(message []byte) (interface{}, error) {
+		value := models.KlineUpdate{}
+		err := json.Unmarshal(message, &value)
+		return value, err
+	}, func(value interface{}) { handler(value.(models.KlineUpdate)) })
+}
+
+func (ed *EventDispatcher) OnContinuousKline(handler func(models.ContinuousKlineUpdate)) {
+	ed.route(models.EventContinuousKline, func(message []byte) (interface{}, error) {
+		value := models.ContinuousKlineUpdate{}
+		err := json.Unmarshal(message, &value)
+		return value, err
+	}, func(value interface{}) { handler(value.(models.ContinuousKlineUpdate)) })
+}
+
+func (ed *EventDispatcher) OnMiniTicker(handler func(models.MiniTicker)) {
+	ed.route(models.EventMiniTicker, func(message []byte) (interface{}, error) {
+		value := models.MiniTicker{}
+		err := json.Unmarshal(message, &value)
+		return value, err
+	}, func(value interface{}) { handler(value.(models.MiniTicker)) })
+}
+
+func (ed *EventDispatcher) OnCompositeIndex(handler func(models.CompositeIndex)) {
+	ed.route(models.EventCompositeIndex, func(message []byte) (interface{}, error) {
+		value := models.CompositeIndex{}
+		err := json.Unmarshal(message, &value)
+		return value, err
+	}, func(value interface{}) { handler(value.(models.CompositeIndex)) })
+}
+
+func (ed *EventDispatcher) OnContractInfo(handler func(models.ContractInfo)) {
+	ed.route(models.EventContractInfo, func(message []byte) (interface{}, error) {
+		value := models.ContractInfo{}
+		err := json.Unmarshal(message, &value)
+		return value, err
+	}, func(value interface{}) { handler(value.(models.ContractInfo)) })
+}
+
+func (ed *EventDispatcher) OnAssetIndex(handler func(models.AssetIndex)) {
+	ed.route(models.EventAssetIndex, func(message []byte) (interface{}, error) {
+		value := models.AssetIndex{}
+		err := json.Unmarshal(message, &value)
+		return value, err
+	}, func(value interface{}) { handler(value.(models.AssetIndex)) })
+}
+
+func (ed *EventDispatcher) OnSymbolTicker(handler func(models.StreamSymbolTickerUpdate)) {
+	ed.route(models.EventSymbolTicker, func(message []byte) (interface{}, error) {
+		value := models.StreamSymbolTickerUpdate{}
+		err := json.Unmarshal(message, &value)
+		return value, err
+	}, func(value interface{}) { handler(value.(models.StreamSymbolTickerUpdate)) })
+}
+
+func (ed *EventDispatcher) OnLiquidation(handler func(models.LiquidationOrder)) {
+	ed.route(models.EventLiquidation, func(message []byte) (interface{}, error) {
+		value := models.LiquidationOrder{}
+		err := json.Unmarshal(message, &value)
+		return value, err
+	}, func(value interface{}) { handler(value.(models.LiquidationOrder)) })
+}
+
+func (ed *EventDispatcher) OnOrderUpdate(handler func(models.StreamOrderUpdate)) {
+	ed.route(models.EventOrder, func(message []byte) (interface{}, error) {
+		value := models.StreamOrderUpdate{}
+		err := json.Unmarshal(message, &value)
+		return value, err
+	}, func(value interface{}) { handler(value.(models.StreamOrderUpdate)) })
+}
+
+func (ed *EventDispatcher) OnAccountUpdate(handler func(models.StreamAccountUpdate)) {
+	ed.route(models.EventAccount, func(message []byte) (interface{}, error) {
+		value := models.StreamAccountUpdate{}
+		err := json.Unmarshal(message, &value)
+		return value, err
+	}, func(value interface{}) { handler(value.(models.StreamAccountUpdate)) })
+}
+
+func (ed *EventDispatcher) OnMarginCall(handler func(models.StreamMarginCall)) {
+	ed.route(models.EventMarginCall, func(message []byte) (interface{}, error) {
+		value := models.StreamMarginCall{}
+		err := json.Unmarshal(message, &value)
+		return value, err
+	}, func(value interface{}) { handler(value.(models.StreamMarginCall)) })
+}
+
+func (ed *EventDispatcher) OnAccountConfigUpdate(handler func(models.StreamAccountConfigUpdate)) {
+	ed.route(models.EventAccountConfig, func(message []byte) (interface{}, error) {
+		value := models.StreamAccountConfigUpdate{}
+		err := json.Unmarshal(message, &value)
+		return value, err
+	}, func(value interface{}) { handler(value.(models.StreamAccountConfigUpdate)) })
+}
+
+func (ed *EventDispatcher) OnTradeLite(handler func(models.StreamTradeLite)) {
+	ed.route(models.EventTradeLite, func(message []byte) (interface{}, error) {
+		value := models.StreamTradeLite{}
+		err := json.Unmarshal(message, &value)
+		return value, err
+	}, func(value interface{}) { handler(value.(models.StreamTradeLite)) })
+}
+
+func (ed *EventDispatcher) OnStrategyUpdate(handler func(models.StreamStrategyUpdate)) {
+	ed.route(models.EventStrategyUpdate, func(message []byte) (interface{}, error) {
+		value := models.StreamStrategyUpdate{}
+		err := json.Unmarshal(message, &value)
+		return value, err
+	}, func(value interface{}) { handler(value.(models.StreamStrategyUpdate)) })
+}
+
+func (ed *EventDispatcher) OnGridUpdate(handler func(models.StreamGridUpdate)) {
+	ed.route(models.EventGridUpdate, func(message []byte) (interface{}, error) {
+		value := models.StreamGridUpdate{}
+		err := json.Unmarshal(message, &value)
+		return value, err
+	}, func(value interface{}) { handler(value.(models.StreamGridUpdate)) })
+}
+
+func (ed *EventDispatcher) OnConditionalOrderReject(handler func(models.StreamConditionalOrderReject)) {
+	ed.route(models.EventConditionalOrderReject, func(message []byte) (interface{}, error) {
+		value := models.StreamConditionalOrderReject{}
+		err := json.Unmarshal(message, &value)
+		return value, err
+	}, func(value interface{}) { handler(value.(models.StreamConditionalOrderReject)) })
+}
+
+func (ed *EventDispatcher) OnListenKeyExpired(handler func(models.StreamListenKeyExpired)) {
+	ed.route(models.EventListenKeyExpired, func(message []byte) (interface{}, error) {
+		value := models.StreamListenKeyExpired{}
+		err := json.Unmarshal(message, &value)
+		return value, err
+	}, func(value interface{}) { handler(value.(models.StreamListenKeyExpired)) })
+}
+
+// Dispatch routes a single frame, the returned error is always a *DecodeError.
+func (ed *EventDispatcher) Dispatch(message []byte) error {
+	stream, data, err := UnwrapCombinedMessage(message)
+	if err != nil {
+		return ed.failed(&DecodeError{Message: message, Err: err})
+	}
+	if len(data) > 0 && data[0] == '[' {
+		elements := make([]json.RawMessage, 0)
+		if err := json.Unmarshal(data, &elements); err != nil {
+			return ed.failed(&DecodeError{Stream: stream, Message: message, Err: err})
+		}
+		var firstErr error
+		for _, element := range elements {
+			if err := ed.dispatchEvent(stream, element); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		return firstErr
+	}
+	return ed.dispatchEvent(stream, data)
+}
+
+func (ed *EventDispatcher) dispatchEvent(stream string, data []byte) error {
+	meta := new(models.StreamMetaMessage)
+	if err := json.Unmarshal(data, meta); err != nil {
+		return ed.failed(&DecodeError{Stream: stream, Message: data, Err: err})
+	}
+
+	ed.mu.RLock()
+	route, raw := ed.routes[meta.Event], ed.routes["raw:"+meta.Event]
+	responses, fallbacks := ed.responses, ed.fallbacks
+	ed.mu.RUnlock()
+
+	if meta.Event == "" {
+		response := models.StreamResponse{}
+		if err := json.Unmarshal(data, &response); err == nil && (response.Result != nil || response.Error != nil) {
+			for _, handler := range responses {
+				handler(response)
+			}
+			return nil
+		}
+	}
+	if route == nil && raw == nil {
+		for _, fallback := range fallbacks {
+			fallback(meta.Event, data)
+		}
+		return nil
+	}
+	if raw != nil {
+		for _, handler := range raw.handlers {
+			handler(data)
+		}
+	}
+	if route != nil {
+		value, err := route.decode(data)
+		if err != nil {
+			return ed.failed(&DecodeError{Event: meta.Event, Stream: stream, Message: data, Err: err})
+		}
+		for _, handler := range route.handlers {
+			handler(value)
+		}
+	}
+	return nil
+}
+
+func (ed *EventDispatcher) failed(decodeError *DecodeError) error {
+	ed.mu.RLock()
+	handlers := ed.decodeErr
+	ed.mu.RUnlock()
+	for _, handler := range handlers {
+		handler(decodeError)
+	}
+	return decodeError
+}
+
+// Run reads and dispatches until the socket returns an error, which is a transport error.
+// Decoding errors go to OnDecodeError handlers and do not stop reading.
+func (ed *EventDispatcher) Run(socket BinanceFutureSocket) error {
+	for {
+		_, message, err := socket.ReadFromConnection()
+		if err != nil {
+			return err
+		}
+		_ = ed.Dispatch(message)
+	}
+}
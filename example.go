@@ -1,9 +1,7 @@
 package go_binance
 
 import (
-	"encoding/json"
 	"fmt"
-	"github.com/gorilla/websocket"
 	"github.com/redlon23/go-binance/models"
 	"log"
 )
@@ -11,31 +9,28 @@ import (
 // pass as many channel as you subscribe to different streams
 // in this example, assume you just use ticker and liquidation
 // you should also pass same channels to your handlers to process them.
-func channelControl(conn *websocket.Conn, tickerChan, liquidationChan chan []byte) {
+// The dispatcher routes every frame by its event type, see EventDispatcher for typed handlers.
+func channelControl(socket BinanceFutureSocket, tickerChan, liquidationChan chan []byte) {
 	defer func() {
-		connectionError := conn.Close()
+		connectionError := socket.CloseConnection()
 		if connectionError != nil {
 			log.Fatal(connectionError)
 		}
 	}()
-	for {
-		_, msg, err := conn.ReadMessage()
-		if err != nil {
-			log.Println(err)
-			continue
-		}
-		meta := new(models.StreamMetaMessage)
-		err = json.Unmarshal(msg, &meta)
-		if err != nil {
-			fmt.Println(err)
-			continue
-		}
-		if meta.Event == models.EventSymbolTicker {
-			tickerChan <- msg
-		} else if meta.Event == models.EventLiquidation {
-			liquidationChan <- msg
-		} else {
-			fmt.Println(string(msg))
-		}
+	dispatcher := NewEventDispatcher()
+	dispatcher.On(models.EventSymbolTicker, func(msg []byte) {
+		tickerChan <- msg
+	})
+	dispatcher.On(models.EventLiquidation, func(msg []byte) {
+		liquidationChan <- msg
+	})
+	dispatcher.OnUnknown(func(event string, msg []byte) {
+		fmt.Println(string(msg))
+	})
+	dispatcher.OnDecodeError(func(err *DecodeError) {
+		fmt.Println(err)
+	})
+	if err := dispatcher.Run(socket); err != nil {
+		log.Println(err)
 	}
 }
\ No newline at end of file
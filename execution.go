@@ -0,0 +1,498 @@
+package go_binance
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/redlon23/go-binance/models"
+	"github.com/sirupsen/logrus"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	ExecutionTwap    = "TWAP"
+	ExecutionIceberg = "ICEBERG"
+	ExecutionPov     = "POV"
+
+	ExecutionStateIdle     = "IDLE"
+	ExecutionStateRunning  = "RUNNING"
+	ExecutionStatePaused   = "PAUSED"
+	ExecutionStateCanceled = "CANCELED"
+	ExecutionStateDone     = "DONE"
+	ExecutionStateFailed   = "FAILED"
+)
+
+// ExecutionConfig parent order shared by every algorithm.
+// Filters are applied to every child order, leave them empty to send quantities as they are.
+// ArrivalPrice is the slippage reference, mid price of the order book at Start is used when 0.
+type ExecutionConfig struct {
+	Symbol       string
+	Side         string
+	Quantity     float64
+	ReduceOnly   bool
+	Filters      SymbolFilters
+	ArrivalPrice float64
+}
+
+// TwapConfig Quantity is split into Slices market orders spread evenly over Duration.
+// Randomization 0.2 varies every child size by up to ±20%.
+type TwapConfig struct {
+	Duration      time.Duration
+	Slices        int
+	Randomization float64
+}
+
+// IcebergConfig only VisibleQuantity rests on the book at Price, refilled after every fill.
+type IcebergConfig struct {
+	VisibleQuantity float64
+	Price           float64
+}
+
+// PovConfig follows Participation (0.1 = 10%) of the aggTrade volume seen after Start.
+// Children are sent once at least MinChildQuantity is due and capped at MaxChildQuantity, 0 means no cap.
+type PovConfig struct {
+	Participation    float64
+	MinChildQuantity float64
+	MaxChildQuantity float64
+}
+
+type ExecutionProgress struct {
+	Algorithm        string
+	State            string
+	Quantity         float64
+	SentQuantity     float64
+	FilledQuantity   float64
+	AverageFillPrice float64
+	ArrivalPrice     float64
+	// Positive slippage is worse than arrival price, in basis points
+	SlippageBps float64
+	ChildOrders int
+	Err         error
+}
+
+// Execution works a parent order through child orders of one algorithm.
+// Feed ORDER_TRADE_UPDATE events (and aggTrade for POV) through HandleMessage.
+type Execution struct {
+	Api    BinanceFutures
+	Logger *logrus.Logger
+
+	algorithm string
+	config    ExecutionConfig
+	twap      TwapConfig
+	iceberg   IcebergConfig
+	pov       PovConfig
+
+	mu           sync.Mutex
+	state        string
+	children     map[string]*ManagedOrder
+	marketVolume float64
+	sendingDone  bool
+	err          error
+	random       *rand.Rand
+	stop         chan struct{}
+	done         chan struct{}
+	// Children cancelled on purpose, their remaining quantity is not working anymore
+	cancelling map[string]bool
+}
+
+func newExecution(api BinanceFutures, algorithm string, config ExecutionConfig) *Execution {
+	return &Execution{
+		Api:        api,
+		Logger:     logrus.New(),
+		algorithm:  algorithm,
+		config:     config,
+		state:      ExecutionStateIdle,
+		children:   make(map[string]*ManagedOrder),
+		cancelling: make(map[string]bool),
+		random:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+func NewTwapExecution(api BinanceFutures, config ExecutionConfig, twap TwapConfig) *Execution {
+	execution := newExecution(api, ExecutionTwap, config)
+	execution.twap = twap
+	return execution
+}
+
+func NewIcebergExecution(api BinanceFutures, config ExecutionConfig, iceberg IcebergConfig) *Execution {
+	execution := newExecution(api, ExecutionIceberg, config)
+	execution.iceberg = iceberg
+	return execution
+}
+
+func NewPovExecution(api BinanceFutures, config ExecutionConfig, pov PovConfig) *Execution {
+	execution := newExecution(api, ExecutionPov, config)
+	execution.pov = pov
+	return execution
+}
+
+func (e *Execution) PrepareLoggers() {
+	e.Logger = logrus.New()
+	e.Logger.Formatter = new(logrus.JSONFormatter)
+
+	executionLogs, err := os.OpenFile("logs/binance_execution.log", os.O_CREATE|os.O_WRONLY, 0666)
+	if err == nil {
+		e.Logger.SetOutput(executionLogs)
+	} else {
+		fmt.Println("Failed to log to file for execution algorithms, using default stderr")
+	}
+}
+
+func (e *Execution) Start() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.state != ExecutionStateIdle {
+		return fmt.Errorf("execution already started, state %s", e.state)
+	}
+	if e.config.ArrivalPrice == 0 {
+		arrival, err := e.midPrice()
+		if err != nil {
+			return err
+		}
+		e.config.ArrivalPrice = arrival
+	}
+	e.state = ExecutionStateRunning
+	e.Logger.Info(e.algorithm, " started for ", e.config.Quantity, " ", e.config.Symbol, " arrival ", e.config.ArrivalPrice)
+
+	switch e.algorithm {
+	case ExecutionTwap:
+		if e.twap.Slices <= 0 {
+			e.twap.Slices = 1
+		}
+		go e.runTwap()
+	case ExecutionIceberg:
+		e.placeIcebergChild()
+	}
+	return nil
+}
+
+func (e *Execution) midPrice() (float64, error) {
+	data, err := e.Api.GetOrderBook(e.config.Symbol, 5)
+	if err != nil {
+		return 0, err
+	}
+	book := new(models.OrderBook)
+	if err := json.Unmarshal(data, book); err != nil {
+		return 0, err
+	}
+	if len(book.Bids) == 0 || len(book.Asks) == 0 {
+		return 0, errors.New("order book is empty, arrival price is unknown")
+	}
+	return (book.Bids[0].Price + book.Asks[0].Price) / 2, nil
+}
+
+// Pause stops sending children, a resting iceberg child is cancelled.
+func (e *Execution) Pause() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.state != ExecutionStateRunning {
+		return
+	}
+	e.state = ExecutionStatePaused
+	e.cancelOpenChildren()
+	e.Logger.Info(e.algorithm, " paused")
+}
+
+func (e *Execution) Resume() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.state != ExecutionStatePaused {
+		return
+	}
+	e.state = ExecutionStateRunning
+	e.Logger.Info(e.algorithm, " resumed")
+	if e.algorithm == ExecutionIceberg {
+		e.placeIcebergChild()
+	}
+}
+
+// Cancel stops the execution for good, open children are cancelled and fills are kept.
+func (e *Execution) Cancel() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.isFinished() {
+		return
+	}
+	e.cancelOpenChildren()
+	e.finish(ExecutionStateCanceled)
+}
+
+// Done is closed once the execution is done, cancelled or failed.
+func (e *Execution) Done() <-chan struct{} {
+	return e.done
+}
+
+func (e *Execution) Progress() ExecutionProgress {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	filled, average := e.filled()
+	progress := ExecutionProgress{
+		Algorithm:        e.algorithm,
+		State:            e.state,
+		Quantity:         e.config.Quantity,
+		SentQuantity:     e.committed(),
+		FilledQuantity:   filled,
+		AverageFillPrice: average,
+		ArrivalPrice:     e.config.ArrivalPrice,
+		ChildOrders:      len(e.children),
+		Err:              e.err,
+	}
+	if average != 0 && e.config.ArrivalPrice != 0 {
+		progress.SlippageBps = (average - e.config.ArrivalPrice) / e.config.ArrivalPrice * 10000
+		if e.config.Side == SideSell {
+			progress.SlippageBps = -progress.SlippageBps
+		}
+	}
+	return progress
+}
+
+// HandleMessage accepts ORDER_TRADE_UPDATE and aggTrade messages, the rest is ignored.
+func (e *Execution) HandleMessage(message []byte) error {
+	meta, err := ParseMetaInformation(message)
+	if err != nil {
+		return err
+	}
+	switch meta.Event {
+	case models.EventOrder:
+		update := new(models.StreamOrderUpdate)
+		if err := json.Unmarshal(message, update); err != nil {
+			return err
+		}
+		e.HandleOrderUpdate(*update)
+	case models.EventAggTrade:
+		trade := new(models.AggTrade)
+		if err := json.Unmarshal(message, trade); err != nil {
+			return err
+		}
+		e.HandleAggTrade(*trade)
+	}
+	return nil
+}
+
+func (e *Execution) HandleOrderUpdate(update models.StreamOrderUpdate) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	order := managedOrderFromUpdate(update)
+	child, exists := e.children[order.ClientOrderId]
+	if !exists || !isNewerOrderState(child, order) {
+		return
+	}
+	order.Acknowledged = true
+	*child = *order
+	if !child.IsOpen() && e.cancelling[child.ClientOrderId] {
+		// Cancelled by Pause or Cancel, a replacement was placed on Resume already
+		delete(e.cancelling, child.ClientOrderId)
+	} else if e.algorithm == ExecutionIceberg && !child.IsOpen() && e.state == ExecutionStateRunning {
+		e.placeIcebergChild()
+	}
+	e.checkDone()
+}
+
+// HandleAggTrade drives POV executions, own fills are part of the market volume as well.
+func (e *Execution) HandleAggTrade(trade models.AggTrade) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.algorithm != ExecutionPov || trade.Symbol != e.config.Symbol || e.isFinished() {
+		return
+	}
+	e.marketVolume += trade.Quantity
+	if e.state != ExecutionStateRunning {
+		return
+	}
+	target := e.pov.Participation * e.marketVolume
+	if target > e.config.Quantity {
+		target = e.config.Quantity
+	}
+	due := target - e.committed()
+	if due <= 0 || due < e.pov.MinChildQuantity {
+		return
+	}
+	if e.pov.MaxChildQuantity > 0 && due > e.pov.MaxChildQuantity {
+		due = e.pov.MaxChildQuantity
+	}
+	if err := e.placeChild(due, 0); err != nil {
+		e.Logger.Info(e.algorithm, " child skipped ", err)
+	}
+	if e.committed() >= e.config.Quantity {
+		e.sendingDone = true
+	}
+}
+
+func (e *Execution) runTwap() {
+	interval := e.twap.Duration / time.Duration(e.twap.Slices)
+	slicesLeft := e.twap.Slices
+	wait := time.Duration(0)
+	for slicesLeft > 0 {
+		select {
+		case <-e.stop:
+			return
+		case <-time.After(wait):
+		}
+		wait = interval
+
+		e.mu.Lock()
+		if e.isFinished() {
+			// Cancelled or failed while waiting for the slice
+			e.mu.Unlock()
+			return
+		}
+		if e.state == ExecutionStatePaused {
+			e.mu.Unlock()
+			continue
+		}
+		remaining := e.config.Quantity - e.committed()
+		quantity := remaining
+		if slicesLeft > 1 {
+			quantity = remaining / float64(slicesLeft) * (1 + e.twap.Randomization*(2*e.random.Float64()-1))
+		}
+		if quantity > 0 {
+			// Sizes under the minimum are carried over to the next slice
+			if err := e.placeChild(quantity, 0); err != nil {
+				e.Logger.Info(e.algorithm, " slice skipped ", err)
+			}
+		}
+		slicesLeft--
+		if slicesLeft == 0 {
+			e.sendingDone = true
+			e.checkDone()
+		}
+		e.mu.Unlock()
+	}
+}
+
+func (e *Execution) placeIcebergChild() {
+	remaining := e.config.Quantity - e.committed()
+	quantity := e.iceberg.VisibleQuantity
+	if remaining < quantity {
+		quantity = remaining
+	}
+	if err := e.placeChild(quantity, e.iceberg.Price); err != nil {
+		// Leftover smaller than the minimum quantity can not be sent
+		e.Logger.Info(e.algorithm, " refill skipped ", err)
+		e.sendingDone = true
+		e.checkDone()
+	}
+}
+
+// placeChild price 0 sends a market order. Returns an error only when the
+// child did not pass symbol filters, exchange errors fail the execution.
+func (e *Execution) placeChild(quantity, price float64) error {
+	market := price == 0
+	filters := e.config.Filters
+	quantity = filters.NormalizeQuantity(quantity, market)
+	if quantity <= 0 {
+		return errors.New("child quantity rounds to zero")
+	}
+	reference := price
+	if market {
+		reference = e.config.ArrivalPrice
+	} else {
+		price = filters.NormalizePrice(price)
+	}
+	if err := filters.Validate(quantity, reference, market, e.config.ReduceOnly); err != nil {
+		return err
+	}
+
+	var data []byte
+	var err error
+	if market {
+		data, err = e.Api.PlaceMarketOrder(e.config.Symbol, e.config.Side, quantity, e.config.ReduceOnly)
+	} else {
+		data, err = e.Api.PlaceLimitOrder(e.config.Symbol, e.config.Side, price, quantity, e.config.ReduceOnly)
+	}
+	if err == nil {
+		response := new(models.OrderResponse)
+		if err = json.Unmarshal(data, response); err == nil {
+			e.children[response.ClientOrderId] = managedOrderFromResponse(*response)
+			e.Logger.Info(e.algorithm, " child ", response.ClientOrderId, " sent for ", quantity)
+			return nil
+		}
+	}
+	e.Logger.Error(e.algorithm, " child order failed ", err)
+	e.err = err
+	e.cancelOpenChildren()
+	e.finish(ExecutionStateFailed)
+	return nil
+}
+
+func (e *Execution) cancelOpenChildren() {
+	for _, child := range e.children {
+		if !child.IsOpen() || child.Type == OrderTypeMarket {
+			continue
+		}
+		if e.cancelling[child.ClientOrderId] {
+			continue
+		}
+		if _, err := e.Api.CancelSingleOrder(child.Symbol, child.ClientOrderId, child.OrderId); err != nil {
+			e.Logger.Error(e.algorithm, " cancel failed for ", child.ClientOrderId, err)
+			continue
+		}
+		e.cancelling[child.ClientOrderId] = true
+	}
+}
+
+// committed quantity which is either filled or still working on the exchange
+func (e *Execution) committed() float64 {
+	total := 0.0
+	for _, child := range e.children {
+		if child.IsOpen() && !e.cancelling[child.ClientOrderId] {
+			total += child.Quantity
+		} else {
+			total += child.FilledQuantity
+		}
+	}
+	return total
+}
+
+func (e *Execution) filled() (float64, float64) {
+	quantity, cost := 0.0, 0.0
+	for _, child := range e.children {
+		quantity += child.FilledQuantity
+		cost += child.FilledQuantity * child.AveragePrice
+	}
+	if quantity == 0 {
+		return 0, 0
+	}
+	return quantity, cost / quantity
+}
+
+func (e *Execution) checkDone() {
+	if e.isFinished() {
+		return
+	}
+	filled, _ := e.filled()
+	if filled >= e.config.Quantity {
+		e.finish(ExecutionStateDone)
+		return
+	}
+	if !e.sendingDone {
+		return
+	}
+	for _, child := range e.children {
+		if child.IsOpen() {
+			return
+		}
+	}
+	e.finish(ExecutionStateDone)
+}
+
+func (e *Execution) isFinished() bool {
+	return e.state == ExecutionStateDone || e.state == ExecutionStateCanceled || e.state == ExecutionStateFailed
+}
+
+// finish only the first call counts, a failing child of a finished execution can call it again
+func (e *Execution) finish(state string) {
+	if e.isFinished() {
+		return
+	}
+	e.state = state
+	close(e.stop)
+	close(e.done)
+	filled, average := e.filled()
+	e.Logger.Info(e.algorithm, " finished ", state, " filled ", filled, " average ", average)
+}
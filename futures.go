@@ -1,11 +1,17 @@
 package go_binance
 
+import (
+	"github.com/redlon23/go-binance/models"
+	"time"
+)
+
 type BinanceFutures interface {
 	SetApiKeys(public, secret string)
 	NewNetClient()
 	NewNetClientHTTP2()
 	UseMainNet()
 	UseTestNet()
+	SetOrderMode(mode string) error
 	Get24HourTickerPriceChangeStatistics(symbol string) ([]byte, error)
 	GetOrderBook(symbol string, limit int) ([]byte, error)
 	GetExchangeInformation() ([]byte, error)
@@ -16,23 +22,63 @@ type BinanceFutures interface {
 	PlaceLimitOrder(symbol, side string, price, qty float64, reduceOnly bool) ([]byte, error)
 	PlacePostOnlyLimitOrder(symbol, side string, price, qty float64, reduceOnly bool) ([]byte, error) //PlaceMarketOrder(symbol, side string, qty float64, reduceOnly bool) ([]byte, error)
 	PlaceMarketOrder(symbol, side string, qty float64, reduceOnly bool) ([]byte, error)
+	PlaceHedgeMarketOrder(symbol, side, positionSide string, qty float64) ([]byte, error)
 	PlaceStopMarketOrder(symbol, side string, stopPrice, qty float64) ([]byte, error)
+	PlaceTakeProfitMarketOrder(symbol, side string, stopPrice, qty float64) ([]byte, error)
+	PlaceClosePositionOrder(symbol, side, orderType string, stopPrice float64) ([]byte, error)
+	QueryOrder(symbol, origClientOrderId string, orderId int64) ([]byte, error)
 	CancelSingleOrder(symbol, origClientOrderId string, orderId int64) ([]byte, error)
 	CancelAllOrders(symbol string) ([]byte, error)
+	GetOpenOrders(symbol string) ([]byte, error)
+	SetAutoCancelCountdown(symbol string, countdownTime int64) ([]byte, error)
 	GetAccountBalance() ([]byte, error)
 	GetAccountInformation() ([]byte, error)
 	GetPositionInformation(symbol string) ([]byte, error)
 	GetTradeList(symbol, startTime, endTime, limit string) ([]byte, error)
+	GetIncomeHistory(symbol, incomeType string, startTime, endTime int64, limit int) ([]byte, error)
+	GetCommissionRate(symbol string) ([]byte, error)
+	GetAdlQuantile(symbol string) ([]byte, error)
+	GetIncomeDownloadId(startTime, endTime int64) ([]byte, error)
+	GetIncomeDownloadLink(downloadId string) ([]byte, error)
+	GetOrderDownloadId(startTime, endTime int64) ([]byte, error)
+	GetOrderDownloadLink(downloadId string) ([]byte, error)
+	GetTradeDownloadId(startTime, endTime int64) ([]byte, error)
+	GetTradeDownloadLink(downloadId string) ([]byte, error)
 	PrepareLoggers()
 }
 
+// OrderTransport order and account calls offered by both the REST client and BinanceFuturesWsApi,
+// a strategy can hold one of each and pick the transport per call. A transport is not checked by
+// a RiskGuard wrapping the REST client, use RiskGuard.GuardTransport for that.
+type OrderTransport interface {
+	PlaceLimitOrder(symbol, side string, price, qty float64, reduceOnly bool) ([]byte, error)
+	PlacePostOnlyLimitOrder(symbol, side string, price, qty float64, reduceOnly bool) ([]byte, error)
+	PlaceMarketOrder(symbol, side string, qty float64, reduceOnly bool) ([]byte, error)
+	PlaceStopMarketOrder(symbol, side string, stopPrice, qty float64) ([]byte, error)
+	PlaceTakeProfitMarketOrder(symbol, side string, stopPrice, qty float64) ([]byte, error)
+	PlaceClosePositionOrder(symbol, side, orderType string, stopPrice float64) ([]byte, error)
+	QueryOrder(symbol, origClientOrderId string, orderId int64) ([]byte, error)
+	ModifyOrder(symbol, side, origClientOrderId string, orderId int64, price, qty float64) ([]byte, error)
+	CancelSingleOrder(symbol, origClientOrderId string, orderId int64) ([]byte, error)
+	GetAccountBalance() ([]byte, error)
+	GetAccountInformation() ([]byte, error)
+	GetPositionInformation(symbol string) ([]byte, error)
+}
+
 type BinanceFutureSocket interface {
 	UseMainNet()
 	UseTestNet()
 	IncrementSubscribeIdCounter()
 	OpenWebSocketConnection() error
 	OpenWebSocketConnectionWithUserStream(listenKey string) error
+	OpenCombinedStreamConnection(streams []string) error
 	SubscribeToStream(symbol, streamType string) error
+	SubscribeToStreamAndWait(symbol, streamType string, timeout time.Duration) error
+	UnsubscribeFromStream(symbol, streamType string) error
+	ListSubscriptions(timeout time.Duration) ([]string, error)
+	SetCombinedProperty(combined bool, timeout time.Duration) error
+	GetCombinedProperty(timeout time.Duration) (bool, error)
+	SendStreamRequest(method string, params []interface{}, timeout time.Duration) (*models.StreamResponse, error)
 	SubscribeLiquidationStream(symbol string) error
 	SubscribeBookTickerStream(symbol string) error
 	SubscribeSymbolTickerStream(symbol string) error
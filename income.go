@@ -0,0 +1,121 @@
+package go_binance
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/redlon23/go-binance/models"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+func incomeParameters(symbol, incomeType string, startTime, endTime int64, limit int) url.Values {
+	parameters := url.Values{}
+	if symbol != "" {
+		parameters.Add("symbol", symbol)
+	}
+	if incomeType != "" {
+		parameters.Add("incomeType", incomeType)
+	}
+	if startTime > 0 {
+		parameters.Add("startTime", strconv.FormatInt(startTime, 10))
+	}
+	if endTime > 0 {
+		parameters.Add("endTime", strconv.FormatInt(endTime, 10))
+	}
+	if limit > 0 {
+		parameters.Add("limit", fmt.Sprintf("%d", limit))
+	}
+	return parameters
+}
+
+func downloadParameters(startTime, endTime int64) url.Values {
+	parameters := url.Values{}
+	parameters.Add("startTime", strconv.FormatInt(startTime, 10))
+	parameters.Add("endTime", strconv.FormatInt(endTime, 10))
+	return parameters
+}
+
+func downloadLinkParameters(downloadId string) url.Values {
+	parameters := url.Values{}
+	parameters.Add("downloadId", downloadId)
+	return parameters
+}
+
+func incomeKey(income models.Income) string {
+	return fmt.Sprintf("%d-%s-%s-%s", income.TransactionId, income.IncomeType, income.Symbol, income.Asset)
+}
+
+// GetAllIncomeHistory pages through income history by time until endTime is reached.
+// Binance returns at most MaxIncomeLimit records per call, ascending by time, so every
+// page starts at the last seen timestamp and records seen already at that timestamp are skipped.
+// startTime is required, without it binance answers with the most recent window only and paging
+// would start from recent data instead of the beginning. endTime 0 means up to now.
+func GetAllIncomeHistory(api BinanceFutures, symbol, incomeType string, startTime, endTime int64) ([]models.Income, error) {
+	if startTime <= 0 {
+		return nil, ErrStartTimeRequired
+	}
+	if endTime == 0 {
+		endTime = time.Now().UnixNano() / int64(time.Millisecond)
+	}
+	history := make([]models.Income, 0)
+	seen := make(map[string]bool)
+	for startTime <= endTime {
+		data, err := api.GetIncomeHistory(symbol, incomeType, startTime, endTime, MaxIncomeLimit)
+		if err != nil {
+			return history, err
+		}
+		page := make(models.IncomeHistory, 0)
+		if err := json.Unmarshal(data, &page); err != nil {
+			return history, err
+		}
+		for _, income := range page {
+			if seen[incomeKey(income)] {
+				continue
+			}
+			history = append(history, income)
+		}
+		if len(page) < MaxIncomeLimit {
+			break
+		}
+
+		last := page[len(page)-1].Time
+		if page[0].Time == last {
+			// Whole page shares one timestamp, moving on is the only way out
+			startTime = last + 1
+			continue
+		}
+		seen = make(map[string]bool)
+		for _, income := range page {
+			if income.Time == last {
+				seen[incomeKey(income)] = true
+			}
+		}
+		startTime = last
+	}
+	return history, nil
+}
+
+// WaitForDownloadLink polls until the file of the given download id is ready.
+// Pass GetIncomeDownloadLink, GetOrderDownloadLink or GetTradeDownloadLink of any client as fetch.
+func WaitForDownloadLink(fetch func(downloadId string) ([]byte, error), downloadId string,
+	pollInterval, timeout time.Duration) (*models.DownloadLink, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		data, err := fetch(downloadId)
+		if err != nil {
+			return nil, err
+		}
+		link := new(models.DownloadLink)
+		if err := json.Unmarshal(data, link); err != nil {
+			return nil, err
+		}
+		if link.Status == DownloadStatusCompleted {
+			return link, nil
+		}
+		if time.Now().Add(pollInterval).After(deadline) {
+			return link, ErrDownloadTimeout
+		}
+		time.Sleep(pollInterval)
+	}
+}
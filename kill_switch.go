@@ -0,0 +1,252 @@
+package go_binance
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/redlon23/go-binance/models"
+	"github.com/sirupsen/logrus"
+	"math"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultKillSwitchCountdown = 60 * time.Second
+	DefaultKillSwitchAttempts  = 5
+	DefaultKillSwitchRetryWait = 500 * time.Millisecond
+)
+
+type KillSwitchClosedPosition struct {
+	Symbol   string
+	Side     string
+	Quantity float64
+}
+
+// KillSwitchReport outcome of one account, Flat tells whether every position was verified closed.
+type KillSwitchReport struct {
+	Started         time.Time
+	Finished        time.Time
+	Symbols         []string
+	CanceledSymbols []string
+	ClosedPositions []KillSwitchClosedPosition
+	Errors          []error
+	Flat            bool
+}
+
+// KillSwitch stops all trading on every account: enables the auto cancel countdown,
+// cancels all open orders and closes all positions with reduce only market orders.
+// The countdown is left running on purpose, anything placed after the kill switch
+// is cancelled by binance unless the countdown is renewed or disabled.
+// Hedge mode positions are closed with market orders on their LONG or SHORT position side.
+type KillSwitch struct {
+	Accounts []BinanceFutures
+	// Optional, quantities are capped at MARKET_LOT_SIZE of the symbol when present
+	Filters   map[string]SymbolFilters
+	Countdown time.Duration
+	Attempts  int
+	RetryWait time.Duration
+	Logger    *logrus.Logger
+
+	mu      sync.Mutex
+	reports []KillSwitchReport
+	running chan struct{}
+}
+
+func NewKillSwitch(accounts ...BinanceFutures) *KillSwitch {
+	return &KillSwitch{
+		Accounts:  accounts,
+		Countdown: DefaultKillSwitchCountdown,
+		Attempts:  DefaultKillSwitchAttempts,
+		RetryWait: DefaultKillSwitchRetryWait,
+		Logger:    logrus.New(),
+	}
+}
+
+func (ks *KillSwitch) PrepareLoggers() {
+	ks.Logger = logrus.New()
+	ks.Logger.Formatter = new(logrus.JSONFormatter)
+
+	killLogs, err := os.OpenFile("logs/binance_kill_switch.log", os.O_CREATE|os.O_WRONLY, 0666)
+	if err == nil {
+		ks.Logger.SetOutput(killLogs)
+	} else {
+		fmt.Println("Failed to log to file for kill switch, using default stderr")
+	}
+}
+
+// TriggerOnSignal runs the kill switch in the background when one of the signals arrives.
+func (ks *KillSwitch) TriggerOnSignal(signals ...os.Signal) {
+	signalChannel := make(chan os.Signal, 1)
+	signal.Notify(signalChannel, signals...)
+	go func() {
+		received := <-signalChannel
+		ks.Logger.Warn("kill switch triggered by signal ", received)
+		ks.Trigger()
+	}()
+}
+
+// Trigger runs every account in parallel and returns one report per account, in order.
+// Concurrent calls wait for the running one and get its reports.
+func (ks *KillSwitch) Trigger() []KillSwitchReport {
+	ks.mu.Lock()
+	if ks.running != nil {
+		running := ks.running
+		ks.mu.Unlock()
+		<-running
+		ks.mu.Lock()
+		defer ks.mu.Unlock()
+		return ks.reports
+	}
+	ks.running = make(chan struct{})
+	ks.mu.Unlock()
+
+	ks.Logger.Warn("kill switch started for ", len(ks.Accounts), " accounts")
+	reports := make([]KillSwitchReport, len(ks.Accounts))
+	var wg sync.WaitGroup
+	for i, account := range ks.Accounts {
+		wg.Add(1)
+		go func(i int, account BinanceFutures) {
+			defer wg.Done()
+			reports[i] = ks.flatten(account)
+		}(i, account)
+	}
+	wg.Wait()
+
+	ks.mu.Lock()
+	ks.reports = reports
+	close(ks.running)
+	ks.running = nil
+	ks.mu.Unlock()
+	return reports
+}
+
+func (ks *KillSwitch) flatten(api BinanceFutures) KillSwitchReport {
+	report := KillSwitchReport{Started: time.Now()}
+	symbols := make(map[string]bool)
+
+	openOrders := make([]models.OrderResponse, 0)
+	ks.retry(func() error {
+		data, err := api.GetOpenOrders("")
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(data, &openOrders)
+	}, &report)
+	for _, order := range openOrders {
+		symbols[order.Symbol] = true
+	}
+	positions, positionsErr := ks.openPositions(api)
+	if positionsErr != nil {
+		report.Errors = append(report.Errors, positionsErr)
+	}
+	for _, position := range positions {
+		symbols[position.Symbol] = true
+	}
+	for symbol := range symbols {
+		report.Symbols = append(report.Symbols, symbol)
+	}
+
+	for _, symbol := range report.Symbols {
+		if _, err := api.SetAutoCancelCountdown(symbol, ks.Countdown.Milliseconds()); err != nil {
+			ks.Logger.Error("countdown failed for ", symbol, err)
+			report.Errors = append(report.Errors, err)
+		}
+	}
+	for _, symbol := range report.Symbols {
+		if ks.retry(func() error { _, err := api.CancelAllOrders(symbol); return err }, &report) {
+			report.CanceledSymbols = append(report.CanceledSymbols, symbol)
+			ks.Logger.Warn("orders cancelled for ", symbol)
+		}
+	}
+
+	for attempt := 0; attempt < ks.Attempts; attempt++ {
+		if attempt > 0 || positionsErr != nil {
+			if attempt > 0 {
+				time.Sleep(ks.RetryWait)
+			}
+			positions, positionsErr = ks.openPositions(api)
+			if positionsErr != nil {
+				report.Errors = append(report.Errors, positionsErr)
+				continue
+			}
+		}
+		if len(positions) == 0 {
+			report.Flat = true
+			break
+		}
+		for _, position := range positions {
+			ks.closePosition(api, position, &report)
+		}
+	}
+	// Flatness is only trusted from a fresh query
+	if !report.Flat {
+		time.Sleep(ks.RetryWait)
+		if remaining, err := ks.openPositions(api); err == nil && len(remaining) == 0 {
+			report.Flat = true
+		}
+	}
+	report.Finished = time.Now()
+	ks.Logger.Warn("kill switch finished, flat: ", report.Flat, " errors: ", len(report.Errors))
+	return report
+}
+
+func (ks *KillSwitch) openPositions(api BinanceFutures) ([]models.PositionRisk, error) {
+	data, err := api.GetPositionInformation("")
+	if err != nil {
+		return nil, err
+	}
+	risks := make(models.PositionRisks, 0)
+	if err := json.Unmarshal(data, &risks); err != nil {
+		return nil, err
+	}
+	open := make([]models.PositionRisk, 0)
+	for _, risk := range risks {
+		if risk.PositionAmount != 0 {
+			open = append(open, risk)
+		}
+	}
+	return open, nil
+}
+
+func (ks *KillSwitch) closePosition(api BinanceFutures, position models.PositionRisk, report *KillSwitchReport) {
+	side := SideSell
+	if position.PositionAmount < 0 {
+		side = SideBuy
+	}
+	quantity := math.Abs(position.PositionAmount)
+	if filters, exists := ks.Filters[position.Symbol]; exists {
+		quantity = filters.NormalizeQuantity(quantity, true)
+	}
+	var err error
+	if position.PositionSide == PositionSideLong || position.PositionSide == PositionSideShort {
+		// reduceOnly is rejected in hedge mode, the position side keeps the order closing
+		_, err = api.PlaceHedgeMarketOrder(position.Symbol, side, position.PositionSide, quantity)
+	} else {
+		_, err = api.PlaceMarketOrder(position.Symbol, side, quantity, true)
+	}
+	if err != nil {
+		ks.Logger.Error("close failed for ", position.Symbol, err)
+		report.Errors = append(report.Errors, err)
+		return
+	}
+	ks.Logger.Warn("closing ", quantity, " ", position.Symbol, " with ", side)
+	report.ClosedPositions = append(report.ClosedPositions,
+		KillSwitchClosedPosition{Symbol: position.Symbol, Side: side, Quantity: quantity})
+}
+
+func (ks *KillSwitch) retry(call func() error, report *KillSwitchReport) bool {
+	for attempt := 0; attempt < ks.Attempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(ks.RetryWait)
+		}
+		err := call()
+		if err == nil {
+			return true
+		}
+		ks.Logger.Error("kill switch call failed ", err)
+		report.Errors = append(report.Errors, err)
+	}
+	return false
+}
@@ -0,0 +1,480 @@
+package go_binance
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	SocketEventConnect     = "CONNECT"
+	SocketEventDisconnect  = "DISCONNECT"
+	SocketEventResubscribe = "RESUBSCRIBE"
+
+	DefaultReconnectMinWait = 500 * time.Millisecond
+	DefaultReconnectMaxWait = 30 * time.Second
+	// Binance disconnects every connection after 24 hours
+	DefaultMaxConnectionAge = 23*time.Hour + 30*time.Minute
+	// Binance accepts 10 incoming messages per second on a connection
+	resubscribeInterval  = 110 * time.Millisecond
+	resubscribeBatchSize = 200
+)
+
+// SocketEvent lifecycle event of a ManagedWebSocket. Attempt counts reconnect attempts,
+// Err is the read error for disconnects and ErrConnectionExpired for planned reconnects.
+type SocketEvent struct {
+	Type          string
+	Time          time.Time
+	Attempt       int
+	Err           error
+	Subscriptions []string
+}
+
+type managedSubscription struct {
+	Symbol     string
+	StreamType string
+}
+
+func (ms managedSubscription) name() string {
+	return StreamName(ms.Symbol, ms.StreamType)
+}
+
+// ManagedWebSocket wraps a socket and keeps it open: a failing read reconnects with exponential
+// backoff and jitter, replays every subscription and then keeps reading, so callers only see
+// errors after CloseConnection or when MaxAttempts is used up.
+// The connection is renewed after MaxConnectionAge, ahead of the forced disconnect of binance.
+// A user stream connection is reopened with the same listen key, use SetListenKey after renewing it.
+type ManagedWebSocket struct {
+	BinanceFutureSocket
+	MinReconnectWait time.Duration
+	MaxReconnectWait time.Duration
+	MaxConnectionAge time.Duration
+	// 0 retries forever
+	MaxAttempts int
+	Logger      *logrus.Logger
+
+	// connMu keeps dials of the reading goroutine apart from closes of other goroutines,
+	// both replace or use the connection of the wrapped socket
+	connMu        sync.Mutex
+	mu            sync.Mutex
+	subscriptions []managedSubscription
+	listenKey     string
+	userStream    bool
+	// Streams of the combined stream url, dialed again instead of being replayed
+	combined         bool
+	combinedStreams  []string
+	combinedProperty *bool
+	connectedAt      time.Time
+	generation       int
+	ageTimer         *time.Timer
+	expired          bool
+	reconnectReason  error
+	closed           bool
+	listeners        []func(SocketEvent)
+}
+
+func NewManagedWebSocket(socket BinanceFutureSocket) *ManagedWebSocket {
+	return &ManagedWebSocket{
+		BinanceFutureSocket: socket,
+		MinReconnectWait:    DefaultReconnectMinWait,
+		MaxReconnectWait:    DefaultReconnectMaxWait,
+		MaxConnectionAge:    DefaultMaxConnectionAge,
+		Logger:              logrus.New(),
+	}
+}
+
+// PrepareLoggers prepares loggers of the wrapped socket as well.
+func (mws *ManagedWebSocket) PrepareLoggers() {
+	mws.BinanceFutureSocket.PrepareLoggers()
+	mws.Logger = logrus.New()
+	mws.Logger.Formatter = new(logrus.JSONFormatter)
+
+	managedLogs, err := os.OpenFile("logs/binance_managed_ws.log", os.O_CREATE|os.O_WRONLY, 0666)
+	if err == nil {
+		mws.Logger.SetOutput(managedLogs)
+	} else {
+		fmt.Println("Failed to log to file for managed websocket, using default stderr")
+	}
+}
+
+// Subscribe registers a listener for lifecycle events, listeners are called on the reading goroutine.
+func (mws *ManagedWebSocket) Subscribe(listener func(SocketEvent)) {
+	mws.mu.Lock()
+	defer mws.mu.Unlock()
+	mws.listeners = append(mws.listeners, listener)
+}
+
+func (mws *ManagedWebSocket) emit(event SocketEvent) {
+	event.Time = time.Now()
+	mws.mu.Lock()
+	listeners := make([]func(SocketEvent), len(mws.listeners))
+	copy(listeners, mws.listeners)
+	mws.mu.Unlock()
+	for _, listener := range listeners {
+		listener(event)
+	}
+}
+
+// Subscriptions names of the active subscriptions, e.g. btcusdt@bookTicker
+func (mws *ManagedWebSocket) Subscriptions() []string {
+	mws.mu.Lock()
+	defer mws.mu.Unlock()
+	names := append(make([]string, 0, len(mws.combinedStreams)+len(mws.subscriptions)), mws.combinedStreams...)
+	for _, subscription := range mws.subscriptions {
+		names = append(names, subscription.name())
+	}
+	return names
+}
+
+func (mws *ManagedWebSocket) SetListenKey(listenKey string) {
+	mws.mu.Lock()
+	defer mws.mu.Unlock()
+	mws.listenKey = listenKey
+}
+
+func (mws *ManagedWebSocket) OpenWebSocketConnection() error {
+	mws.mu.Lock()
+	mws.userStream, mws.combined, mws.closed = false, false, false
+	mws.mu.Unlock()
+	if err := mws.dial(mws.BinanceFutureSocket.OpenWebSocketConnection); err != nil {
+		return err
+	}
+	mws.connected(0)
+	return nil
+}
+
+func (mws *ManagedWebSocket) OpenCombinedStreamConnection(streams []string) error {
+	mws.mu.Lock()
+	mws.userStream, mws.combined, mws.closed = false, true, false
+	mws.combinedStreams = append([]string(nil), streams...)
+	mws.mu.Unlock()
+	err := mws.dial(func() error {
+		return mws.BinanceFutureSocket.OpenCombinedStreamConnection(streams)
+	})
+	if err != nil {
+		return err
+	}
+	mws.connected(0)
+	return nil
+}
+
+func (mws *ManagedWebSocket) OpenWebSocketConnectionWithUserStream(listenKey string) error {
+	mws.mu.Lock()
+	mws.userStream, mws.combined, mws.listenKey, mws.closed = true, false, listenKey, false
+	mws.mu.Unlock()
+	err := mws.dial(func() error {
+		return mws.BinanceFutureSocket.OpenWebSocketConnectionWithUserStream(listenKey)
+	})
+	if err != nil {
+		return err
+	}
+	mws.connected(0)
+	return nil
+}
+
+// dial opens the wrapped connection under connMu, every successful dial starts a new generation
+func (mws *ManagedWebSocket) dial(open func() error) error {
+	mws.connMu.Lock()
+	defer mws.connMu.Unlock()
+	if err := open(); err != nil {
+		return err
+	}
+	mws.mu.Lock()
+	mws.generation++
+	mws.mu.Unlock()
+	return nil
+}
+
+// closeWrapped closes the wrapped connection under connMu, safe from any goroutine
+func (mws *ManagedWebSocket) closeWrapped() error {
+	mws.connMu.Lock()
+	defer mws.connMu.Unlock()
+	return mws.BinanceFutureSocket.CloseConnection()
+}
+
+// connected restarts the age timer, the timer closes the connection so the blocked read reconnects
+func (mws *ManagedWebSocket) connected(attempt int) {
+	mws.mu.Lock()
+	mws.connectedAt = time.Now()
+	mws.expired = false
+	if mws.ageTimer != nil {
+		mws.ageTimer.Stop()
+	}
+	if mws.MaxConnectionAge > 0 {
+		generation := mws.generation
+		mws.ageTimer = time.AfterFunc(mws.MaxConnectionAge, func() {
+			mws.expire(generation)
+		})
+	}
+	mws.mu.Unlock()
+	mws.emit(SocketEvent{Type: SocketEventConnect, Attempt: attempt})
+}
+
+// expire runs on the timer goroutine. connMu keeps the close away from a dial in progress and
+// a timer of an older connection, which fired while being stopped, closes nothing.
+func (mws *ManagedWebSocket) expire(generation int) {
+	mws.connMu.Lock()
+	defer mws.connMu.Unlock()
+	mws.mu.Lock()
+	current := generation == mws.generation && !mws.closed
+	if current {
+		mws.expired = true
+	}
+	mws.mu.Unlock()
+	if !current {
+		return
+	}
+	mws.Logger.Info("connection reached its maximum age, reconnecting")
+	_ = mws.BinanceFutureSocket.CloseConnection()
+}
+
+// SubscribeToStream does not wait for the acknowledgement. The stream is replayed after reconnects
+// right away and dropped again when binance rejects it, the acknowledgement is awaited in the
+// background and only arrives while the connection is read.
+func (mws *ManagedWebSocket) SubscribeToStream(symbol, streamType string) error {
+	subscription := managedSubscription{Symbol: symbol, StreamType: streamType}
+	mws.track(subscription)
+	go mws.awaitSubscription(subscription)
+	return nil
+}
+
+// awaitSubscription a write failure is not dropped, the reconnect it causes replays the stream
+func (mws *ManagedWebSocket) awaitSubscription(subscription managedSubscription) {
+	name := subscription.name()
+	_, err := mws.BinanceFutureSocket.SendStreamRequest(StreamMethodSubscribe, []interface{}{name}, DefaultStreamAckTimeout)
+	var requestError *StreamRequestError
+	switch {
+	case errors.As(err, &requestError):
+		mws.Logger.Error("subscription to ", name, " rejected, not replaying it: ", err)
+		mws.untrack(name)
+	case err != nil:
+		mws.Logger.Warn("subscription to ", name, " not acknowledged: ", err)
+	}
+}
+
+func (mws *ManagedWebSocket) track(subscription managedSubscription) {
+	mws.mu.Lock()
+	defer mws.mu.Unlock()
+	for _, existing := range mws.subscriptions {
+		if existing.name() == subscription.name() {
+			return
+		}
+	}
+	mws.subscriptions = append(mws.subscriptions, subscription)
+}
+
+// SubscribeToStreamAndWait the subscription is replayed after reconnects once acknowledged.
+func (mws *ManagedWebSocket) SubscribeToStreamAndWait(symbol, streamType string, timeout time.Duration) error {
+	if err := mws.BinanceFutureSocket.SubscribeToStreamAndWait(symbol, streamType, timeout); err != nil {
+		return err
+	}
+	mws.track(managedSubscription{Symbol: symbol, StreamType: streamType})
+	return nil
+}
+
+// UnsubscribeFromStream the stream is not replayed anymore, streams of the combined url included.
+func (mws *ManagedWebSocket) UnsubscribeFromStream(symbol, streamType string) error {
+	if err := mws.BinanceFutureSocket.UnsubscribeFromStream(symbol, streamType); err != nil {
+		return err
+	}
+	mws.untrack(StreamName(symbol, streamType))
+	return nil
+}
+
+func (mws *ManagedWebSocket) untrack(name string) {
+	mws.mu.Lock()
+	defer mws.mu.Unlock()
+	remaining := mws.subscriptions[:0]
+	for _, subscription := range mws.subscriptions {
+		if subscription.name() != name {
+			remaining = append(remaining, subscription)
+		}
+	}
+	mws.subscriptions = remaining
+	streams := mws.combinedStreams[:0]
+	for _, stream := range mws.combinedStreams {
+		if stream != name {
+			streams = append(streams, stream)
+		}
+	}
+	mws.combinedStreams = streams
+}
+
+// SetCombinedProperty the property is set again after reconnects.
+func (mws *ManagedWebSocket) SetCombinedProperty(combined bool, timeout time.Duration) error {
+	if err := mws.BinanceFutureSocket.SetCombinedProperty(combined, timeout); err != nil {
+		return err
+	}
+	mws.mu.Lock()
+	defer mws.mu.Unlock()
+	mws.combinedProperty = &combined
+	return nil
+}
+
+func (mws *ManagedWebSocket) SubscribeLiquidationStream(symbol string) error {
+	return mws.SubscribeToStream(symbol, liquidationStreamName)
+}
+
+func (mws *ManagedWebSocket) SubscribeBookTickerStream(symbol string) error {
+	return mws.SubscribeToStream(symbol, bookTickerSteamName)
+}
+
+func (mws *ManagedWebSocket) SubscribeSymbolTickerStream(symbol string) error {
+	return mws.SubscribeToStream(symbol, symbolTickerName)
+}
+
+// ReadFromConnection reconnects on read errors, messages lost while reconnecting are not recovered.
+func (mws *ManagedWebSocket) ReadFromConnection() (messageType int, p []byte, err error) {
+	for {
+		messageType, p, err = mws.BinanceFutureSocket.ReadFromConnection()
+		if err == nil {
+			return messageType, p, nil
+		}
+		mws.mu.Lock()
+		closed, expired, reason := mws.closed, mws.expired, mws.reconnectReason
+		mws.reconnectReason = nil
+		mws.mu.Unlock()
+		if closed {
+			return messageType, p, err
+		}
+		if expired {
+			err = ErrConnectionExpired
+		} else if reason != nil {
+			err = reason
+			mws.Logger.Warn("reconnect requested: ", reason)
+		} else {
+			mws.Logger.Warn("read failed, reconnecting: ", err)
+		}
+		mws.emit(SocketEvent{Type: SocketEventDisconnect, Err: err})
+		if reconnectErr := mws.reconnect(); reconnectErr != nil {
+			return -1, nil, reconnectErr
+		}
+	}
+}
+
+func (mws *ManagedWebSocket) backoff(attempt int) time.Duration {
+	return reconnectBackoff(mws.MinReconnectWait, mws.MaxReconnectWait, attempt)
+}
+
+// reconnectBackoff doubles the wait on every attempt, the actual wait is random in the upper half
+func reconnectBackoff(minWait, maxWait time.Duration, attempt int) time.Duration {
+	wait := minWait
+	for i := 1; i < attempt && wait < maxWait; i++ {
+		wait *= 2
+	}
+	if wait > maxWait {
+		wait = maxWait
+	}
+	if wait <= 0 {
+		return 0
+	}
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+func (mws *ManagedWebSocket) reconnect() error {
+	_ = mws.closeWrapped()
+	for attempt := 1; mws.MaxAttempts == 0 || attempt <= mws.MaxAttempts; attempt++ {
+		mws.mu.Lock()
+		expired, closed := mws.expired, mws.closed
+		userStream, listenKey := mws.userStream, mws.listenKey
+		combined, streams := mws.combined, mws.combinedStreams
+		mws.mu.Unlock()
+		if closed {
+			return ErrConnectionClosed
+		}
+		// Planned reconnects dial right away
+		if attempt > 1 || !expired {
+			time.Sleep(mws.backoff(attempt))
+		}
+
+		err := mws.dial(func() error {
+			if userStream {
+				return mws.BinanceFutureSocket.OpenWebSocketConnectionWithUserStream(listenKey)
+			} else if combined {
+				return mws.BinanceFutureSocket.OpenCombinedStreamConnection(streams)
+			}
+			return mws.BinanceFutureSocket.OpenWebSocketConnection()
+		})
+		if err != nil {
+			mws.Logger.Error("reconnect attempt ", attempt, " failed: ", err)
+			continue
+		}
+		mws.connected(attempt)
+		if err := mws.resubscribe(); err != nil {
+			mws.Logger.Error("resubscribe failed: ", err)
+			_ = mws.closeWrapped()
+			continue
+		}
+		return nil
+	}
+	return fmt.Errorf("websocket could not reconnect after %d attempts", mws.MaxAttempts)
+}
+
+func (mws *ManagedWebSocket) resubscribe() error {
+	mws.mu.Lock()
+	subscriptions := append([]managedSubscription(nil), mws.subscriptions...)
+	combinedProperty := mws.combinedProperty
+	mws.mu.Unlock()
+	if combinedProperty != nil {
+		if err := mws.BinanceFutureSocket.SetCombinedProperty(*combinedProperty, 0); err != nil {
+			return err
+		}
+	}
+	if len(subscriptions) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		names = append(names, subscription.name())
+	}
+	// Streams are sent in batches, a full connection would take minutes one by one
+	for start := 0; start < len(names); start += resubscribeBatchSize {
+		if start > 0 {
+			time.Sleep(resubscribeInterval)
+		}
+		end := start + resubscribeBatchSize
+		if end > len(names) {
+			end = len(names)
+		}
+		params := make([]interface{}, 0, end-start)
+		for _, name := range names[start:end] {
+			params = append(params, name)
+		}
+		if _, err := mws.BinanceFutureSocket.SendStreamRequest(StreamMethodSubscribe, params, 0); err != nil {
+			return err
+		}
+	}
+	mws.Logger.Info("resubscribed to ", len(names), " streams")
+	mws.emit(SocketEvent{Type: SocketEventResubscribe, Subscriptions: names})
+	return nil
+}
+
+// Reconnect closes the current connection, the blocked read reconnects and reports reason
+// in the DISCONNECT event. Used by StreamWatchdog for silent connections.
+func (mws *ManagedWebSocket) Reconnect(reason error) {
+	mws.mu.Lock()
+	mws.reconnectReason = reason
+	mws.mu.Unlock()
+	_ = mws.closeWrapped()
+}
+
+// ConnectedAt time of the last successful dial
+func (mws *ManagedWebSocket) ConnectedAt() time.Time {
+	mws.mu.Lock()
+	defer mws.mu.Unlock()
+	return mws.connectedAt
+}
+
+func (mws *ManagedWebSocket) CloseConnection() error {
+	mws.mu.Lock()
+	mws.closed = true
+	if mws.ageTimer != nil {
+		mws.ageTimer.Stop()
+	}
+	mws.mu.Unlock()
+	return mws.closeWrapped()
+}
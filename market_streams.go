@@ -0,0 +1,152 @@
+package go_binance
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	aggTradeStreamName        = "aggTrade"
+	markPriceStreamName       = "markPrice"
+	klineStreamName           = "kline"
+	continuousKlineStreamName = "continuousKline"
+	miniTickerStreamName      = "miniTicker"
+	depthStreamName           = "depth"
+	compositeIndexStreamName  = "compositeIndex"
+	assetIndexStreamName      = "assetIndex"
+
+	AllTickersStream           = "!ticker@arr"
+	AllMiniTickersStream       = "!miniTicker@arr"
+	AllBookTickersStream       = "!bookTicker"
+	AllMarkPricesStream        = "!markPrice@arr"
+	AllLiquidationsStream      = "!forceOrder@arr"
+	AllContractInfoStream      = "!contractInfo"
+	AllAssetIndexesStream      = "!assetIndex@arr"
+	oneSecondUpdateSpeed       = "@1s"
+	PartialDepthLevels5        = 5
+	PartialDepthLevels10       = 10
+	PartialDepthLevels20       = 20
+	DepthUpdateSpeed100ms      = "100ms"
+	DepthUpdateSpeed250ms      = "250ms"
+	DepthUpdateSpeed500ms      = "500ms"
+	ContractTypePerpetual      = "perpetual"
+	ContractTypeCurrentQuarter = "current_quarter"
+	ContractTypeNextQuarter    = "next_quarter"
+)
+
+// MarkPriceStream markPrice updates every 3 seconds, or every second with oneSecond
+func MarkPriceStream(oneSecond bool) string {
+	if oneSecond {
+		return markPriceStreamName + oneSecondUpdateSpeed
+	}
+	return markPriceStreamName
+}
+
+// KlineStream kline_<interval>, intervals as in GetKlines, e.g. 1m, 4h, 1d
+func KlineStream(interval string) string {
+	return klineStreamName + "_" + interval
+}
+
+// ContinuousKlineSymbol the "symbol" of a continuous kline stream, pair and contract type, e.g. btcusdt_perpetual
+func ContinuousKlineSymbol(pair, contractType string) string {
+	return strings.ToLower(pair) + "_" + strings.ToLower(contractType)
+}
+
+func ContinuousKlineStream(interval string) string {
+	return continuousKlineStreamName + "_" + interval
+}
+
+// depthSpeed 250ms is the default speed and has no suffix
+func depthSpeed(speed string) string {
+	if speed == "" || speed == DepthUpdateSpeed250ms {
+		return ""
+	}
+	return "@" + speed
+}
+
+// PartialDepthStream depth<levels>[@100ms|@500ms], top levels of the book as a snapshot
+func PartialDepthStream(levels int, speed string) string {
+	return fmt.Sprintf("%s%d%s", depthStreamName, levels, depthSpeed(speed))
+}
+
+// DiffDepthStream depth[@100ms|@500ms], changes of the book for a locally kept order book
+func DiffDepthStream(speed string) string {
+	return depthStreamName + depthSpeed(speed)
+}
+
+// The helpers below take any BinanceFutureSocket and subscribe through its SubscribeToStream,
+// so ManagedWebSocket, StreamPool and PaperWebSocket track the stream like any other subscription.
+
+// Symbol streams
+
+func SubscribeAggTradeStream(socket BinanceFutureSocket, symbol string) error {
+	return socket.SubscribeToStream(symbol, aggTradeStreamName)
+}
+
+func SubscribeMarkPriceStream(socket BinanceFutureSocket, symbol string, oneSecond bool) error {
+	return socket.SubscribeToStream(symbol, MarkPriceStream(oneSecond))
+}
+
+func SubscribeKlineStream(socket BinanceFutureSocket, symbol, interval string) error {
+	return socket.SubscribeToStream(symbol, KlineStream(interval))
+}
+
+func SubscribeContinuousKlineStream(socket BinanceFutureSocket, pair, contractType, interval string) error {
+	return socket.SubscribeToStream(ContinuousKlineSymbol(pair, contractType), ContinuousKlineStream(interval))
+}
+
+func SubscribeMiniTickerStream(socket BinanceFutureSocket, symbol string) error {
+	return socket.SubscribeToStream(symbol, miniTickerStreamName)
+}
+
+func SubscribePartialDepthStream(socket BinanceFutureSocket, symbol string, levels int, speed string) error {
+	return socket.SubscribeToStream(symbol, PartialDepthStream(levels, speed))
+}
+
+func SubscribeDiffDepthStream(socket BinanceFutureSocket, symbol string, speed string) error {
+	return socket.SubscribeToStream(symbol, DiffDepthStream(speed))
+}
+
+// SubscribeCompositeIndexStream USD-M only, composite index symbols, e.g. DEFIUSDT
+func SubscribeCompositeIndexStream(socket BinanceFutureSocket, symbol string) error {
+	return socket.SubscribeToStream(symbol, compositeIndexStreamName)
+}
+
+// SubscribeAssetIndexStream USD-M only, multi-assets mode index of a single asset symbol, e.g. BTCUSD
+func SubscribeAssetIndexStream(socket BinanceFutureSocket, symbol string) error {
+	return socket.SubscribeToStream(symbol, assetIndexStreamName)
+}
+
+// All market streams
+
+func SubscribeAllTickersStream(socket BinanceFutureSocket) error {
+	return socket.SubscribeToStream("", AllTickersStream)
+}
+
+func SubscribeAllMiniTickersStream(socket BinanceFutureSocket) error {
+	return socket.SubscribeToStream("", AllMiniTickersStream)
+}
+
+func SubscribeAllBookTickersStream(socket BinanceFutureSocket) error {
+	return socket.SubscribeToStream("", AllBookTickersStream)
+}
+
+func SubscribeAllMarkPricesStream(socket BinanceFutureSocket, oneSecond bool) error {
+	if oneSecond {
+		return socket.SubscribeToStream("", AllMarkPricesStream+oneSecondUpdateSpeed)
+	}
+	return socket.SubscribeToStream("", AllMarkPricesStream)
+}
+
+func SubscribeAllLiquidationsStream(socket BinanceFutureSocket) error {
+	return socket.SubscribeToStream("", AllLiquidationsStream)
+}
+
+func SubscribeContractInfoStream(socket BinanceFutureSocket) error {
+	return socket.SubscribeToStream("", AllContractInfoStream)
+}
+
+// SubscribeAllAssetIndexesStream USD-M only
+func SubscribeAllAssetIndexesStream(socket BinanceFutureSocket) error {
+	return socket.SubscribeToStream("", AllAssetIndexesStream)
+}
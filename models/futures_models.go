@@ -30,6 +30,14 @@ type OrderResponse struct {
 	Side		string 	`json:"side"`
 	Price 		float64 `json:"price,string"`
 	Quantity 	float64 `json:"origQty,string"`
+	ClientOrderId 		string 	`json:"clientOrderId"`
+	Status 				string 	`json:"status"`
+	Type 				string 	`json:"type"`
+	ExecutedQuantity 	float64 `json:"executedQty,string"`
+	AveragePrice 		float64 `json:"avgPrice,string"`
+	StopPrice 			float64 `json:"stopPrice,string"`
+	ReduceOnly 			bool 	`json:"reduceOnly"`
+	UpdateTime 			int64 	`json:"updateTime"`
 }
 
 type PriceFilter struct {
@@ -84,6 +92,7 @@ type BookData struct {
 }
 
 type OrderBook struct {
+	LastUpdateId int64 `json:"lastUpdateId"`
 	Bids []BookData `json:"bids"`
 	Asks []BookData `json:"asks"`
 }
@@ -97,4 +106,106 @@ func (bd *BookData) UnmarshalJSON(data []byte) error {
 	bd.Price, _ = strconv.ParseFloat(v[0].(string), 64)
 	bd.Quantity, _ = strconv.ParseFloat(v[1].(string), 64)
 	return nil
-}
\ No newline at end of file
+}
+
+type Income struct {
+	Symbol        string  `json:"symbol"`
+	IncomeType    string  `json:"incomeType"`
+	Income        float64 `json:"income,string"`
+	Asset         string  `json:"asset"`
+	Info          string  `json:"info"`
+	Time          int64   `json:"time"`
+	TransactionId int64   `json:"tranId"`
+	TradeId       string  `json:"tradeId"`
+}
+
+type IncomeHistory []Income
+
+type CommissionRate struct {
+	Symbol              string  `json:"symbol"`
+	MakerCommissionRate float64 `json:"makerCommissionRate,string"`
+	TakerCommissionRate float64 `json:"takerCommissionRate,string"`
+}
+
+// AdlQuantile keys of Quantile are LONG, SHORT and HEDGE in hedge mode, BOTH in one-way mode.
+type AdlQuantile struct {
+	Symbol   string         `json:"symbol"`
+	Quantile map[string]int `json:"adlQuantile"`
+}
+
+type AdlQuantiles []AdlQuantile
+
+type DownloadId struct {
+	AvgCostTimestamp int64  `json:"avgCostTimestampOfLast30d"`
+	DownloadId       string `json:"downloadId"`
+}
+
+type DownloadLink struct {
+	DownloadId          string `json:"downloadId"`
+	Status              string `json:"status"`
+	Url                 string `json:"url"`
+	Notified            bool   `json:"notified"`
+	ExpirationTimestamp int64  `json:"expirationTimestamp"`
+	IsExpired           bool   `json:"isExpired"`
+}
+
+type AccountAsset struct {
+	Asset              string  `json:"asset"`
+	WalletBalance      float64 `json:"walletBalance,string"`
+	UnrealizedProfit   float64 `json:"unrealizedProfit,string"`
+	MarginBalance      float64 `json:"marginBalance,string"`
+	AvailableBalance   float64 `json:"availableBalance,string"`
+	CrossWalletBalance float64 `json:"crossWalletBalance,string"`
+}
+
+type AccountPosition struct {
+	Symbol           string  `json:"symbol"`
+	PositionAmount   float64 `json:"positionAmt,string"`
+	EntryPrice       float64 `json:"entryPrice,string"`
+	UnrealizedProfit float64 `json:"unrealizedProfit,string"`
+	Leverage         float64 `json:"leverage,string"`
+	Isolated         bool    `json:"isolated"`
+	PositionSide     string  `json:"positionSide"`
+}
+
+type AccountInformation struct {
+	Assets    []AccountAsset    `json:"assets"`
+	Positions []AccountPosition `json:"positions"`
+}
+
+type PositionRisk struct {
+	Symbol           string  `json:"symbol"`
+	PositionAmount   float64 `json:"positionAmt,string"`
+	EntryPrice       float64 `json:"entryPrice,string"`
+	BreakEvenPrice   float64 `json:"breakEvenPrice,string"`
+	MarkPrice        float64 `json:"markPrice,string"`
+	UnrealizedProfit float64 `json:"unRealizedProfit,string"`
+	LiquidationPrice float64 `json:"liquidationPrice,string"`
+	Leverage         float64 `json:"leverage,string"`
+	MarginType       string  `json:"marginType"`
+	IsolatedMargin   float64 `json:"isolatedMargin,string"`
+	PositionSide     string  `json:"positionSide"`
+	UpdateTime       int64   `json:"updateTime"`
+}
+
+type PositionRisks []PositionRisk
+
+// AccountTrade single fill returned by the trade list endpoint
+type AccountTrade struct {
+	Id              int64   `json:"id"`
+	OrderId         int64   `json:"orderId"`
+	Symbol          string  `json:"symbol"`
+	Side            string  `json:"side"`
+	PositionSide    string  `json:"positionSide"`
+	Price           float64 `json:"price,string"`
+	Quantity        float64 `json:"qty,string"`
+	QuoteQuantity   float64 `json:"quoteQty,string"`
+	RealizedPnl     float64 `json:"realizedPnl,string"`
+	Commission      float64 `json:"commission,string"`
+	CommissionAsset string  `json:"commissionAsset"`
+	Buyer           bool    `json:"buyer"`
+	Maker           bool    `json:"maker"`
+	Time            int64   `json:"time"`
+}
+
+type AccountTrades []AccountTrade
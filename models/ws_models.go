@@ -1,13 +1,54 @@
 package models
 
+import "encoding/json"
+
 const (
 	ReasonOrder       	= "ORDER"
 	ReasonFunding     	= "FUNDING_FEE"
+	ReasonDeposit 				= "DEPOSIT"
+	ReasonWithdraw 				= "WITHDRAW"
+	ReasonWithdrawReject 		= "WITHDRAW_REJECT"
+	ReasonAdjustment 			= "ADJUSTMENT"
+	ReasonInsuranceClear 		= "INSURANCE_CLEAR"
+	ReasonAdminDeposit 			= "ADMIN_DEPOSIT"
+	ReasonAdminWithdraw 		= "ADMIN_WITHDRAW"
+	ReasonMarginTransfer 		= "MARGIN_TRANSFER"
+	ReasonMarginTypeChange 		= "MARGIN_TYPE_CHANGE"
+	ReasonAssetTransfer 		= "ASSET_TRANSFER"
+	ReasonOptionsPremiumFee 	= "OPTIONS_PREMIUM_FEE"
+	ReasonOptionsSettleProfit 	= "OPTIONS_SETTLE_PROFIT"
+	ReasonAutoExchange 			= "AUTO_EXCHANGE"
+	ReasonCoinSwapDeposit 		= "COIN_SWAP_DEPOSIT"
+	ReasonCoinSwapWithdraw 		= "COIN_SWAP_WITHDRAW"
 	EventAccount      	= "ACCOUNT_UPDATE"
 	EventOrder 		  	= "ORDER_TRADE_UPDATE"
 	EventSymbolTicker 	= "24hrTicker"
 	EventLiquidation  	= "forceOrder"
+	EventMarkPrice 		= "markPriceUpdate"
+	EventBookTicker 	= "bookTicker"
+	EventAggTrade 		= "aggTrade"
+	EventDepth 			= "depthUpdate"
+	EventKline 			= "kline"
+	EventContinuousKline = "continuous_kline"
+	EventMiniTicker 	= "24hrMiniTicker"
+	EventCompositeIndex = "compositeIndex"
+	EventContractInfo 	= "contractInfo"
+	EventAssetIndex 	= "assetIndexUpdate"
+	EventListenKeyExpired = "listenKeyExpired"
+	EventMarginCall 	= "MARGIN_CALL"
+	EventAccountConfig 	= "ACCOUNT_CONFIG_UPDATE"
+	EventTradeLite 		= "TRADE_LITE"
+	EventStrategyUpdate = "STRATEGY_UPDATE"
+	EventGridUpdate 	= "GRID_UPDATE"
+	EventConditionalOrderReject = "CONDITIONAL_ORDER_TRIGGER_REJECT"
 	OrderUpdateExpired 	= "EXPIRED"
+
+	OrderStatusNew 				= "NEW"
+	OrderStatusPartiallyFilled 	= "PARTIALLY_FILLED"
+	OrderStatusFilled 			= "FILLED"
+	OrderStatusCanceled 		= "CANCELED"
+	OrderStatusExpired 			= "EXPIRED"
+	OrderStatusRejected 		= "REJECTED"
 )
 
 // Used for sending subscribe and unsubscribe message in websocket
@@ -17,13 +58,96 @@ type LiveStream struct {
 	Id     int      `json:"id"`
 }
 
+// StreamRequest control message, SET_PROPERTY mixes strings and booleans in params
+type StreamRequest struct {
+	Method string        `json:"method"`
+	Params []interface{} `json:"params,omitempty"`
+	Id     int           `json:"id"`
+}
+
+// StreamResponse answer to a StreamRequest with the same id, Error is set for rejected requests
+type StreamResponse struct {
+	Result json.RawMessage 		`json:"result"`
+	Id     int             		`json:"id"`
+	Error  *StreamResponseError `json:"error"`
+}
+
+type StreamResponseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"msg"`
+}
+
+// WsApiRequest request of the websocket api, signed requests carry apiKey, timestamp and signature in params
+type WsApiRequest struct {
+	Id 		int 				`json:"id"`
+	Method 	string 				`json:"method"`
+	Params 	map[string]string 	`json:"params,omitempty"`
+}
+
+// WsApiResponse answer to a WsApiRequest with the same id, Error is set when Status is not 200
+type WsApiResponse struct {
+	Id 			int 					`json:"id"`
+	Status 		int 					`json:"status"`
+	Result 		json.RawMessage 		`json:"result"`
+	Error 		*StreamResponseError 	`json:"error"`
+	RateLimits 	[]RateLimit 			`json:"rateLimits"`
+}
+
+// RateLimit usage of one limit after the request, Count is what was used of Limit in the interval
+type RateLimit struct {
+	RateLimitType 	string 	`json:"rateLimitType"`
+	Interval 		string 	`json:"interval"`
+	IntervalNum 	int 	`json:"intervalNum"`
+	Limit 			int 	`json:"limit"`
+	Count 			int 	`json:"count"`
+}
+
+// CombinedStreamMessage envelope of every message on the combined stream endpoint
+type CombinedStreamMessage struct {
+	Stream 	string 			`json:"stream"`
+	Data 	json.RawMessage `json:"data"`
+}
+
 // Identification meta for incoming websocket messages
 type StreamMetaMessage struct {
 	EventTime 		int `json:"E"`
 	Event 			string `json:"e"`
-	Reason struct {
-		MessageType string `json:"m"`
-	} `json:"a"`
+	Reason StreamMetaReason `json:"a"`
+}
+
+// UnmarshalJSON listenKeyExpired sends the event time as a string
+func (smm *StreamMetaMessage) UnmarshalJSON(data []byte) error {
+	var meta struct {
+		EventTime 	json.Number 		`json:"E"`
+		Event 		string 				`json:"e"`
+		Reason 		StreamMetaReason 	`json:"a"`
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return err
+	}
+	smm.Event, smm.Reason = meta.Event, meta.Reason
+	if meta.EventTime != "" {
+		eventTime, err := meta.EventTime.Int64()
+		if err != nil {
+			return err
+		}
+		smm.EventTime = int(eventTime)
+	}
+	return nil
+}
+
+// StreamMetaReason "a" is only an object in ACCOUNT_UPDATE, market streams use the key for
+// other values (best ask, aggregate trade id, ask levels) which are skipped
+type StreamMetaReason struct {
+	MessageType string `json:"m"`
+}
+
+func (smr *StreamMetaReason) UnmarshalJSON(data []byte) error {
+	if len(data) == 0 || data[0] != '{' {
+		return nil
+	}
+	type plainReason StreamMetaReason
+	return json.Unmarshal(data, (*plainReason)(smr))
 }
 
 type Position struct {
@@ -31,12 +155,183 @@ type Position struct {
 	Side 		string
 	Quantity 	float64 `json:"pa,string"`
 	EntryPrice 	float64 `json:"ep,string"`
+	BreakEvenPrice 		float64 `json:"bep,string"`
+	AccumulatedRealized float64 `json:"cr,string"`
+	UnrealizedPnl 		float64 `json:"up,string"`
+	MarginType 			string 	`json:"mt"`
+	IsolatedWallet 		float64 `json:"iw,string"`
+	PositionSide 		string 	`json:"ps"`
+}
+
+type StreamBalance struct {
+	Asset 				string 	`json:"a"`
+	WalletBalance 		float64 `json:"wb,string"`
+	CrossWalletBalance 	float64 `json:"cw,string"`
+	BalanceChange 		float64 `json:"bc,string"`
+}
+
+type AccountUpdateData struct {
+	Reason 		string 			`json:"m"`
+	Balances 	[]StreamBalance `json:"B"`
+	Positions 	[]Position 		`json:"P"`
 }
 
 type StreamAccountUpdate struct {
-	UpdateData struct{
-		Positions []Position `json:"P"`
-	} `json:"a"`
+	Event 			string `json:"e"`
+	EventTime 		int64 `json:"E"`
+	TransactionTime int64 `json:"T"`
+	UpdateData 		AccountUpdateData `json:"a"`
+}
+
+type BookTicker struct {
+	Event 			string 	`json:"e"`
+	UpdateId 		int64 	`json:"u"`
+	EventTime 		int64 	`json:"E"`
+	TransactionTime int64 	`json:"T"`
+	Symbol 			string 	`json:"s"`
+	BestBidPrice 	float64 `json:"b,string"`
+	BestBidQuantity float64 `json:"B,string"`
+	BestAskPrice 	float64 `json:"a,string"`
+	BestAskQuantity float64 `json:"A,string"`
+}
+
+type AggTrade struct {
+	Event 			string 	`json:"e"`
+	EventTime 		int64 	`json:"E"`
+	Symbol 			string 	`json:"s"`
+	AggTradeId 		int64 	`json:"a"`
+	Price 			float64 `json:"p,string"`
+	Quantity 		float64 `json:"q,string"`
+	FirstTradeId 	int64 	`json:"f"`
+	LastTradeId 	int64 	`json:"l"`
+	TradeTime 		int64 	`json:"T"`
+	IsBuyerMaker 	bool 	`json:"m"`
+}
+
+// DepthUpdate used by both partial book depth and diff depth streams
+type DepthUpdate struct {
+	Event 					string 		`json:"e"`
+	EventTime 				int64 		`json:"E"`
+	TransactionTime 		int64 		`json:"T"`
+	Symbol 					string 		`json:"s"`
+	FirstUpdateId 			int64 		`json:"U"`
+	FinalUpdateId 			int64 		`json:"u"`
+	PreviousFinalUpdateId 	int64 		`json:"pu"`
+	Bids 					[]BookData 	`json:"b"`
+	Asks 					[]BookData 	`json:"a"`
+}
+
+type MarkPriceUpdate struct {
+	Event 					string 	`json:"e"`
+	EventTime 				int64 	`json:"E"`
+	Symbol 					string 	`json:"s"`
+	MarkPrice 				float64 `json:"p,string"`
+	IndexPrice 				float64 `json:"i,string"`
+	EstimatedSettlePrice 	float64 `json:"P,string"`
+	FundingRate 			float64 `json:"r,string"`
+	NextFundingTime 		int64 	`json:"T"`
+}
+
+type StreamKline struct {
+	StartTime 				int64 	`json:"t"`
+	CloseTime 				int64 	`json:"T"`
+	Symbol 					string 	`json:"s"`
+	Interval 				string 	`json:"i"`
+	FirstTradeId 			int64 	`json:"f"`
+	LastTradeId 			int64 	`json:"L"`
+	Open 					float64 `json:"o,string"`
+	Close 					float64 `json:"c,string"`
+	High 					float64 `json:"h,string"`
+	Low 					float64 `json:"l,string"`
+	Volume 					float64 `json:"v,string"`
+	NumberOfTrades 			int64 	`json:"n"`
+	IsClosed 				bool 	`json:"x"`
+	QuoteVolume 			float64 `json:"q,string"`
+	TakerBuyVolume 			float64 `json:"V,string"`
+	TakerBuyQuoteVolume 	float64 `json:"Q,string"`
+	Ignore 					string 	`json:"B"`
+}
+
+type KlineUpdate struct {
+	Event 		string 		`json:"e"`
+	EventTime 	int64 		`json:"E"`
+	Symbol 		string 		`json:"s"`
+	Kline 		StreamKline `json:"k"`
+}
+
+type ContinuousKlineUpdate struct {
+	Event 			string 		`json:"e"`
+	EventTime 		int64 		`json:"E"`
+	Pair 			string 		`json:"ps"`
+	ContractType 	string 		`json:"ct"`
+	Kline 			StreamKline `json:"k"`
+}
+
+type MiniTicker struct {
+	Event 		string 	`json:"e"`
+	EventTime 	int64 	`json:"E"`
+	Symbol 		string 	`json:"s"`
+	Close 		float64 `json:"c,string"`
+	Open 		float64 `json:"o,string"`
+	High 		float64 `json:"h,string"`
+	Low 		float64 `json:"l,string"`
+	Volume 		float64 `json:"v,string"`
+	QuoteVolume float64 `json:"q,string"`
+}
+
+type CompositeIndexComponent struct {
+	BaseAsset 		string 	`json:"b"`
+	QuoteAsset 		string 	`json:"q"`
+	WeightQuantity 	float64 `json:"w,string"`
+	WeightPercent 	float64 `json:"W,string"`
+	IndexPrice 		float64 `json:"i,string"`
+}
+
+type CompositeIndex struct {
+	Event 		string 						`json:"e"`
+	EventTime 	int64 						`json:"E"`
+	Symbol 		string 						`json:"s"`
+	Price 		float64 					`json:"p,string"`
+	BaseType 	string 						`json:"C"`
+	Components 	[]CompositeIndexComponent 	`json:"c"`
+}
+
+type ContractBracket struct {
+	Bracket 			int 	`json:"bs"`
+	NotionalFloor 		float64 `json:"bnf"`
+	NotionalCap 		float64 `json:"bnc"`
+	MaintenanceRatio 	float64 `json:"mmr"`
+	Cumulative 			float64 `json:"cf"`
+	MinLeverage 		int 	`json:"mi"`
+	MaxLeverage 		int 	`json:"ma"`
+}
+
+type ContractInfo struct {
+	Event 			string 				`json:"e"`
+	EventTime 		int64 				`json:"E"`
+	Symbol 			string 				`json:"s"`
+	Pair 			string 				`json:"ps"`
+	ContractType 	string 				`json:"ct"`
+	DeliveryTime 	int64 				`json:"dt"`
+	OnboardTime 	int64 				`json:"ot"`
+	Status 			string 				`json:"cs"`
+	Brackets 		[]ContractBracket 	`json:"bks"`
+}
+
+// AssetIndex multi-assets mode index of a margin asset
+type AssetIndex struct {
+	Event 				string 	`json:"e"`
+	EventTime 			int64 	`json:"E"`
+	Symbol 				string 	`json:"s"`
+	IndexPrice 			float64 `json:"i,string"`
+	BidBuffer 			float64 `json:"b,string"`
+	AskBuffer 			float64 `json:"a,string"`
+	BidRate 			float64 `json:"B,string"`
+	AskRate 			float64 `json:"A,string"`
+	AutoExchangeBidBuffer float64 `json:"q,string"`
+	AutoExchangeAskBuffer float64 `json:"g,string"`
+	AutoExchangeBidRate float64 `json:"Q,string"`
+	AutoExchangeAskRate float64 `json:"G,string"`
 }
 
 type StreamOrder struct {
@@ -47,10 +342,145 @@ type StreamOrder struct {
 	ExecutionType 	string `json:"x"`
 	ClientId 		string `json:"c"`
 	OrderId			int64 	`json:"i"`
+	OriginalQuantity 	float64 `json:"q,string"`
+	Price 				float64 `json:"p,string"`
+	AveragePrice 		float64 `json:"ap,string"`
+	StopPrice 			float64 `json:"sp,string"`
+	LastFilledQuantity 	float64 `json:"l,string"`
+	FilledQuantity 		float64 `json:"z,string"`
+	LastFilledPrice 	float64 `json:"L,string"`
+	TradeTime 			int64 	`json:"T"`
+	TradeId 			int64 	`json:"t"`
+	ReduceOnly 			bool 	`json:"R"`
+	ActivationPrice 	float64 `json:"AP,string"`
+	Commission 			float64 `json:"n,string"`
+	CommissionAsset 	string 	`json:"N"`
+	IsMaker 			bool 	`json:"m"`
+	RealizedProfit 		float64 `json:"rp,string"`
+	TimeInForce 		string 	`json:"f"`
+	BidsNotional 		float64 `json:"b,string"`
+	AsksNotional 		float64 `json:"a,string"`
+	WorkingType 		string 	`json:"wt"`
+	OriginalType 		string 	`json:"ot"`
+	PositionSide 		string 	`json:"ps"`
+	ClosePosition 		bool 	`json:"cp"`
+	CallbackRate 		float64 `json:"cr,string"`
+	PriceProtect 		bool 	`json:"pP"`
+	SelfTradePrevention string 	`json:"V"`
+	PriceMatch 			string 	`json:"pm"`
+	GoodTillDate 		int64 	`json:"gtd"`
 }
 
+// Single letter keys are case sensitive in binance messages, while encoding/json is not.
+// Keep both cases declared, otherwise one silently overwrites the other.
 type StreamOrderUpdate struct {
-	OrderInformation StreamOrder `json:"o"`
+	Event 				string 		`json:"e"`
+	EventTime 			int64 		`json:"E"`
+	TransactionTime 	int64 		`json:"T"`
+	OrderInformation 	StreamOrder `json:"o"`
+}
+
+type MarginCallPosition struct {
+	Symbol 				string 	`json:"s"`
+	PositionSide 		string 	`json:"ps"`
+	Quantity 			float64 `json:"pa,string"`
+	MarginType 			string 	`json:"mt"`
+	IsolatedWallet 		float64 `json:"iw,string"`
+	MarkPrice 			float64 `json:"mp,string"`
+	UnrealizedPnl 		float64 `json:"up,string"`
+	MaintenanceMargin 	float64 `json:"mm,string"`
+}
+
+type StreamMarginCall struct {
+	Event 				string 					`json:"e"`
+	EventTime 			int64 					`json:"E"`
+	CrossWalletBalance 	float64 				`json:"cw,string"`
+	Positions 			[]MarginCallPosition 	`json:"p"`
+}
+
+// StreamAccountConfigUpdate either the leverage of a symbol or the multi-assets mode changed
+type StreamAccountConfigUpdate struct {
+	Event 				string 	`json:"e"`
+	EventTime 			int64 	`json:"E"`
+	TransactionTime 	int64 	`json:"T"`
+	LeverageConfig 		*struct {
+		Symbol 		string 	`json:"s"`
+		Leverage 	int 	`json:"l"`
+	} `json:"ac"`
+	AssetConfig 		*struct {
+		MultiAssetsMode bool `json:"j"`
+	} `json:"ai"`
+}
+
+// StreamTradeLite reduced and faster ORDER_TRADE_UPDATE, sent for trades only
+type StreamTradeLite struct {
+	Event 				string 	`json:"e"`
+	EventTime 			int64 	`json:"E"`
+	TransactionTime 	int64 	`json:"T"`
+	Symbol 				string 	`json:"s"`
+	OriginalQuantity 	float64 `json:"q,string"`
+	Price 				float64 `json:"p,string"`
+	IsMaker 			bool 	`json:"m"`
+	ClientId 			string 	`json:"c"`
+	Side 				string 	`json:"S"`
+	LastFilledPrice 	float64 `json:"L,string"`
+	LastFilledQuantity 	float64 `json:"l,string"`
+	TradeId 			int64 	`json:"t"`
+	OrderId 			int64 	`json:"i"`
+}
+
+type StrategyUpdate struct {
+	StrategyId 		int64 	`json:"si"`
+	StrategyType 	string 	`json:"st"`
+	StrategyStatus 	string 	`json:"ss"`
+	Symbol 			string 	`json:"s"`
+	UpdateTime 		int64 	`json:"ut"`
+	OperationCode 	int 	`json:"c"`
+}
+
+type StreamStrategyUpdate struct {
+	Event 			string 			`json:"e"`
+	EventTime 		int64 			`json:"E"`
+	TransactionTime int64 			`json:"T"`
+	Strategy 		StrategyUpdate 	`json:"su"`
+}
+
+type GridUpdate struct {
+	StrategyId 			int64 	`json:"si"`
+	StrategyType 		string 	`json:"st"`
+	StrategyStatus 		string 	`json:"ss"`
+	Symbol 				string 	`json:"s"`
+	RealizedPnl 		float64 `json:"r,string"`
+	UnmatchedAverage 	float64 `json:"up,string"`
+	UnmatchedQuantity 	float64 `json:"uq,string"`
+	UnmatchedFee 		float64 `json:"uf,string"`
+	MatchedPnl 			float64 `json:"mp,string"`
+	UpdateTime 			int64 	`json:"ut"`
+}
+
+type StreamGridUpdate struct {
+	Event 			string 		`json:"e"`
+	EventTime 		int64 		`json:"E"`
+	TransactionTime int64 		`json:"T"`
+	Grid 			GridUpdate 	`json:"gu"`
+}
+
+type StreamConditionalOrderReject struct {
+	Event 			string 	`json:"e"`
+	EventTime 		int64 	`json:"E"`
+	TransactionTime int64 	`json:"T"`
+	Rejection 		struct {
+		Symbol 	string 	`json:"s"`
+		OrderId int64 	`json:"i"`
+		Reason 	string 	`json:"r"`
+	} `json:"or"`
+}
+
+// StreamListenKeyExpired the event time is a string in this event, json.Number accepts both
+type StreamListenKeyExpired struct {
+	Event 		string 		`json:"e"`
+	EventTime 	json.Number `json:"E"`
+	ListenKey 	string 		`json:"listenKey"`
 }
 
 type StreamSymbolTickerUpdate struct {
@@ -0,0 +1,383 @@
+package go_binance
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/redlon23/go-binance/models"
+	"github.com/sirupsen/logrus"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultOrderBookSnapshotLimit = 1000
+	DefaultOrderBookRetryDelay    = time.Second
+	// Updates buffered while the snapshot is fetched, the oldest are dropped beyond it
+	DefaultOrderBookBufferSize = 1000
+
+	OrderBookEventSynced   = "SYNCED"
+	OrderBookEventUpdate   = "UPDATE"
+	OrderBookEventResync   = "RESYNC"
+	OrderBookSideBid       = "BID"
+	OrderBookSideAsk       = "ASK"
+	orderBookStateUnsynced = 0
+	orderBookStateSnapshot = 1
+	orderBookStateSynced   = 2
+)
+
+// OrderBookEvent change notification, Reason is set for RESYNC.
+type OrderBookEvent struct {
+	Type         string
+	Symbol       string
+	LastUpdateId int64
+	EventTime    int64
+	Reason       string
+}
+
+// OrderBookManager keeps a local order book of one symbol from a REST snapshot and the
+// <symbol>@depth stream. Updates are buffered while the snapshot is fetched, then applied
+// following the binance rules: updates with u below lastUpdateId of the snapshot are dropped,
+// the first applied update has U <= lastUpdateId <= u and every later one has pu equal to
+// u of the previous update. Anything else is a gap and the book is fetched again.
+// Feed it with HandleMessage or HandleDepthUpdate, the snapshot is fetched in the background
+// so the reading goroutine is not blocked.
+type OrderBookManager struct {
+	Api           BinanceFutures
+	Symbol        string
+	SnapshotLimit int
+	RetryDelay    time.Duration
+	BufferSize    int
+	Logger        *logrus.Logger
+
+	mu           sync.RWMutex
+	delivering   sync.Mutex
+	pending      []OrderBookEvent
+	state        int
+	generation   int
+	fetching     bool
+	closed       bool
+	buffer       []models.DepthUpdate
+	bids         []models.BookData
+	asks         []models.BookData
+	lastUpdateId int64
+	eventTime    int64
+	listeners    []func(OrderBookEvent)
+}
+
+func NewOrderBookManager(api BinanceFutures, symbol string) *OrderBookManager {
+	return &OrderBookManager{
+		Api:           api,
+		Symbol:        symbol,
+		SnapshotLimit: DefaultOrderBookSnapshotLimit,
+		RetryDelay:    DefaultOrderBookRetryDelay,
+		BufferSize:    DefaultOrderBookBufferSize,
+		Logger:        logrus.New(),
+	}
+}
+
+func (obm *OrderBookManager) PrepareLoggers() {
+	obm.Logger = logrus.New()
+	obm.Logger.Formatter = new(logrus.JSONFormatter)
+
+	bookLogs, err := os.OpenFile("logs/binance_order_book.log", os.O_CREATE|os.O_WRONLY, 0666)
+	if err == nil {
+		obm.Logger.SetOutput(bookLogs)
+	} else {
+		fmt.Println("Failed to log to file for order book manager, using default stderr")
+	}
+}
+
+// Subscribe registers a listener for book changes, listeners are called without the lock held.
+func (obm *OrderBookManager) Subscribe(listener func(OrderBookEvent)) {
+	obm.mu.Lock()
+	defer obm.mu.Unlock()
+	obm.listeners = append(obm.listeners, listener)
+}
+
+// unlockAndEmit queues the events, releases the lock and delivers queued events unless another
+// goroutine is delivering already, so listeners see events of the reading and of the snapshot
+// goroutine in the order they were applied and may call the manager themselves.
+func (obm *OrderBookManager) unlockAndEmit(events []OrderBookEvent) {
+	obm.pending = append(obm.pending, events...)
+	obm.mu.Unlock()
+	if !obm.delivering.TryLock() {
+		return
+	}
+	for {
+		obm.mu.Lock()
+		if len(obm.pending) == 0 {
+			// released under mu, events queued later find no delivering goroutine
+			obm.delivering.Unlock()
+			obm.mu.Unlock()
+			return
+		}
+		events, listeners := obm.pending, obm.listeners
+		obm.pending = nil
+		obm.mu.Unlock()
+		for _, event := range events {
+			for _, listener := range listeners {
+				listener(event)
+			}
+		}
+	}
+}
+
+// HandleMessage accepts raw or combined stream messages, depthUpdate of the symbol is applied.
+func (obm *OrderBookManager) HandleMessage(message []byte) error {
+	_, data, err := UnwrapCombinedMessage(message)
+	if err != nil {
+		return err
+	}
+	meta, err := ParseMetaInformation(data)
+	if err != nil {
+		return err
+	}
+	if meta.Event != models.EventDepth {
+		return nil
+	}
+	update := models.DepthUpdate{}
+	if err := json.Unmarshal(data, &update); err != nil {
+		return err
+	}
+	obm.HandleDepthUpdate(update)
+	return nil
+}
+
+// HandleDepthUpdate fits EventDispatcher.OnDepthUpdate, updates of other symbols are ignored.
+func (obm *OrderBookManager) HandleDepthUpdate(update models.DepthUpdate) {
+	if update.Symbol != obm.Symbol {
+		return
+	}
+	obm.mu.Lock()
+	if obm.closed {
+		obm.mu.Unlock()
+		return
+	}
+	var events []OrderBookEvent
+	if obm.state == orderBookStateUnsynced {
+		obm.bufferUpdate(update)
+	} else {
+		events = obm.apply(update)
+	}
+	obm.unlockAndEmit(events)
+}
+
+func (obm *OrderBookManager) bufferUpdate(update models.DepthUpdate) {
+	obm.buffer = append(obm.buffer, update)
+	if obm.BufferSize > 0 && len(obm.buffer) > obm.BufferSize {
+		obm.buffer = obm.buffer[len(obm.buffer)-obm.BufferSize:]
+	}
+	if !obm.fetching {
+		obm.fetching = true
+		go obm.fetchSnapshot(obm.generation)
+	}
+}
+
+// apply runs with the lock held on a book which has a snapshot
+func (obm *OrderBookManager) apply(update models.DepthUpdate) []OrderBookEvent {
+	if update.FinalUpdateId < obm.lastUpdateId {
+		return nil
+	}
+	if obm.state == orderBookStateSnapshot {
+		if update.FirstUpdateId > obm.lastUpdateId {
+			return obm.resync(update, "snapshot is older than the first buffered update")
+		}
+	} else if update.PreviousFinalUpdateId != obm.lastUpdateId {
+		return obm.resync(update, fmt.Sprintf("update %d does not follow %d", update.FirstUpdateId, obm.lastUpdateId))
+	}
+
+	obm.bids = applyLevels(obm.bids, update.Bids, true)
+	obm.asks = applyLevels(obm.asks, update.Asks, false)
+	obm.lastUpdateId, obm.eventTime = update.FinalUpdateId, update.EventTime
+	event := OrderBookEvent{Type: OrderBookEventUpdate, Symbol: obm.Symbol, LastUpdateId: obm.lastUpdateId,
+		EventTime: obm.eventTime}
+	if obm.state == orderBookStateSnapshot {
+		obm.state = orderBookStateSynced
+		event.Type = OrderBookEventSynced
+	}
+	return []OrderBookEvent{event}
+}
+
+// resync drops the book and buffers the update which revealed the gap for the next snapshot
+func (obm *OrderBookManager) resync(update models.DepthUpdate, reason string) []OrderBookEvent {
+	obm.Logger.Warn("order book of ", obm.Symbol, " out of sync, fetching snapshot again: ", reason)
+	obm.state = orderBookStateUnsynced
+	obm.generation++
+	obm.fetching = false
+	obm.bids, obm.asks, obm.buffer = nil, nil, nil
+	obm.bufferUpdate(update)
+	return []OrderBookEvent{{Type: OrderBookEventResync, Symbol: obm.Symbol, LastUpdateId: obm.lastUpdateId,
+		EventTime: update.EventTime, Reason: reason}}
+}
+
+// Resync drops the book, the next update starts a new snapshot. Useful after a reconnect.
+func (obm *OrderBookManager) Resync() {
+	obm.mu.Lock()
+	obm.state = orderBookStateUnsynced
+	obm.generation++
+	obm.fetching = false
+	obm.bids, obm.asks, obm.buffer = nil, nil, nil
+	event := OrderBookEvent{Type: OrderBookEventResync, Symbol: obm.Symbol, LastUpdateId: obm.lastUpdateId,
+		Reason: "requested"}
+	obm.unlockAndEmit([]OrderBookEvent{event})
+}
+
+// fetchSnapshot retries until a snapshot is loaded, the book is resynced again or closed
+func (obm *OrderBookManager) fetchSnapshot(generation int) {
+	for {
+		book, err := obm.loadSnapshot()
+		obm.mu.Lock()
+		if obm.closed || obm.generation != generation {
+			obm.mu.Unlock()
+			return
+		}
+		if err != nil {
+			obm.mu.Unlock()
+			obm.Logger.Error("could not load order book snapshot of ", obm.Symbol, ": ", err)
+			time.Sleep(obm.RetryDelay)
+			continue
+		}
+
+		obm.fetching = false
+		obm.state = orderBookStateSnapshot
+		obm.lastUpdateId = book.LastUpdateId
+		obm.bids = applyLevels(nil, book.Bids, true)
+		obm.asks = applyLevels(nil, book.Asks, false)
+		buffered := obm.buffer
+		obm.buffer = nil
+		var events []OrderBookEvent
+		for index, update := range buffered {
+			events = append(events, obm.apply(update)...)
+			if obm.state == orderBookStateUnsynced {
+				// apply started a new snapshot, the rest is buffered for it
+				for _, rest := range buffered[index+1:] {
+					obm.bufferUpdate(rest)
+				}
+				break
+			}
+		}
+		obm.unlockAndEmit(events)
+		return
+	}
+}
+
+func (obm *OrderBookManager) loadSnapshot() (*models.OrderBook, error) {
+	data, err := obm.Api.GetOrderBook(obm.Symbol, obm.SnapshotLimit)
+	if err != nil {
+		return nil, err
+	}
+	book := new(models.OrderBook)
+	if err := json.Unmarshal(data, book); err != nil {
+		return nil, err
+	}
+	return book, nil
+}
+
+// applyLevels sets the quantity of every level, quantity 0 removes the level.
+// Bids are kept descending and asks ascending by price.
+func applyLevels(levels, changes []models.BookData, descending bool) []models.BookData {
+	for _, change := range changes {
+		index := sort.Search(len(levels), func(i int) bool {
+			if descending {
+				return levels[i].Price <= change.Price
+			}
+			return levels[i].Price >= change.Price
+		})
+		exists := index < len(levels) && levels[index].Price == change.Price
+		switch {
+		case change.Quantity == 0 && exists:
+			levels = append(levels[:index], levels[index+1:]...)
+		case change.Quantity == 0:
+		case exists:
+			levels[index].Quantity = change.Quantity
+		default:
+			levels = append(levels, models.BookData{})
+			copy(levels[index+1:], levels[index:])
+			levels[index] = change
+		}
+	}
+	return levels
+}
+
+// Synced the book reflects the stream, reads of an unsynced book return nothing.
+func (obm *OrderBookManager) Synced() bool {
+	obm.mu.RLock()
+	defer obm.mu.RUnlock()
+	return obm.state == orderBookStateSynced
+}
+
+func (obm *OrderBookManager) LastUpdateId() int64 {
+	obm.mu.RLock()
+	defer obm.mu.RUnlock()
+	return obm.lastUpdateId
+}
+
+func (obm *OrderBookManager) BestBid() (models.BookData, bool) {
+	obm.mu.RLock()
+	defer obm.mu.RUnlock()
+	if obm.state != orderBookStateSynced || len(obm.bids) == 0 {
+		return models.BookData{}, false
+	}
+	return obm.bids[0], true
+}
+
+func (obm *OrderBookManager) BestAsk() (models.BookData, bool) {
+	obm.mu.RLock()
+	defer obm.mu.RUnlock()
+	if obm.state != orderBookStateSynced || len(obm.asks) == 0 {
+		return models.BookData{}, false
+	}
+	return obm.asks[0], true
+}
+
+// Depth copy of the best levels on both sides, all levels for levels <= 0.
+func (obm *OrderBookManager) Depth(levels int) models.OrderBook {
+	obm.mu.RLock()
+	defer obm.mu.RUnlock()
+	book := models.OrderBook{LastUpdateId: obm.lastUpdateId, Bids: []models.BookData{}, Asks: []models.BookData{}}
+	if obm.state != orderBookStateSynced {
+		return book
+	}
+	book.Bids = append(book.Bids, topLevels(obm.bids, levels)...)
+	book.Asks = append(book.Asks, topLevels(obm.asks, levels)...)
+	return book
+}
+
+func topLevels(levels []models.BookData, count int) []models.BookData {
+	if count <= 0 || count > len(levels) {
+		return levels
+	}
+	return levels[:count]
+}
+
+// CumulativeQuantity quantity of the side at prices at least as good as price: bids at or above
+// and asks at or below it, which is what a taker order limited to price could fill against.
+func (obm *OrderBookManager) CumulativeQuantity(side string, price float64) float64 {
+	obm.mu.RLock()
+	defer obm.mu.RUnlock()
+	if obm.state != orderBookStateSynced {
+		return 0
+	}
+	levels, better := obm.asks, func(level float64) bool { return level <= price }
+	if side == OrderBookSideBid {
+		levels, better = obm.bids, func(level float64) bool { return level >= price }
+	}
+	quantity := 0.0
+	for _, level := range levels {
+		if !better(level.Price) {
+			break
+		}
+		quantity += level.Quantity
+	}
+	return quantity
+}
+
+// Close stops a running snapshot fetch, later updates are ignored.
+func (obm *OrderBookManager) Close() {
+	obm.mu.Lock()
+	defer obm.mu.Unlock()
+	obm.closed = true
+	obm.generation++
+}
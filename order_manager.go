@@ -0,0 +1,271 @@
+package go_binance
+
+import (
+	"encoding/json"
+	"github.com/redlon23/go-binance/models"
+	"sync"
+)
+
+// ManagedOrder local view of an order, built from REST acknowledgements and ORDER_TRADE_UPDATE events.
+type ManagedOrder struct {
+	ClientOrderId  string
+	OrderId        int64
+	Symbol         string
+	Side           string
+	Type           string
+	Price          float64
+	StopPrice      float64
+	Quantity       float64
+	FilledQuantity float64
+	AveragePrice   float64
+	ReduceOnly     bool
+	Status         string
+	// Last filled quantity and price of the update that produced this state
+	LastFilledQuantity float64
+	LastFilledPrice    float64
+	// Exchange time of the latest applied update, in milliseconds
+	UpdateTime int64
+	// Set once the REST response of the placement has been seen
+	Acknowledged bool
+}
+
+func (mo ManagedOrder) IsOpen() bool {
+	return mo.Status == models.OrderStatusNew || mo.Status == models.OrderStatusPartiallyFilled
+}
+
+func (mo ManagedOrder) RemainingQuantity() float64 {
+	return mo.Quantity - mo.FilledQuantity
+}
+
+func orderStatusRank(status string) int {
+	switch status {
+	case models.OrderStatusNew:
+		return 0
+	case models.OrderStatusPartiallyFilled:
+		return 1
+	case models.OrderStatusFilled, models.OrderStatusCanceled,
+		models.OrderStatusExpired, models.OrderStatusRejected:
+		return 2
+	}
+	return -1
+}
+
+// isNewerOrderState decides whether update should replace current.
+// Events can arrive out of order, so filled quantity and status progression
+// win over timestamps, terminal states are never left.
+func isNewerOrderState(current, update *ManagedOrder) bool {
+	if orderStatusRank(current.Status) == 2 {
+		return false
+	}
+	if update.FilledQuantity != current.FilledQuantity {
+		return update.FilledQuantity > current.FilledQuantity
+	}
+	currentRank, updateRank := orderStatusRank(current.Status), orderStatusRank(update.Status)
+	if currentRank != updateRank {
+		return updateRank > currentRank
+	}
+	return update.UpdateTime >= current.UpdateTime
+}
+
+func managedOrderFromUpdate(update models.StreamOrderUpdate) *ManagedOrder {
+	info := update.OrderInformation
+	updateTime := info.TradeTime
+	if updateTime == 0 {
+		updateTime = update.TransactionTime
+	}
+	return &ManagedOrder{
+		ClientOrderId:      info.ClientId,
+		OrderId:            info.OrderId,
+		Symbol:             info.Symbol,
+		Side:               info.Side,
+		Type:               info.Type,
+		Price:              info.Price,
+		StopPrice:          info.StopPrice,
+		Quantity:           info.OriginalQuantity,
+		FilledQuantity:     info.FilledQuantity,
+		AveragePrice:       info.AveragePrice,
+		ReduceOnly:         info.ReduceOnly,
+		Status:             info.ExecutionStatus,
+		LastFilledQuantity: info.LastFilledQuantity,
+		LastFilledPrice:    info.LastFilledPrice,
+		UpdateTime:         updateTime,
+	}
+}
+
+func managedOrderFromResponse(response models.OrderResponse) *ManagedOrder {
+	return &ManagedOrder{
+		ClientOrderId:  response.ClientOrderId,
+		OrderId:        response.OrderId,
+		Symbol:         response.Symbol,
+		Side:           response.Side,
+		Type:           response.Type,
+		Price:          response.Price,
+		StopPrice:      response.StopPrice,
+		Quantity:       response.Quantity,
+		FilledQuantity: response.ExecutedQuantity,
+		AveragePrice:   response.AveragePrice,
+		ReduceOnly:     response.ReduceOnly,
+		Status:         response.Status,
+		UpdateTime:     response.UpdateTime,
+		Acknowledged:   true,
+	}
+}
+
+// OrderManager keeps the state of orders in memory, keyed by client order id and order id.
+// Feed it with HandleOrderUpdate (or HandleMessage for raw user stream messages) and
+// Acknowledge with the REST response of each placement.
+type OrderManager struct {
+	mu              sync.RWMutex
+	orders          map[string]*ManagedOrder
+	orderIds        map[int64]string
+	callbacks       map[string][]func(ManagedOrder)
+	globalCallbacks []func(ManagedOrder)
+}
+
+func NewOrderManager() *OrderManager {
+	return &OrderManager{
+		orders:    make(map[string]*ManagedOrder),
+		orderIds:  make(map[int64]string),
+		callbacks: make(map[string][]func(ManagedOrder)),
+	}
+}
+
+// Subscribe registers a callback for a single order, called after every applied change.
+// Callbacks of an order are dropped once it reaches a final state.
+func (om *OrderManager) Subscribe(clientOrderId string, callback func(ManagedOrder)) {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+	om.callbacks[clientOrderId] = append(om.callbacks[clientOrderId], callback)
+}
+
+// SubscribeAll registers a callback which is called for changes of every order.
+func (om *OrderManager) SubscribeAll(callback func(ManagedOrder)) {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+	om.globalCallbacks = append(om.globalCallbacks, callback)
+}
+
+// HandleMessage accepts raw user stream messages, anything other than ORDER_TRADE_UPDATE is ignored.
+func (om *OrderManager) HandleMessage(message []byte) error {
+	meta, err := ParseMetaInformation(message)
+	if err != nil {
+		return err
+	}
+	if meta.Event != models.EventOrder {
+		return nil
+	}
+	update := new(models.StreamOrderUpdate)
+	if err := json.Unmarshal(message, update); err != nil {
+		return err
+	}
+	om.HandleOrderUpdate(*update)
+	return nil
+}
+
+func (om *OrderManager) HandleOrderUpdate(update models.StreamOrderUpdate) {
+	om.apply(managedOrderFromUpdate(update), false)
+}
+
+// Acknowledge records the REST response of an order placement.
+// Stream events may already have moved the order further, in that case
+// only the acknowledgement flag is set.
+// Responses of QueryOrder can be passed as well to catch up after a stream gap.
+func (om *OrderManager) Acknowledge(response models.OrderResponse) {
+	om.apply(managedOrderFromResponse(response), true)
+}
+
+// AcknowledgeResponse decodes the body returned by the order calls and acknowledges it.
+func (om *OrderManager) AcknowledgeResponse(data []byte) (*models.OrderResponse, error) {
+	response := new(models.OrderResponse)
+	if err := json.Unmarshal(data, response); err != nil {
+		return nil, err
+	}
+	om.Acknowledge(*response)
+	return response, nil
+}
+
+func (om *OrderManager) apply(update *ManagedOrder, acknowledgement bool) {
+	om.mu.Lock()
+	clientOrderId := update.ClientOrderId
+	if clientOrderId == "" {
+		clientOrderId = om.orderIds[update.OrderId]
+		update.ClientOrderId = clientOrderId
+	}
+	current, exists := om.orders[clientOrderId]
+	changed := false
+	switch {
+	case !exists:
+		om.orders[clientOrderId] = update
+		current = update
+		changed = true
+	case isNewerOrderState(current, update):
+		update.Acknowledged = current.Acknowledged || acknowledgement
+		*current = *update
+		changed = true
+	case acknowledgement && !current.Acknowledged:
+		current.Acknowledged = true
+	}
+	if current.OrderId != 0 {
+		om.orderIds[current.OrderId] = clientOrderId
+	}
+
+	if !changed {
+		om.mu.Unlock()
+		return
+	}
+	snapshot := *current
+	callbacks := append(append([]func(ManagedOrder){}, om.callbacks[clientOrderId]...), om.globalCallbacks...)
+	if !snapshot.IsOpen() {
+		delete(om.callbacks, clientOrderId)
+	}
+	om.mu.Unlock()
+
+	for _, callback := range callbacks {
+		callback(snapshot)
+	}
+}
+
+func (om *OrderManager) GetOrder(clientOrderId string) (ManagedOrder, bool) {
+	om.mu.RLock()
+	defer om.mu.RUnlock()
+	order, exists := om.orders[clientOrderId]
+	if !exists {
+		return ManagedOrder{}, false
+	}
+	return *order, true
+}
+
+func (om *OrderManager) GetOrderById(orderId int64) (ManagedOrder, bool) {
+	om.mu.RLock()
+	clientOrderId, exists := om.orderIds[orderId]
+	om.mu.RUnlock()
+	if !exists {
+		return ManagedOrder{}, false
+	}
+	return om.GetOrder(clientOrderId)
+}
+
+// OpenOrders returns NEW and PARTIALLY_FILLED orders, empty symbol returns all symbols.
+func (om *OrderManager) OpenOrders(symbol string) []ManagedOrder {
+	om.mu.RLock()
+	defer om.mu.RUnlock()
+	open := make([]ManagedOrder, 0)
+	for _, order := range om.orders {
+		if order.IsOpen() && (symbol == "" || order.Symbol == symbol) {
+			open = append(open, *order)
+		}
+	}
+	return open
+}
+
+// PruneClosedOrders removes orders in a final state last updated before the given time in milliseconds.
+func (om *OrderManager) PruneClosedOrders(before int64) {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+	for clientOrderId, order := range om.orders {
+		if !order.IsOpen() && order.UpdateTime < before {
+			delete(om.orders, clientOrderId)
+			delete(om.orderIds, order.OrderId)
+		}
+	}
+}
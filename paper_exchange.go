@@ -0,0 +1,1005 @@
+package go_binance
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/redlon23/go-binance/models"
+	"github.com/sirupsen/logrus"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultPaperMakerFee              = 0.0002
+	DefaultPaperTakerFee              = 0.0004
+	DefaultPaperLeverage              = 20
+	DefaultPaperMaintenanceMarginRate = 0.004
+
+	paperAsset = "USDT"
+
+	executionTypeNew         = "NEW"
+	executionTypeTrade       = "TRADE"
+	executionTypeCanceled    = "CANCELED"
+	executionTypeExpired     = "EXPIRED"
+	liquidationClientIdStart = "autoclose-"
+)
+
+var errPaperNotSupported = errors.New("not supported in paper trading")
+
+type paperOrder struct {
+	orderId       int64
+	clientOrderId string
+	symbol        string
+	side          string
+	orderType     string
+	timeInForce   string
+	status        string
+	price         float64
+	stopPrice     float64
+	quantity      float64
+	executed      float64
+	cumulative    float64
+	reduceOnly    bool
+	closePosition bool
+	updateTime    int64
+}
+
+func (po *paperOrder) averagePrice() float64 {
+	if po.executed == 0 {
+		return 0
+	}
+	return po.cumulative / po.executed
+}
+
+func (po *paperOrder) isOpen() bool {
+	return po.status == models.OrderStatusNew || po.status == models.OrderStatusPartiallyFilled
+}
+
+func (po *paperOrder) response() models.OrderResponse {
+	return models.OrderResponse{
+		OrderId:          po.orderId,
+		Symbol:           po.symbol,
+		Side:             po.side,
+		Price:            po.price,
+		Quantity:         po.quantity,
+		ClientOrderId:    po.clientOrderId,
+		Status:           po.status,
+		Type:             po.orderType,
+		ExecutedQuantity: po.executed,
+		AveragePrice:     po.averagePrice(),
+		StopPrice:        po.stopPrice,
+		ReduceOnly:       po.reduceOnly,
+		UpdateTime:       po.updateTime,
+	}
+}
+
+type paperPosition struct {
+	quantity   float64
+	entryPrice float64
+	realized   float64
+}
+
+type paperBook struct {
+	bids []models.BookData
+	asks []models.BookData
+	mark float64
+	last float64
+}
+
+// Reference price for stops and margin, last trade or mid of the book
+func (pb *paperBook) lastPrice() float64 {
+	if pb.last != 0 {
+		return pb.last
+	}
+	if len(pb.bids) > 0 && len(pb.asks) > 0 {
+		return (pb.bids[0].Price + pb.asks[0].Price) / 2
+	}
+	return pb.mark
+}
+
+func (pb *paperBook) markPrice() float64 {
+	if pb.mark != 0 {
+		return pb.mark
+	}
+	return pb.lastPrice()
+}
+
+// PaperExchange in memory simulated USD-M exchange implementing BinanceFutures.
+// Orders are matched against books fed through HandleMessage (bookTicker, partial depth,
+// markPriceUpdate and aggTrade messages) or SetOrderBook. Books are not depleted by own fills
+// and resting limit orders fill completely as soon as the opposite side crosses their price.
+// Single asset cross margin, one-way position mode, no funding.
+// Public endpoints without local data are served by MarketData when it is set.
+type PaperExchange struct {
+	MarketData            BinanceFutures
+	MakerFee              float64
+	TakerFee              float64
+	MaintenanceMarginRate float64
+	Logger                *logrus.Logger
+
+	mu         sync.Mutex
+	balance    float64
+	leverage   map[string]float64
+	books      map[string]*paperBook
+	orders     map[int64]*paperOrder
+	positions  map[string]*paperPosition
+	trades     []models.AccountTrade
+	incomes    []models.Income
+	orderCount int64
+	tradeCount int64
+	listeners  []func([]byte)
+	pending    [][]byte
+	// held by the goroutine delivering pending events, see flush
+	delivering sync.Mutex
+}
+
+func NewPaperExchange(balance float64) *PaperExchange {
+	return &PaperExchange{
+		MakerFee:              DefaultPaperMakerFee,
+		TakerFee:              DefaultPaperTakerFee,
+		MaintenanceMarginRate: DefaultPaperMaintenanceMarginRate,
+		Logger:                logrus.New(),
+		balance:               balance,
+		leverage:              make(map[string]float64),
+		books:                 make(map[string]*paperBook),
+		orders:                make(map[int64]*paperOrder),
+		positions:             make(map[string]*paperPosition),
+	}
+}
+
+func (pe *PaperExchange) PrepareLoggers() {
+	pe.Logger = logrus.New()
+	pe.Logger.Formatter = new(logrus.JSONFormatter)
+
+	paperLogs, err := os.OpenFile("logs/binance_paper.log", os.O_CREATE|os.O_WRONLY, 0666)
+	if err == nil {
+		pe.Logger.SetOutput(paperLogs)
+	} else {
+		fmt.Println("Failed to log to file for paper trading, using default stderr")
+	}
+}
+
+func (pe *PaperExchange) SetApiKeys(public, secret string) {}
+func (pe *PaperExchange) NewNetClient()                    {}
+func (pe *PaperExchange) NewNetClientHTTP2()               {}
+func (pe *PaperExchange) UseMainNet()                      {}
+func (pe *PaperExchange) UseTestNet()                      {}
+
+// SetOrderMode only validated, paper orders never reach the exchange in any mode.
+func (pe *PaperExchange) SetOrderMode(mode string) error {
+	return ValidateOrderMode(mode)
+}
+
+func (pe *PaperExchange) SetLeverage(symbol string, leverage float64) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	pe.leverage[symbol] = leverage
+}
+
+// Subscribe registers a receiver for ORDER_TRADE_UPDATE and ACCOUNT_UPDATE messages.
+func (pe *PaperExchange) Subscribe(listener func([]byte)) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	pe.listeners = append(pe.listeners, listener)
+}
+
+// flush delivers events collected under the lock, called after unlocking. Listeners run without
+// any lock held and may call the exchange again, events they cause are queued and delivered
+// in order by the goroutine which is delivering already.
+func (pe *PaperExchange) flush() {
+	if !pe.delivering.TryLock() {
+		return
+	}
+	for {
+		pe.mu.Lock()
+		if len(pe.pending) == 0 {
+			// released under mu, events queued later find no delivering goroutine
+			pe.delivering.Unlock()
+			pe.mu.Unlock()
+			return
+		}
+		pending := pe.pending
+		listeners := make([]func([]byte), len(pe.listeners))
+		copy(listeners, pe.listeners)
+		pe.pending = nil
+		pe.mu.Unlock()
+		for _, event := range pending {
+			for _, listener := range listeners {
+				listener(event)
+			}
+		}
+	}
+}
+
+func (pe *PaperExchange) book(symbol string) *paperBook {
+	book, exists := pe.books[symbol]
+	if !exists {
+		book = new(paperBook)
+		pe.books[symbol] = book
+	}
+	return book
+}
+
+func (pe *PaperExchange) position(symbol string) *paperPosition {
+	position, exists := pe.positions[symbol]
+	if !exists {
+		position = new(paperPosition)
+		pe.positions[symbol] = position
+	}
+	return position
+}
+
+func (pe *PaperExchange) symbolLeverage(symbol string) float64 {
+	if leverage, exists := pe.leverage[symbol]; exists {
+		return leverage
+	}
+	return DefaultPaperLeverage
+}
+
+func paperNow() int64 {
+	return time.Now().UnixNano() / int64(time.Millisecond)
+}
+
+func formatPaperFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func paperError(code int, message string) error {
+	return &RequestError{
+		StatusCode: http.StatusBadRequest,
+		UrlUsed:    "paper",
+		Message:    BinanceErrorMessage{Code: code, Message: message},
+	}
+}
+
+// ======================= MARKET DATA ================================
+
+// SetOrderBook replaces the book of the symbol, bids descending and asks ascending.
+func (pe *PaperExchange) SetOrderBook(symbol string, book models.OrderBook) {
+	pe.mu.Lock()
+	paper := pe.book(symbol)
+	paper.bids, paper.asks = book.Bids, book.Asks
+	pe.matchSymbol(symbol)
+	pe.mu.Unlock()
+	pe.flush()
+}
+
+// HandleMessage applies market data messages, depthUpdate messages are expected from
+// partial depth streams which always carry the top levels of the book.
+func (pe *PaperExchange) HandleMessage(message []byte) error {
+	meta, err := ParseMetaInformation(message)
+	if err != nil {
+		return err
+	}
+	pe.mu.Lock()
+	switch meta.Event {
+	case models.EventBookTicker:
+		update := new(models.BookTicker)
+		if err = json.Unmarshal(message, update); err == nil {
+			book := pe.book(update.Symbol)
+			book.bids = []models.BookData{{Price: update.BestBidPrice, Quantity: update.BestBidQuantity}}
+			book.asks = []models.BookData{{Price: update.BestAskPrice, Quantity: update.BestAskQuantity}}
+			pe.matchSymbol(update.Symbol)
+		}
+	case models.EventDepth:
+		update := new(models.DepthUpdate)
+		if err = json.Unmarshal(message, update); err == nil {
+			book := pe.book(update.Symbol)
+			book.bids, book.asks = update.Bids, update.Asks
+			pe.matchSymbol(update.Symbol)
+		}
+	case models.EventMarkPrice:
+		update := new(models.MarkPriceUpdate)
+		if err = json.Unmarshal(message, update); err == nil {
+			pe.book(update.Symbol).mark = update.MarkPrice
+			pe.matchSymbol(update.Symbol)
+			pe.checkLiquidation()
+		}
+	case models.EventAggTrade:
+		update := new(models.AggTrade)
+		if err = json.Unmarshal(message, update); err == nil {
+			pe.book(update.Symbol).last = update.Price
+			pe.matchSymbol(update.Symbol)
+		}
+	}
+	pe.mu.Unlock()
+	pe.flush()
+	return err
+}
+
+func (pe *PaperExchange) Get24HourTickerPriceChangeStatistics(symbol string) ([]byte, error) {
+	if pe.MarketData != nil {
+		return pe.MarketData.Get24HourTickerPriceChangeStatistics(symbol)
+	}
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	last := pe.book(symbol).lastPrice()
+	return json.Marshal(map[string]string{"symbol": symbol, "lastPrice": formatPaperFloat(last),
+		"weightedAvgPrice": formatPaperFloat(last)})
+}
+
+func (pe *PaperExchange) GetOrderBook(symbol string, limit int) ([]byte, error) {
+	pe.mu.Lock()
+	book := pe.book(symbol)
+	if len(book.bids) == 0 && len(book.asks) == 0 && pe.MarketData != nil {
+		pe.mu.Unlock()
+		return pe.MarketData.GetOrderBook(symbol, limit)
+	}
+	levels := func(side []models.BookData) [][2]string {
+		formatted := make([][2]string, 0, len(side))
+		for i, level := range side {
+			if i == limit {
+				break
+			}
+			formatted = append(formatted, [2]string{formatPaperFloat(level.Price), formatPaperFloat(level.Quantity)})
+		}
+		return formatted
+	}
+	data, err := json.Marshal(map[string]interface{}{"bids": levels(book.bids), "asks": levels(book.asks)})
+	pe.mu.Unlock()
+	return data, err
+}
+
+func (pe *PaperExchange) GetExchangeInformation() ([]byte, error) {
+	if pe.MarketData != nil {
+		return pe.MarketData.GetExchangeInformation()
+	}
+	return []byte(`{"symbols":[]}`), nil
+}
+
+func (pe *PaperExchange) GetKlines(symbol, interval string, limit int) ([]byte, error) {
+	if pe.MarketData != nil {
+		return pe.MarketData.GetKlines(symbol, interval, limit)
+	}
+	return []byte(`[]`), nil
+}
+
+// ======================= USER STREAM ================================
+
+func (pe *PaperExchange) GetUserStreamKey() ([]byte, error) {
+	return []byte(`{"listenKey":"paper"}`), nil
+}
+
+func (pe *PaperExchange) UpdateKeepAliveUserStream() ([]byte, error) {
+	return []byte(`{}`), nil
+}
+
+func (pe *PaperExchange) DeleteUserStream() ([]byte, error) {
+	return []byte(`{}`), nil
+}
+
+// ======================= ORDERS ================================
+
+func (pe *PaperExchange) PlaceLimitOrder(symbol, side string, price, qty float64, reduceOnly bool) ([]byte, error) {
+	return pe.place(&paperOrder{symbol: symbol, side: side, orderType: OrderTypeLimit, timeInForce: GoodTillCancel,
+		price: price, quantity: qty, reduceOnly: reduceOnly})
+}
+
+func (pe *PaperExchange) PlacePostOnlyLimitOrder(symbol, side string, price, qty float64, reduceOnly bool) ([]byte, error) {
+	return pe.place(&paperOrder{symbol: symbol, side: side, orderType: OrderTypeLimit, timeInForce: GoodTillCrossing,
+		price: price, quantity: qty, reduceOnly: reduceOnly})
+}
+
+func (pe *PaperExchange) PlaceMarketOrder(symbol, side string, qty float64, reduceOnly bool) ([]byte, error) {
+	return pe.place(&paperOrder{symbol: symbol, side: side, orderType: OrderTypeMarket, quantity: qty, reduceOnly: reduceOnly})
+}
+
+// PlaceHedgeMarketOrder paper accounts are one way mode, there are no LONG or SHORT positions
+func (pe *PaperExchange) PlaceHedgeMarketOrder(symbol, side, positionSide string, qty float64) ([]byte, error) {
+	return nil, errPaperNotSupported
+}
+
+func (pe *PaperExchange) PlaceStopMarketOrder(symbol, side string, stopPrice, qty float64) ([]byte, error) {
+	return pe.place(&paperOrder{symbol: symbol, side: side, orderType: OrderTypeStopMarket, stopPrice: stopPrice,
+		quantity: qty, reduceOnly: true})
+}
+
+func (pe *PaperExchange) PlaceTakeProfitMarketOrder(symbol, side string, stopPrice, qty float64) ([]byte, error) {
+	return pe.place(&paperOrder{symbol: symbol, side: side, orderType: OrderTypeTakeProfitMarket, stopPrice: stopPrice,
+		quantity: qty, reduceOnly: true})
+}
+
+func (pe *PaperExchange) PlaceClosePositionOrder(symbol, side, orderType string, stopPrice float64) ([]byte, error) {
+	return pe.place(&paperOrder{symbol: symbol, side: side, orderType: orderType, stopPrice: stopPrice,
+		reduceOnly: true, closePosition: true})
+}
+
+func (pe *PaperExchange) place(order *paperOrder) ([]byte, error) {
+	pe.mu.Lock()
+	if order.quantity <= 0 && !order.closePosition {
+		pe.mu.Unlock()
+		return nil, paperError(ParameterValueWrong, "Quantity less than or equal to zero.")
+	}
+	if !order.reduceOnly {
+		if err := pe.checkMargin(order); err != nil {
+			pe.mu.Unlock()
+			return nil, err
+		}
+	} else if !order.closePosition && pe.reducibleQuantity(order.symbol, order.side) == 0 {
+		pe.mu.Unlock()
+		return nil, paperError(-2022, "ReduceOnly Order is rejected.")
+	}
+	if pe.wouldTrigger(order, pe.book(order.symbol).lastPrice()) {
+		pe.mu.Unlock()
+		return nil, paperError(OrderWouldTrigger, "Order would immediately trigger.")
+	}
+
+	pe.orderCount++
+	order.orderId = pe.orderCount
+	order.clientOrderId = fmt.Sprintf("paper-%d", order.orderId)
+	order.status = models.OrderStatusNew
+	order.updateTime = paperNow()
+	pe.orders[order.orderId] = order
+	pe.emitOrder(order, executionTypeNew, 0, 0, 0, false, 0)
+	pe.Logger.Info("paper order ", order.orderId, " ", order.side, " ", order.quantity, " ", order.symbol, " ", order.orderType)
+
+	book := pe.book(order.symbol)
+	switch order.orderType {
+	case OrderTypeMarket:
+		pe.executeTaker(order, math.Inf(1)*sideSign(order.side))
+	case OrderTypeLimit:
+		crosses := (order.side == SideBuy && len(book.asks) > 0 && book.asks[0].Price <= order.price) ||
+			(order.side == SideSell && len(book.bids) > 0 && book.bids[0].Price >= order.price)
+		if crosses && order.timeInForce == GoodTillCrossing {
+			pe.finishOrder(order, models.OrderStatusExpired, executionTypeExpired)
+		} else if crosses {
+			pe.executeTaker(order, order.price)
+		}
+	}
+	response := order.response()
+	pe.mu.Unlock()
+	pe.flush()
+	return json.Marshal(response)
+}
+
+// Margin held by positions and open orders is compared with wallet balance plus unrealized pnl
+func (pe *PaperExchange) checkMargin(order *paperOrder) error {
+	price := order.price
+	if price == 0 {
+		price = pe.book(order.symbol).markPrice()
+	}
+	required := order.quantity * price / pe.symbolLeverage(order.symbol)
+	if required > pe.availableBalance() {
+		return paperError(-2019, "Margin is insufficient.")
+	}
+	return nil
+}
+
+func (pe *PaperExchange) unrealizedPnl() float64 {
+	total := 0.0
+	for symbol, position := range pe.positions {
+		if position.quantity != 0 {
+			total += position.quantity * (pe.book(symbol).markPrice() - position.entryPrice)
+		}
+	}
+	return total
+}
+
+func (pe *PaperExchange) availableBalance() float64 {
+	used := 0.0
+	for symbol, position := range pe.positions {
+		used += math.Abs(position.quantity) * pe.book(symbol).markPrice() / pe.symbolLeverage(symbol)
+	}
+	for _, order := range pe.orders {
+		if order.isOpen() && !order.reduceOnly && order.orderType == OrderTypeLimit {
+			used += (order.quantity - order.executed) * order.price / pe.symbolLeverage(order.symbol)
+		}
+	}
+	return pe.balance + pe.unrealizedPnl() - used
+}
+
+// reducibleQuantity quantity an order on side can close without opening the other way
+func (pe *PaperExchange) reducibleQuantity(symbol, side string) float64 {
+	position := pe.position(symbol).quantity
+	if (side == SideSell && position > 0) || (side == SideBuy && position < 0) {
+		return math.Abs(position)
+	}
+	return 0
+}
+
+// executeTaker walks the opposite side of the book up to limit, the rest stays open for limit orders
+func (pe *PaperExchange) executeTaker(order *paperOrder, limit float64) {
+	book := pe.book(order.symbol)
+	levels := book.asks
+	if order.side == SideSell {
+		levels = book.bids
+	}
+	remaining := order.quantity - order.executed
+	if order.reduceOnly {
+		remaining = math.Min(remaining, pe.reducibleQuantity(order.symbol, order.side))
+	}
+	if len(levels) == 0 && order.orderType != OrderTypeLimit && book.markPrice() != 0 {
+		// No book yet, market orders fill at mark price
+		levels = []models.BookData{{Price: book.markPrice(), Quantity: remaining}}
+	}
+	quantity, cost := 0.0, 0.0
+	for i, level := range levels {
+		if remaining-quantity <= 0 {
+			break
+		}
+		if (order.side == SideBuy && level.Price > limit) || (order.side == SideSell && level.Price < limit) {
+			break
+		}
+		fill := math.Min(level.Quantity, remaining-quantity)
+		if i == len(levels)-1 && order.orderType != OrderTypeLimit {
+			// Book depth is limited to what was fed, the last level absorbs the rest
+			fill = remaining - quantity
+		}
+		quantity += fill
+		cost += fill * level.Price
+	}
+	if quantity > 0 {
+		pe.fill(order, quantity, cost/quantity, false)
+	}
+	if order.isOpen() && order.orderType != OrderTypeLimit {
+		pe.finishOrder(order, models.OrderStatusExpired, executionTypeExpired)
+	}
+}
+
+func (pe *PaperExchange) fill(order *paperOrder, quantity, price float64, maker bool) {
+	feeRate := pe.TakerFee
+	if maker {
+		feeRate = pe.MakerFee
+	}
+	commission := quantity * price * feeRate
+	realized := pe.applyFill(order.symbol, order.side, quantity, price)
+	pe.balance += realized - commission
+	now := paperNow()
+
+	order.executed += quantity
+	order.cumulative += quantity * price
+	order.updateTime = now
+	order.status = models.OrderStatusPartiallyFilled
+	if order.executed >= order.quantity || order.closePosition {
+		order.status = models.OrderStatusFilled
+		if order.closePosition {
+			order.quantity = order.executed
+		}
+	}
+
+	pe.tradeCount++
+	pe.trades = append(pe.trades, models.AccountTrade{
+		Id: pe.tradeCount, OrderId: order.orderId, Symbol: order.symbol, Side: order.side,
+		PositionSide: PositionSideBoth, Price: price, Quantity: quantity, QuoteQuantity: quantity * price,
+		RealizedPnl: realized, Commission: commission, CommissionAsset: paperAsset,
+		Buyer: order.side == SideBuy, Maker: maker, Time: now,
+	})
+	if realized != 0 {
+		pe.incomes = append(pe.incomes, models.Income{Symbol: order.symbol, IncomeType: IncomeTypeRealizedPnl,
+			Income: realized, Asset: paperAsset, Time: now, TransactionId: pe.tradeCount,
+			TradeId: strconv.FormatInt(pe.tradeCount, 10)})
+	}
+	pe.incomes = append(pe.incomes, models.Income{Symbol: order.symbol, IncomeType: IncomeTypeCommission,
+		Income: -commission, Asset: paperAsset, Time: now, TransactionId: pe.tradeCount,
+		TradeId: strconv.FormatInt(pe.tradeCount, 10)})
+
+	pe.emitOrder(order, executionTypeTrade, quantity, price, commission, maker, realized)
+	pe.emitAccount(order.symbol, models.ReasonOrder, 0)
+	pe.Logger.Info("paper fill ", order.orderId, " ", quantity, " at ", price)
+}
+
+// applyFill updates the position and returns realized pnl of the closed part
+func (pe *PaperExchange) applyFill(symbol, side string, quantity, price float64) float64 {
+	position := pe.position(symbol)
+	signed := quantity
+	if side == SideSell {
+		signed = -quantity
+	}
+	realized := 0.0
+	if position.quantity == 0 || (position.quantity > 0) == (signed > 0) {
+		total := position.quantity + signed
+		position.entryPrice = (position.entryPrice*math.Abs(position.quantity) + price*quantity) / math.Abs(total)
+		position.quantity = total
+		return 0
+	}
+	closed := math.Min(quantity, math.Abs(position.quantity))
+	if position.quantity > 0 {
+		realized = closed * (price - position.entryPrice)
+	} else {
+		realized = closed * (position.entryPrice - price)
+	}
+	position.realized += realized
+	position.quantity += signed
+	if math.Abs(position.quantity) < 1e-12 {
+		position.quantity, position.entryPrice = 0, 0
+	} else if (position.quantity > 0) == (signed > 0) {
+		// Flipped, the rest opened a new position at fill price
+		position.entryPrice = price
+	}
+	return realized
+}
+
+func (pe *PaperExchange) finishOrder(order *paperOrder, status, executionType string) {
+	order.status = status
+	order.updateTime = paperNow()
+	pe.emitOrder(order, executionType, 0, 0, 0, false, 0)
+}
+
+// matchSymbol fills resting limit orders and triggers stops after a market data change
+func (pe *PaperExchange) matchSymbol(symbol string) {
+	book := pe.book(symbol)
+	ids := make([]int64, 0)
+	for id, order := range pe.orders {
+		if order.symbol == symbol && order.isOpen() {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		order := pe.orders[id]
+		if !order.isOpen() {
+			continue
+		}
+		if order.orderType != OrderTypeLimit {
+			pe.checkTrigger(order, book)
+			continue
+		}
+		crossed := (order.side == SideBuy && len(book.asks) > 0 && book.asks[0].Price <= order.price) ||
+			(order.side == SideSell && len(book.bids) > 0 && book.bids[0].Price >= order.price)
+		if !crossed {
+			continue
+		}
+		quantity := order.quantity - order.executed
+		if order.reduceOnly {
+			quantity = math.Min(quantity, pe.reducibleQuantity(symbol, order.side))
+		}
+		if quantity <= 0 {
+			pe.finishOrder(order, models.OrderStatusExpired, executionTypeExpired)
+			continue
+		}
+		pe.fill(order, quantity, order.price, true)
+	}
+}
+
+func (pe *PaperExchange) checkTrigger(order *paperOrder, book *paperBook) {
+	if !pe.wouldTrigger(order, book.lastPrice()) {
+		return
+	}
+	if order.closePosition {
+		order.quantity = pe.reducibleQuantity(order.symbol, order.side)
+		if order.quantity == 0 {
+			pe.finishOrder(order, models.OrderStatusExpired, executionTypeExpired)
+			return
+		}
+	}
+	pe.executeTaker(order, math.Inf(1)*sideSign(order.side))
+}
+
+// wouldTrigger stop price of STOP_MARKET and TAKE_PROFIT_MARKET orders is crossed by the last price
+func (pe *PaperExchange) wouldTrigger(order *paperOrder, last float64) bool {
+	if last == 0 {
+		return false
+	}
+	switch order.orderType {
+	case OrderTypeStopMarket:
+		return (order.side == SideBuy && last >= order.stopPrice) || (order.side == SideSell && last <= order.stopPrice)
+	case OrderTypeTakeProfitMarket:
+		return (order.side == SideBuy && last <= order.stopPrice) || (order.side == SideSell && last >= order.stopPrice)
+	}
+	return false
+}
+
+func sideSign(side string) float64 {
+	if side == SideSell {
+		return -1
+	}
+	return 1
+}
+
+// checkLiquidation closes every position at mark price once margin balance drops under maintenance margin
+func (pe *PaperExchange) checkLiquidation() {
+	maintenance := 0.0
+	for symbol, position := range pe.positions {
+		maintenance += math.Abs(position.quantity) * pe.book(symbol).markPrice() * pe.MaintenanceMarginRate
+	}
+	if maintenance == 0 || pe.balance+pe.unrealizedPnl() >= maintenance {
+		return
+	}
+	pe.Logger.Warn("paper account liquidated, margin balance ", pe.balance+pe.unrealizedPnl())
+	for _, order := range pe.orders {
+		if order.isOpen() {
+			pe.finishOrder(order, models.OrderStatusCanceled, executionTypeCanceled)
+		}
+	}
+	for symbol, position := range pe.positions {
+		if position.quantity == 0 {
+			continue
+		}
+		side := SideSell
+		if position.quantity < 0 {
+			side = SideBuy
+		}
+		pe.orderCount++
+		order := &paperOrder{orderId: pe.orderCount, symbol: symbol, side: side, orderType: OrderTypeMarket,
+			quantity: math.Abs(position.quantity), reduceOnly: true, status: models.OrderStatusNew,
+			clientOrderId: fmt.Sprintf("%s%d", liquidationClientIdStart, pe.orderCount)}
+		pe.orders[order.orderId] = order
+		pe.fill(order, order.quantity, pe.book(symbol).markPrice(), false)
+	}
+	if pe.balance < 0 {
+		pe.balance = 0
+	}
+}
+
+func (pe *PaperExchange) QueryOrder(symbol, origClientOrderId string, orderId int64) ([]byte, error) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	order := pe.findOrder(origClientOrderId, orderId)
+	if order == nil {
+		return nil, paperError(-2013, "Order does not exist.")
+	}
+	return json.Marshal(order.response())
+}
+
+func (pe *PaperExchange) findOrder(clientOrderId string, orderId int64) *paperOrder {
+	if order, exists := pe.orders[orderId]; exists {
+		return order
+	}
+	for _, order := range pe.orders {
+		if clientOrderId != "" && order.clientOrderId == clientOrderId {
+			return order
+		}
+	}
+	return nil
+}
+
+func (pe *PaperExchange) CancelSingleOrder(symbol, origClientOrderId string, orderId int64) ([]byte, error) {
+	pe.mu.Lock()
+	order := pe.findOrder(origClientOrderId, orderId)
+	if order == nil || !order.isOpen() {
+		pe.mu.Unlock()
+		return nil, paperError(-2011, "Unknown order sent.")
+	}
+	pe.finishOrder(order, models.OrderStatusCanceled, executionTypeCanceled)
+	response := order.response()
+	pe.mu.Unlock()
+	pe.flush()
+	return json.Marshal(response)
+}
+
+func (pe *PaperExchange) CancelAllOrders(symbol string) ([]byte, error) {
+	pe.mu.Lock()
+	for _, order := range pe.orders {
+		if order.symbol == symbol && order.isOpen() {
+			pe.finishOrder(order, models.OrderStatusCanceled, executionTypeCanceled)
+		}
+	}
+	pe.mu.Unlock()
+	pe.flush()
+	return []byte(`{"code":200,"msg":"The operation of cancel all open order is done."}`), nil
+}
+
+func (pe *PaperExchange) GetOpenOrders(symbol string) ([]byte, error) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	open := make([]models.OrderResponse, 0)
+	for _, order := range pe.orders {
+		if order.isOpen() && (symbol == "" || order.symbol == symbol) {
+			open = append(open, order.response())
+		}
+	}
+	return json.Marshal(open)
+}
+
+// SetAutoCancelCountdown accepted and ignored, paper orders do not outlive the process anyway.
+func (pe *PaperExchange) SetAutoCancelCountdown(symbol string, countdownTime int64) ([]byte, error) {
+	return json.Marshal(map[string]string{"symbol": symbol, "countdownTime": strconv.FormatInt(countdownTime, 10)})
+}
+
+// ======================= ACCOUNT ================================
+
+func (pe *PaperExchange) GetAccountBalance() ([]byte, error) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	return json.Marshal([]map[string]string{{
+		"asset":            paperAsset,
+		"balance":          formatPaperFloat(pe.balance),
+		"availableBalance": formatPaperFloat(pe.availableBalance()),
+	}})
+}
+
+func (pe *PaperExchange) GetAccountInformation() ([]byte, error) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	unrealized := pe.unrealizedPnl()
+	positions := make([]map[string]interface{}, 0)
+	for symbol, position := range pe.positions {
+		positions = append(positions, map[string]interface{}{
+			"symbol":           symbol,
+			"positionAmt":      formatPaperFloat(position.quantity),
+			"entryPrice":       formatPaperFloat(position.entryPrice),
+			"unrealizedProfit": formatPaperFloat(position.quantity * (pe.book(symbol).markPrice() - position.entryPrice)),
+			"leverage":         formatPaperFloat(pe.symbolLeverage(symbol)),
+			"isolated":         false,
+			"positionSide":     PositionSideBoth,
+		})
+	}
+	return json.Marshal(map[string]interface{}{
+		"assets": []map[string]string{{
+			"asset":              paperAsset,
+			"walletBalance":      formatPaperFloat(pe.balance),
+			"unrealizedProfit":   formatPaperFloat(unrealized),
+			"marginBalance":      formatPaperFloat(pe.balance + unrealized),
+			"availableBalance":   formatPaperFloat(pe.availableBalance()),
+			"crossWalletBalance": formatPaperFloat(pe.balance),
+		}},
+		"positions": positions,
+	})
+}
+
+func (pe *PaperExchange) GetPositionInformation(symbol string) ([]byte, error) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	risks := make([]map[string]interface{}, 0)
+	for positionSymbol, position := range pe.positions {
+		if symbol != "" && positionSymbol != symbol {
+			continue
+		}
+		mark := pe.book(positionSymbol).markPrice()
+		risks = append(risks, map[string]interface{}{
+			"symbol":           positionSymbol,
+			"positionAmt":      formatPaperFloat(position.quantity),
+			"entryPrice":       formatPaperFloat(position.entryPrice),
+			"breakEvenPrice":   formatPaperFloat(position.entryPrice),
+			"markPrice":        formatPaperFloat(mark),
+			"unRealizedProfit": formatPaperFloat(position.quantity * (mark - position.entryPrice)),
+			"liquidationPrice": "0",
+			"leverage":         formatPaperFloat(pe.symbolLeverage(positionSymbol)),
+			"marginType":       "cross",
+			"isolatedMargin":   "0",
+			"positionSide":     PositionSideBoth,
+			"updateTime":       paperNow(),
+		})
+	}
+	return json.Marshal(risks)
+}
+
+// GetTradeList only symbol and limit are applied.
+func (pe *PaperExchange) GetTradeList(symbol, startTime, endTime, limit string) ([]byte, error) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	trades := make([]models.AccountTrade, 0)
+	for _, trade := range pe.trades {
+		if trade.Symbol == symbol {
+			trades = append(trades, trade)
+		}
+	}
+	if max, err := strconv.Atoi(limit); err == nil && max > 0 && len(trades) > max {
+		trades = trades[len(trades)-max:]
+	}
+	return marshalAccountTrades(trades)
+}
+
+func marshalAccountTrades(trades []models.AccountTrade) ([]byte, error) {
+	formatted := make([]map[string]interface{}, 0, len(trades))
+	for _, trade := range trades {
+		formatted = append(formatted, map[string]interface{}{
+			"id": trade.Id, "orderId": trade.OrderId, "symbol": trade.Symbol, "side": trade.Side,
+			"positionSide": trade.PositionSide, "price": formatPaperFloat(trade.Price),
+			"qty": formatPaperFloat(trade.Quantity), "quoteQty": formatPaperFloat(trade.QuoteQuantity),
+			"realizedPnl": formatPaperFloat(trade.RealizedPnl), "commission": formatPaperFloat(trade.Commission),
+			"commissionAsset": trade.CommissionAsset, "buyer": trade.Buyer, "maker": trade.Maker, "time": trade.Time,
+		})
+	}
+	return json.Marshal(formatted)
+}
+
+func (pe *PaperExchange) GetIncomeHistory(symbol, incomeType string, startTime, endTime int64, limit int) ([]byte, error) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	if limit <= 0 {
+		limit = DefaultIncomeLimit
+	}
+	formatted := make([]map[string]interface{}, 0)
+	for _, income := range pe.incomes {
+		if (symbol != "" && income.Symbol != symbol) || (incomeType != "" && income.IncomeType != incomeType) ||
+			(startTime > 0 && income.Time < startTime) || (endTime > 0 && income.Time > endTime) {
+			continue
+		}
+		formatted = append(formatted, map[string]interface{}{
+			"symbol": income.Symbol, "incomeType": income.IncomeType, "income": formatPaperFloat(income.Income),
+			"asset": income.Asset, "info": income.Info, "time": income.Time, "tranId": income.TransactionId,
+			"tradeId": income.TradeId,
+		})
+		if len(formatted) == limit {
+			break
+		}
+	}
+	return json.Marshal(formatted)
+}
+
+func (pe *PaperExchange) GetCommissionRate(symbol string) ([]byte, error) {
+	return json.Marshal(map[string]string{"symbol": symbol,
+		"makerCommissionRate": formatPaperFloat(pe.MakerFee), "takerCommissionRate": formatPaperFloat(pe.TakerFee)})
+}
+
+func (pe *PaperExchange) GetAdlQuantile(symbol string) ([]byte, error) {
+	return []byte(`[]`), nil
+}
+
+func (pe *PaperExchange) GetIncomeDownloadId(startTime, endTime int64) ([]byte, error) {
+	return nil, errPaperNotSupported
+}
+
+func (pe *PaperExchange) GetIncomeDownloadLink(downloadId string) ([]byte, error) {
+	return nil, errPaperNotSupported
+}
+
+func (pe *PaperExchange) GetOrderDownloadId(startTime, endTime int64) ([]byte, error) {
+	return nil, errPaperNotSupported
+}
+
+func (pe *PaperExchange) GetOrderDownloadLink(downloadId string) ([]byte, error) {
+	return nil, errPaperNotSupported
+}
+
+func (pe *PaperExchange) GetTradeDownloadId(startTime, endTime int64) ([]byte, error) {
+	return nil, errPaperNotSupported
+}
+
+func (pe *PaperExchange) GetTradeDownloadLink(downloadId string) ([]byte, error) {
+	return nil, errPaperNotSupported
+}
+
+// ======================= EVENTS ================================
+
+func (pe *PaperExchange) emitOrder(order *paperOrder, executionType string, lastQuantity, lastPrice,
+	commission float64, maker bool, realized float64) {
+	now := paperNow()
+	tradeId := int64(0)
+	if executionType == executionTypeTrade {
+		tradeId = pe.tradeCount
+	}
+	event := map[string]interface{}{
+		"e": models.EventOrder,
+		"E": now,
+		"T": now,
+		"o": map[string]interface{}{
+			"s": order.symbol, "c": order.clientOrderId, "S": order.side, "o": order.orderType,
+			"f": order.timeInForce, "q": formatPaperFloat(order.quantity), "p": formatPaperFloat(order.price),
+			"ap": formatPaperFloat(order.averagePrice()), "sp": formatPaperFloat(order.stopPrice),
+			"x": executionType, "X": order.status, "i": order.orderId,
+			"l": formatPaperFloat(lastQuantity), "z": formatPaperFloat(order.executed), "L": formatPaperFloat(lastPrice),
+			"n": formatPaperFloat(commission), "N": paperAsset, "T": order.updateTime, "t": tradeId,
+			"m": maker, "R": order.reduceOnly, "wt": "CONTRACT_PRICE", "ot": order.orderType,
+			"ps": PositionSideBoth, "cp": order.closePosition, "rp": formatPaperFloat(realized),
+		},
+	}
+	pe.queue(event)
+}
+
+func (pe *PaperExchange) emitAccount(symbol, reason string, balanceChange float64) {
+	now := paperNow()
+	position := pe.position(symbol)
+	event := map[string]interface{}{
+		"e": models.EventAccount,
+		"E": now,
+		"T": now,
+		"a": map[string]interface{}{
+			"m": reason,
+			"B": []map[string]string{{"a": paperAsset, "wb": formatPaperFloat(pe.balance),
+				"cw": formatPaperFloat(pe.balance), "bc": formatPaperFloat(balanceChange)}},
+			"P": []map[string]string{{"s": symbol, "pa": formatPaperFloat(position.quantity),
+				"ep": formatPaperFloat(position.entryPrice), "bep": formatPaperFloat(position.entryPrice),
+				"cr": formatPaperFloat(position.realized),
+				"up": formatPaperFloat(position.quantity * (pe.book(symbol).markPrice() - position.entryPrice)),
+				"mt": "cross", "iw": "0", "ps": PositionSideBoth}},
+		},
+	}
+	pe.queue(event)
+}
+
+func (pe *PaperExchange) queue(event map[string]interface{}) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		pe.Logger.Error("paper event could not be encoded ", err)
+		return
+	}
+	pe.pending = append(pe.pending, data)
+}
@@ -0,0 +1,223 @@
+package go_binance
+
+import (
+	"errors"
+	"github.com/gorilla/websocket"
+	"github.com/redlon23/go-binance/models"
+	"sync"
+	"time"
+)
+
+var errPaperSocketClosed = errors.New("paper websocket is closed")
+
+// PaperWebSocket user stream of a PaperExchange, also usable as market data socket.
+// Messages read from Source (a live socket or a replayer) are applied to the exchange first and
+// then returned by ReadFromConnection together with the simulated ORDER_TRADE_UPDATE and
+// ACCOUNT_UPDATE messages. Without Source only the simulated user stream is returned.
+type PaperWebSocket struct {
+	BinanceFutureSocket
+	Exchange *PaperExchange
+
+	mu       sync.Mutex
+	ready    *sync.Cond
+	messages [][]byte
+	err      error
+	started  bool
+	closed   bool
+}
+
+func NewPaperWebSocket(exchange *PaperExchange, source BinanceFutureSocket) *PaperWebSocket {
+	pws := &PaperWebSocket{BinanceFutureSocket: source, Exchange: exchange}
+	pws.ready = sync.NewCond(&pws.mu)
+	exchange.Subscribe(pws.push)
+	return pws
+}
+
+func (pws *PaperWebSocket) UseMainNet() {
+	if pws.BinanceFutureSocket != nil {
+		pws.BinanceFutureSocket.UseMainNet()
+	}
+}
+
+func (pws *PaperWebSocket) UseTestNet() {
+	if pws.BinanceFutureSocket != nil {
+		pws.BinanceFutureSocket.UseTestNet()
+	}
+}
+
+func (pws *PaperWebSocket) IncrementSubscribeIdCounter() {
+	if pws.BinanceFutureSocket != nil {
+		pws.BinanceFutureSocket.IncrementSubscribeIdCounter()
+	}
+}
+
+func (pws *PaperWebSocket) PrepareLoggers() {
+	if pws.BinanceFutureSocket != nil {
+		pws.BinanceFutureSocket.PrepareLoggers()
+	}
+}
+
+func (pws *PaperWebSocket) OpenWebSocketConnection() error {
+	pws.mu.Lock()
+	defer pws.mu.Unlock()
+	if pws.started {
+		return nil
+	}
+	if pws.BinanceFutureSocket != nil {
+		if err := pws.BinanceFutureSocket.OpenWebSocketConnection(); err != nil {
+			return err
+		}
+		go pws.pump()
+	}
+	pws.started, pws.closed, pws.err = true, false, nil
+	return nil
+}
+
+// OpenCombinedStreamConnection Source is dialed on the combined endpoint, market data arrives in
+// envelopes while simulated user stream messages do not, UnwrapCombinedMessage handles both.
+func (pws *PaperWebSocket) OpenCombinedStreamConnection(streams []string) error {
+	pws.mu.Lock()
+	defer pws.mu.Unlock()
+	if pws.started {
+		return nil
+	}
+	if pws.BinanceFutureSocket != nil {
+		if err := pws.BinanceFutureSocket.OpenCombinedStreamConnection(streams); err != nil {
+			return err
+		}
+		go pws.pump()
+	}
+	pws.started, pws.closed, pws.err = true, false, nil
+	return nil
+}
+
+// OpenWebSocketConnectionWithUserStream the listen key is ignored, the user stream is simulated
+// and Source is opened as a plain market data connection.
+func (pws *PaperWebSocket) OpenWebSocketConnectionWithUserStream(listenKey string) error {
+	return pws.OpenWebSocketConnection()
+}
+
+func (pws *PaperWebSocket) SubscribeToStream(symbol, streamType string) error {
+	if pws.BinanceFutureSocket == nil {
+		return nil
+	}
+	return pws.BinanceFutureSocket.SubscribeToStream(symbol, streamType)
+}
+
+func (pws *PaperWebSocket) SubscribeToStreamAndWait(symbol, streamType string, timeout time.Duration) error {
+	if pws.BinanceFutureSocket == nil {
+		return nil
+	}
+	return pws.BinanceFutureSocket.SubscribeToStreamAndWait(symbol, streamType, timeout)
+}
+
+func (pws *PaperWebSocket) UnsubscribeFromStream(symbol, streamType string) error {
+	if pws.BinanceFutureSocket == nil {
+		return nil
+	}
+	return pws.BinanceFutureSocket.UnsubscribeFromStream(symbol, streamType)
+}
+
+func (pws *PaperWebSocket) ListSubscriptions(timeout time.Duration) ([]string, error) {
+	if pws.BinanceFutureSocket == nil {
+		return []string{}, nil
+	}
+	return pws.BinanceFutureSocket.ListSubscriptions(timeout)
+}
+
+func (pws *PaperWebSocket) SetCombinedProperty(combined bool, timeout time.Duration) error {
+	if pws.BinanceFutureSocket == nil {
+		return errPaperNotSupported
+	}
+	return pws.BinanceFutureSocket.SetCombinedProperty(combined, timeout)
+}
+
+func (pws *PaperWebSocket) GetCombinedProperty(timeout time.Duration) (bool, error) {
+	if pws.BinanceFutureSocket == nil {
+		return false, errPaperNotSupported
+	}
+	return pws.BinanceFutureSocket.GetCombinedProperty(timeout)
+}
+
+func (pws *PaperWebSocket) SendStreamRequest(method string, params []interface{}, timeout time.Duration) (*models.StreamResponse, error) {
+	if pws.BinanceFutureSocket == nil {
+		return nil, errPaperNotSupported
+	}
+	return pws.BinanceFutureSocket.SendStreamRequest(method, params, timeout)
+}
+
+func (pws *PaperWebSocket) SubscribeLiquidationStream(symbol string) error {
+	return pws.SubscribeToStream(symbol, liquidationStreamName)
+}
+
+func (pws *PaperWebSocket) SubscribeBookTickerStream(symbol string) error {
+	return pws.SubscribeToStream(symbol, bookTickerSteamName)
+}
+
+func (pws *PaperWebSocket) SubscribeSymbolTickerStream(symbol string) error {
+	return pws.SubscribeToStream(symbol, symbolTickerName)
+}
+
+func (pws *PaperWebSocket) pump() {
+	for {
+		_, message, err := pws.BinanceFutureSocket.ReadFromConnection()
+		if err != nil {
+			pws.mu.Lock()
+			pws.err = err
+			pws.ready.Broadcast()
+			pws.mu.Unlock()
+			return
+		}
+		// Subscription results are not events, they are only forwarded
+		_, data, err := UnwrapCombinedMessage(message)
+		if err != nil {
+			pws.Exchange.Logger.Error("paper exchange could not unwrap market data ", err)
+		} else if meta, err := ParseMetaInformation(data); err == nil && meta.Event != "" {
+			if err := pws.Exchange.HandleMessage(data); err != nil {
+				pws.Exchange.Logger.Error("paper exchange could not apply market data ", err)
+			}
+		}
+		pws.push(message)
+	}
+}
+
+func (pws *PaperWebSocket) push(message []byte) {
+	pws.mu.Lock()
+	defer pws.mu.Unlock()
+	if pws.closed {
+		return
+	}
+	pws.messages = append(pws.messages, message)
+	pws.ready.Signal()
+}
+
+// ReadFromConnection blocks until a message is queued, queued messages are returned before a Source error.
+func (pws *PaperWebSocket) ReadFromConnection() (messageType int, p []byte, err error) {
+	pws.mu.Lock()
+	defer pws.mu.Unlock()
+	for len(pws.messages) == 0 && pws.err == nil && !pws.closed {
+		pws.ready.Wait()
+	}
+	if len(pws.messages) > 0 {
+		message := pws.messages[0]
+		pws.messages[0] = nil
+		pws.messages = pws.messages[1:]
+		return websocket.TextMessage, message, nil
+	}
+	if pws.closed {
+		return -1, nil, errPaperSocketClosed
+	}
+	return -1, nil, pws.err
+}
+
+func (pws *PaperWebSocket) CloseConnection() error {
+	pws.mu.Lock()
+	pws.closed, pws.started = true, false
+	pws.messages = nil
+	pws.ready.Broadcast()
+	pws.mu.Unlock()
+	if pws.BinanceFutureSocket != nil {
+		return pws.BinanceFutureSocket.CloseConnection()
+	}
+	return nil
+}
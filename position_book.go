@@ -0,0 +1,279 @@
+package go_binance
+
+import (
+	"encoding/json"
+	"github.com/redlon23/go-binance/models"
+	"sync"
+)
+
+const (
+	PositionSideBoth  = "BOTH"
+	PositionSideLong  = "LONG"
+	PositionSideShort = "SHORT"
+)
+
+type BookBalance struct {
+	Asset              string
+	WalletBalance      float64
+	CrossWalletBalance float64
+	// Balance change and reason of the latest applied account update
+	LastChange float64
+	LastReason string
+	UpdateTime int64
+}
+
+type BookPosition struct {
+	Symbol              string
+	PositionSide        string
+	Quantity            float64
+	EntryPrice          float64
+	BreakEvenPrice      float64
+	AccumulatedRealized float64
+	MarginType          string
+	IsolatedWallet      float64
+	MarkPrice           float64
+	UnrealizedPnl       float64
+	UpdateTime          int64
+}
+
+// PositionBook keeps balances and positions from ACCOUNT_UPDATE events and
+// recalculates unrealized pnl on every mark price update.
+// Call Seed once at startup, before messages are handled.
+type PositionBook struct {
+	mu        sync.RWMutex
+	balances  map[string]*BookBalance
+	positions map[string]*BookPosition
+	marks     map[string]float64
+	// Coin-M contracts are inverse, pnl is settled in the base asset
+	inverse       bool
+	contractSizes map[string]float64
+}
+
+func NewPositionBook() *PositionBook {
+	return &PositionBook{
+		balances:      make(map[string]*BookBalance),
+		positions:     make(map[string]*BookPosition),
+		marks:         make(map[string]float64),
+		contractSizes: make(map[string]float64),
+	}
+}
+
+// NewCoinPositionBook positions are in contracts, use SetContractSize for every traded symbol.
+func NewCoinPositionBook() *PositionBook {
+	pb := NewPositionBook()
+	pb.inverse = true
+	return pb
+}
+
+// SetContractSize contract value in quote currency, 100 for BTCUSD perpetual and 10 for the rest.
+func (pb *PositionBook) SetContractSize(symbol string, size float64) {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	pb.contractSizes[symbol] = size
+}
+
+func positionKey(symbol, positionSide string) string {
+	if positionSide == "" {
+		positionSide = PositionSideBoth
+	}
+	return symbol + "_" + positionSide
+}
+
+// Seed loads balances and positions over REST.
+func (pb *PositionBook) Seed(api BinanceFutures) error {
+	data, err := api.GetAccountInformation()
+	if err != nil {
+		return err
+	}
+	account := new(models.AccountInformation)
+	if err := json.Unmarshal(data, account); err != nil {
+		return err
+	}
+	data, err = api.GetPositionInformation("")
+	if err != nil {
+		return err
+	}
+	risks := make(models.PositionRisks, 0)
+	if err := json.Unmarshal(data, &risks); err != nil {
+		return err
+	}
+
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	for _, asset := range account.Assets {
+		pb.balances[asset.Asset] = &BookBalance{
+			Asset:              asset.Asset,
+			WalletBalance:      asset.WalletBalance,
+			CrossWalletBalance: asset.CrossWalletBalance,
+		}
+	}
+	for _, risk := range risks {
+		position := &BookPosition{
+			Symbol:         risk.Symbol,
+			PositionSide:   risk.PositionSide,
+			Quantity:       risk.PositionAmount,
+			EntryPrice:     risk.EntryPrice,
+			BreakEvenPrice: risk.BreakEvenPrice,
+			MarginType:     risk.MarginType,
+			IsolatedWallet: risk.IsolatedMargin,
+			UpdateTime:     risk.UpdateTime,
+		}
+		pb.marks[risk.Symbol] = risk.MarkPrice
+		pb.updatePnl(position)
+		pb.positions[positionKey(risk.Symbol, risk.PositionSide)] = position
+	}
+	return nil
+}
+
+// HandleMessage accepts raw stream messages, ACCOUNT_UPDATE and markPriceUpdate are applied.
+func (pb *PositionBook) HandleMessage(message []byte) error {
+	meta, err := ParseMetaInformation(message)
+	if err != nil {
+		return err
+	}
+	switch meta.Event {
+	case models.EventAccount:
+		update := new(models.StreamAccountUpdate)
+		if err := json.Unmarshal(message, update); err != nil {
+			return err
+		}
+		pb.HandleAccountUpdate(*update)
+	case models.EventMarkPrice:
+		update := new(models.MarkPriceUpdate)
+		if err := json.Unmarshal(message, update); err != nil {
+			return err
+		}
+		pb.HandleMarkPrice(*update)
+	}
+	return nil
+}
+
+// HandleAccountUpdate applies updates of every reason, only changed balances and positions are sent.
+// Updates older than the stored state are skipped.
+func (pb *PositionBook) HandleAccountUpdate(update models.StreamAccountUpdate) {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	updateTime := update.TransactionTime
+	for _, streamBalance := range update.UpdateData.Balances {
+		balance, exists := pb.balances[streamBalance.Asset]
+		if !exists {
+			balance = &BookBalance{Asset: streamBalance.Asset}
+			pb.balances[streamBalance.Asset] = balance
+		} else if balance.UpdateTime > updateTime {
+			continue
+		}
+		balance.WalletBalance = streamBalance.WalletBalance
+		balance.CrossWalletBalance = streamBalance.CrossWalletBalance
+		balance.LastChange = streamBalance.BalanceChange
+		balance.LastReason = update.UpdateData.Reason
+		balance.UpdateTime = updateTime
+	}
+	for _, streamPosition := range update.UpdateData.Positions {
+		key := positionKey(streamPosition.Symbol, streamPosition.PositionSide)
+		position, exists := pb.positions[key]
+		if !exists {
+			position = &BookPosition{Symbol: streamPosition.Symbol, PositionSide: streamPosition.PositionSide}
+			pb.positions[key] = position
+		} else if position.UpdateTime > updateTime {
+			continue
+		}
+		position.Quantity = streamPosition.Quantity
+		position.EntryPrice = streamPosition.EntryPrice
+		position.BreakEvenPrice = streamPosition.BreakEvenPrice
+		position.AccumulatedRealized = streamPosition.AccumulatedRealized
+		position.MarginType = streamPosition.MarginType
+		position.IsolatedWallet = streamPosition.IsolatedWallet
+		position.UnrealizedPnl = streamPosition.UnrealizedPnl
+		position.UpdateTime = updateTime
+		pb.updatePnl(position)
+	}
+}
+
+func (pb *PositionBook) HandleMarkPrice(update models.MarkPriceUpdate) {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	pb.marks[update.Symbol] = update.MarkPrice
+	for _, side := range []string{PositionSideBoth, PositionSideLong, PositionSideShort} {
+		if position, exists := pb.positions[positionKey(update.Symbol, side)]; exists {
+			pb.updatePnl(position)
+		}
+	}
+}
+
+// Keeps the exchange value when there is no mark price yet
+func (pb *PositionBook) updatePnl(position *BookPosition) {
+	mark := pb.marks[position.Symbol]
+	if mark == 0 {
+		return
+	}
+	position.MarkPrice = mark
+	if position.Quantity == 0 || position.EntryPrice == 0 {
+		position.UnrealizedPnl = 0
+		return
+	}
+	if pb.inverse {
+		size, exists := pb.contractSizes[position.Symbol]
+		if !exists {
+			return
+		}
+		position.UnrealizedPnl = position.Quantity * size * (1/position.EntryPrice - 1/mark)
+		return
+	}
+	position.UnrealizedPnl = position.Quantity * (mark - position.EntryPrice)
+}
+
+func (pb *PositionBook) Balance(asset string) (BookBalance, bool) {
+	pb.mu.RLock()
+	defer pb.mu.RUnlock()
+	balance, exists := pb.balances[asset]
+	if !exists {
+		return BookBalance{}, false
+	}
+	return *balance, true
+}
+
+func (pb *PositionBook) Balances() []BookBalance {
+	pb.mu.RLock()
+	defer pb.mu.RUnlock()
+	balances := make([]BookBalance, 0, len(pb.balances))
+	for _, balance := range pb.balances {
+		balances = append(balances, *balance)
+	}
+	return balances
+}
+
+// Position positionSide is BOTH in one-way mode, LONG or SHORT in hedge mode.
+func (pb *PositionBook) Position(symbol, positionSide string) (BookPosition, bool) {
+	pb.mu.RLock()
+	defer pb.mu.RUnlock()
+	position, exists := pb.positions[positionKey(symbol, positionSide)]
+	if !exists {
+		return BookPosition{}, false
+	}
+	return *position, true
+}
+
+// OpenPositions returns positions with non zero quantity.
+func (pb *PositionBook) OpenPositions() []BookPosition {
+	pb.mu.RLock()
+	defer pb.mu.RUnlock()
+	positions := make([]BookPosition, 0)
+	for _, position := range pb.positions {
+		if position.Quantity != 0 {
+			positions = append(positions, *position)
+		}
+	}
+	return positions
+}
+
+// TotalUnrealizedPnl sum over every position, for Coin-M positions settled in different
+// assets are summed as well, use OpenPositions to split them.
+func (pb *PositionBook) TotalUnrealizedPnl() float64 {
+	pb.mu.RLock()
+	defer pb.mu.RUnlock()
+	total := 0.0
+	for _, position := range pb.positions {
+		total += position.UnrealizedPnl
+	}
+	return total
+}
@@ -0,0 +1,370 @@
+package go_binance
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/redlon23/go-binance/models"
+	"github.com/sirupsen/logrus"
+	"math"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	RiskRuleSymbol         = "SYMBOL_ALLOWLIST"
+	RiskRuleNotional       = "MAX_ORDER_NOTIONAL"
+	RiskRulePosition       = "MAX_POSITION"
+	RiskRuleOpenOrders     = "MAX_OPEN_ORDERS"
+	RiskRulePriceBand      = "PRICE_BAND"
+	RiskRuleOrderRate      = "MAX_ORDERS_PER_SECOND"
+	RiskRuleReferencePrice = "REFERENCE_PRICE"
+)
+
+// RiskLimits zero value of a limit disables it.
+// PriceBandPercent 5 only lets limit orders within ±5% of the reference price through.
+// MaxPosition is the absolute position quantity per symbol after the order fills.
+// For Coin-M set ContractSizes, notional is then counted as contracts times contract size.
+type RiskLimits struct {
+	MaxOrderNotional   float64
+	MaxPosition        map[string]float64
+	MaxOpenOrders      int
+	PriceBandPercent   float64
+	MaxOrdersPerSecond int
+	AllowedSymbols     []string
+	ContractSizes      map[string]float64
+}
+
+// RiskGuard wraps a BinanceFutures client and checks every order call against RiskLimits.
+// It is a BinanceFutures itself, so it can be used in place of the wrapped client.
+// Positions and Orders are optional, position and open order limits need them.
+// Reference prices come from mark price and ticker messages passed to HandleMessage,
+// orders without a known reference price are blocked.
+// Reduce only orders are held to the allowlist and the price band only, they are not counted for the order rate.
+type RiskGuard struct {
+	BinanceFutures
+	Limits    RiskLimits
+	Positions *PositionBook
+	Orders    *OrderManager
+	Logger    *logrus.Logger
+
+	mu         sync.Mutex
+	prices     map[string]float64
+	orderTimes []time.Time
+}
+
+func NewRiskGuard(api BinanceFutures, limits RiskLimits) *RiskGuard {
+	return &RiskGuard{
+		BinanceFutures: api,
+		Limits:         limits,
+		Logger:         logrus.New(),
+		prices:         make(map[string]float64),
+	}
+}
+
+// PrepareLoggers prepares loggers of the wrapped client as well.
+func (rg *RiskGuard) PrepareLoggers() {
+	rg.BinanceFutures.PrepareLoggers()
+	rg.Logger = logrus.New()
+	rg.Logger.Formatter = new(logrus.JSONFormatter)
+
+	riskLogs, err := os.OpenFile("logs/binance_risk.log", os.O_CREATE|os.O_WRONLY, 0666)
+	if err == nil {
+		rg.Logger.SetOutput(riskLogs)
+	} else {
+		fmt.Println("Failed to log to file for risk checks, using default stderr")
+	}
+}
+
+func (rg *RiskGuard) SetReferencePrice(symbol string, price float64) {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+	rg.prices[symbol] = price
+}
+
+// HandleMessage takes reference prices from markPriceUpdate, bookTicker and 24hrTicker messages.
+func (rg *RiskGuard) HandleMessage(message []byte) error {
+	meta, err := ParseMetaInformation(message)
+	if err != nil {
+		return err
+	}
+	switch meta.Event {
+	case models.EventMarkPrice:
+		update := new(models.MarkPriceUpdate)
+		if err := json.Unmarshal(message, update); err != nil {
+			return err
+		}
+		rg.SetReferencePrice(update.Symbol, update.MarkPrice)
+	case models.EventBookTicker:
+		update := new(models.BookTicker)
+		if err := json.Unmarshal(message, update); err != nil {
+			return err
+		}
+		rg.SetReferencePrice(update.Symbol, (update.BestBidPrice+update.BestAskPrice)/2)
+	case models.EventSymbolTicker:
+		update := new(models.StreamSymbolTickerUpdate)
+		if err := json.Unmarshal(message, update); err != nil {
+			return err
+		}
+		rg.SetReferencePrice(update.Symbol, update.Price)
+	}
+	return nil
+}
+
+// Check runs every rule without sending anything, price 0 means a market order.
+func (rg *RiskGuard) Check(symbol, side string, quantity, price float64, reduceOnly bool) error {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+	err := rg.check(symbol, side, quantity, price, reduceOnly)
+	if err != nil {
+		rg.Logger.Warn("blocked ", side, " ", quantity, " ", symbol, " at ", price, " reduceOnly ", reduceOnly, ": ", err)
+		return err
+	}
+	if !reduceOnly {
+		rg.orderTimes = append(rg.orderTimes, time.Now())
+	}
+	rg.Logger.Info("allowed ", side, " ", quantity, " ", symbol, " at ", price, " reduceOnly ", reduceOnly)
+	return nil
+}
+
+// CheckModify runs the rules for changing an open order to price and quantity. Modifies are not
+// counted for the order rate and open orders, MaxPosition only holds the increase of the order size.
+// The order is looked up in Orders, an unknown order counts with its whole new quantity.
+func (rg *RiskGuard) CheckModify(symbol, side, origClientOrderId string, orderId int64, price, quantity float64) error {
+	previous, known := ManagedOrder{}, false
+	if rg.Orders != nil {
+		previous, known = rg.Orders.GetOrder(origClientOrderId)
+		if !known && orderId != 0 {
+			previous, known = rg.Orders.GetOrderById(orderId)
+		}
+	}
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+	err := rg.checkPrice(symbol, price)
+	if err == nil && !(known && previous.ReduceOnly) {
+		increase := quantity
+		if known {
+			increase = quantity - previous.Quantity
+		}
+		err = rg.checkExposure(symbol, side, quantity, increase, price)
+	}
+	if err != nil {
+		rg.Logger.Warn("blocked modify of ", origClientOrderId, " ", orderId, " to ", quantity, " ", symbol, " at ", price, ": ", err)
+		return err
+	}
+	rg.Logger.Info("allowed modify of ", origClientOrderId, " ", orderId, " to ", quantity, " ", symbol, " at ", price)
+	return nil
+}
+
+func (rg *RiskGuard) check(symbol, side string, quantity, price float64, reduceOnly bool) error {
+	if err := rg.checkPrice(symbol, price); err != nil {
+		return err
+	}
+	// Reduce only orders can not increase exposure, only the allowlist and the price band hold them back
+	if reduceOnly {
+		return nil
+	}
+	limits := rg.Limits
+	if limits.MaxOrdersPerSecond > 0 {
+		cutoff := time.Now().Add(-time.Second)
+		recent := rg.orderTimes[:0]
+		for _, orderTime := range rg.orderTimes {
+			if orderTime.After(cutoff) {
+				recent = append(recent, orderTime)
+			}
+		}
+		rg.orderTimes = recent
+		if len(recent) >= limits.MaxOrdersPerSecond {
+			return &RiskError{Rule: RiskRuleOrderRate, Symbol: symbol,
+				Reason: fmt.Sprintf("%d orders sent in the last second", len(recent))}
+		}
+	}
+
+	if limits.MaxOpenOrders > 0 && rg.Orders != nil {
+		if open := len(rg.Orders.OpenOrders("")); open >= limits.MaxOpenOrders {
+			return &RiskError{Rule: RiskRuleOpenOrders, Symbol: symbol,
+				Reason: fmt.Sprintf("%d orders are open already", open)}
+		}
+	}
+	return rg.checkExposure(symbol, side, quantity, quantity, price)
+}
+
+// checkPrice allowlist and price band
+func (rg *RiskGuard) checkPrice(symbol string, price float64) error {
+	limits := rg.Limits
+	if len(limits.AllowedSymbols) > 0 {
+		allowed := false
+		for _, allowedSymbol := range limits.AllowedSymbols {
+			allowed = allowed || allowedSymbol == symbol
+		}
+		if !allowed {
+			return &RiskError{Rule: RiskRuleSymbol, Symbol: symbol, Reason: "symbol is not in the allowlist"}
+		}
+	}
+
+	reference, known := rg.prices[symbol]
+	if price != 0 && limits.PriceBandPercent > 0 {
+		if !known {
+			return &RiskError{Rule: RiskRuleReferencePrice, Symbol: symbol, Reason: "no reference price for the price band"}
+		}
+		if math.Abs(price-reference)/reference*100 > limits.PriceBandPercent {
+			return &RiskError{Rule: RiskRulePriceBand, Symbol: symbol,
+				Reason: fmt.Sprintf("price %v is more than %v%% away from %v", price, limits.PriceBandPercent, reference)}
+		}
+	}
+	return nil
+}
+
+// checkExposure notional of quantity and the position after increase fills, increase 0 or less
+// can not grow the position
+func (rg *RiskGuard) checkExposure(symbol, side string, quantity, increase, price float64) error {
+	limits := rg.Limits
+	reference, known := rg.prices[symbol]
+	if limits.MaxOrderNotional > 0 {
+		notionalPrice := price
+		if notionalPrice == 0 {
+			if !known {
+				return &RiskError{Rule: RiskRuleReferencePrice, Symbol: symbol, Reason: "no reference price for market order notional"}
+			}
+			notionalPrice = reference
+		}
+		notional := quantity * notionalPrice
+		if size, exists := limits.ContractSizes[symbol]; exists {
+			notional = quantity * size
+		}
+		if notional > limits.MaxOrderNotional {
+			return &RiskError{Rule: RiskRuleNotional, Symbol: symbol,
+				Reason: fmt.Sprintf("notional %v is above %v", notional, limits.MaxOrderNotional)}
+		}
+	}
+	if maxPosition, exists := limits.MaxPosition[symbol]; exists && rg.Positions != nil && increase > 0 {
+		current := 0.0
+		for _, positionSide := range []string{PositionSideBoth, PositionSideLong, PositionSideShort} {
+			if position, exists := rg.Positions.Position(symbol, positionSide); exists {
+				current += position.Quantity
+			}
+		}
+		projected := current + increase
+		if side == SideSell {
+			projected = current - increase
+		}
+		if math.Abs(projected) > maxPosition {
+			return &RiskError{Rule: RiskRulePosition, Symbol: symbol,
+				Reason: fmt.Sprintf("position would be %v, limit is %v", projected, maxPosition)}
+		}
+	}
+	return nil
+}
+
+func (rg *RiskGuard) PlaceLimitOrder(symbol, side string, price, qty float64, reduceOnly bool) ([]byte, error) {
+	if err := rg.Check(symbol, side, qty, price, reduceOnly); err != nil {
+		return nil, err
+	}
+	return rg.BinanceFutures.PlaceLimitOrder(symbol, side, price, qty, reduceOnly)
+}
+
+func (rg *RiskGuard) PlacePostOnlyLimitOrder(symbol, side string, price, qty float64, reduceOnly bool) ([]byte, error) {
+	if err := rg.Check(symbol, side, qty, price, reduceOnly); err != nil {
+		return nil, err
+	}
+	return rg.BinanceFutures.PlacePostOnlyLimitOrder(symbol, side, price, qty, reduceOnly)
+}
+
+func (rg *RiskGuard) PlaceMarketOrder(symbol, side string, qty float64, reduceOnly bool) ([]byte, error) {
+	if err := rg.Check(symbol, side, qty, 0, reduceOnly); err != nil {
+		return nil, err
+	}
+	return rg.BinanceFutures.PlaceMarketOrder(symbol, side, qty, reduceOnly)
+}
+
+// PlaceHedgeMarketOrder is checked as reduce only when it closes the position side.
+func (rg *RiskGuard) PlaceHedgeMarketOrder(symbol, side, positionSide string, qty float64) ([]byte, error) {
+	closing := (positionSide == PositionSideLong && side == SideSell) || (positionSide == PositionSideShort && side == SideBuy)
+	if err := rg.Check(symbol, side, qty, 0, closing); err != nil {
+		return nil, err
+	}
+	return rg.BinanceFutures.PlaceHedgeMarketOrder(symbol, side, positionSide, qty)
+}
+
+// PlaceStopMarketOrder stop orders are reduce only, stop price is not held to the price band.
+func (rg *RiskGuard) PlaceStopMarketOrder(symbol, side string, stopPrice, qty float64) ([]byte, error) {
+	if err := rg.Check(symbol, side, qty, 0, true); err != nil {
+		return nil, err
+	}
+	return rg.BinanceFutures.PlaceStopMarketOrder(symbol, side, stopPrice, qty)
+}
+
+func (rg *RiskGuard) PlaceTakeProfitMarketOrder(symbol, side string, stopPrice, qty float64) ([]byte, error) {
+	if err := rg.Check(symbol, side, qty, 0, true); err != nil {
+		return nil, err
+	}
+	return rg.BinanceFutures.PlaceTakeProfitMarketOrder(symbol, side, stopPrice, qty)
+}
+
+func (rg *RiskGuard) PlaceClosePositionOrder(symbol, side, orderType string, stopPrice float64) ([]byte, error) {
+	if err := rg.Check(symbol, side, 0, 0, true); err != nil {
+		return nil, err
+	}
+	return rg.BinanceFutures.PlaceClosePositionOrder(symbol, side, orderType, stopPrice)
+}
+
+// GuardTransport wraps an OrderTransport, e.g. BinanceFuturesWsApi, with the checks of the guard.
+// Limits, reference prices and the order rate are shared with orders sent through the guard.
+func (rg *RiskGuard) GuardTransport(transport OrderTransport) OrderTransport {
+	return &guardedTransport{OrderTransport: transport, guard: rg}
+}
+
+type guardedTransport struct {
+	OrderTransport
+	guard *RiskGuard
+}
+
+func (gt *guardedTransport) PlaceLimitOrder(symbol, side string, price, qty float64, reduceOnly bool) ([]byte, error) {
+	if err := gt.guard.Check(symbol, side, qty, price, reduceOnly); err != nil {
+		return nil, err
+	}
+	return gt.OrderTransport.PlaceLimitOrder(symbol, side, price, qty, reduceOnly)
+}
+
+func (gt *guardedTransport) PlacePostOnlyLimitOrder(symbol, side string, price, qty float64, reduceOnly bool) ([]byte, error) {
+	if err := gt.guard.Check(symbol, side, qty, price, reduceOnly); err != nil {
+		return nil, err
+	}
+	return gt.OrderTransport.PlacePostOnlyLimitOrder(symbol, side, price, qty, reduceOnly)
+}
+
+func (gt *guardedTransport) PlaceMarketOrder(symbol, side string, qty float64, reduceOnly bool) ([]byte, error) {
+	if err := gt.guard.Check(symbol, side, qty, 0, reduceOnly); err != nil {
+		return nil, err
+	}
+	return gt.OrderTransport.PlaceMarketOrder(symbol, side, qty, reduceOnly)
+}
+
+func (gt *guardedTransport) PlaceStopMarketOrder(symbol, side string, stopPrice, qty float64) ([]byte, error) {
+	if err := gt.guard.Check(symbol, side, qty, 0, true); err != nil {
+		return nil, err
+	}
+	return gt.OrderTransport.PlaceStopMarketOrder(symbol, side, stopPrice, qty)
+}
+
+func (gt *guardedTransport) PlaceTakeProfitMarketOrder(symbol, side string, stopPrice, qty float64) ([]byte, error) {
+	if err := gt.guard.Check(symbol, side, qty, 0, true); err != nil {
+		return nil, err
+	}
+	return gt.OrderTransport.PlaceTakeProfitMarketOrder(symbol, side, stopPrice, qty)
+}
+
+func (gt *guardedTransport) PlaceClosePositionOrder(symbol, side, orderType string, stopPrice float64) ([]byte, error) {
+	if err := gt.guard.Check(symbol, side, 0, 0, true); err != nil {
+		return nil, err
+	}
+	return gt.OrderTransport.PlaceClosePositionOrder(symbol, side, orderType, stopPrice)
+}
+
+// ModifyOrder see RiskGuard.CheckModify
+func (gt *guardedTransport) ModifyOrder(symbol, side, origClientOrderId string, orderId int64, price, qty float64) ([]byte, error) {
+	if err := gt.guard.CheckModify(symbol, side, origClientOrderId, orderId, price, qty); err != nil {
+		return nil, err
+	}
+	return gt.OrderTransport.ModifyOrder(symbol, side, origClientOrderId, orderId, price, qty)
+}
@@ -0,0 +1,581 @@
+package go_binance
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"github.com/redlon23/go-binance/models"
+	"github.com/sirupsen/logrus"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// Binance limits
+	MaxStreamsPerConnection = 1024
+	DefaultPoolBufferSize   = 1024
+	// Binance accepts 10 incoming messages per second on a connection
+	DefaultPoolControlInterval = 110 * time.Millisecond
+	poolRequestBatchSize       = resubscribeBatchSize
+)
+
+var (
+	errPoolUserStream      = errors.New("user streams are not pooled, use a UserStreamManager")
+	errPoolControlRequests = errors.New("the pool decides the connection, subscribe through the pool")
+)
+
+type poolShard struct {
+	socket  *ManagedWebSocket
+	streams map[string]bool
+	closed  bool
+
+	controlMu   sync.Mutex
+	lastControl time.Time
+}
+
+// StreamPool spreads stream subscriptions over as many connections as needed, at most
+// MaxStreams per connection, and merges their messages into a single feed read with
+// ReadFromConnection. Every shard is a ManagedWebSocket, its reconnects and resubscriptions
+// are not visible to readers apart from the messages missed meanwhile.
+// Control messages of a shard are sent at most every ControlInterval. New streams go to the
+// least loaded shard, after unsubscribing the streams are packed into as few shards as needed.
+// Messages are not enveloped unless SetCombinedProperty is used, EventDispatcher reads both.
+type StreamPool struct {
+	// NewSocket creates the socket of a new shard, main or test net is set by the pool
+	NewSocket       func() BinanceFutureSocket
+	MaxStreams      int
+	ControlInterval time.Duration
+	BufferSize      int
+	Logger          *logrus.Logger
+
+	mu             sync.Mutex
+	shards         []*poolShard
+	testNet        bool
+	combined       *bool
+	prepareLoggers bool
+	messages       chan []byte
+	done           chan struct{}
+	closed         bool
+}
+
+func NewStreamPool(newSocket func() BinanceFutureSocket) *StreamPool {
+	return &StreamPool{
+		NewSocket:       newSocket,
+		MaxStreams:      MaxStreamsPerConnection,
+		ControlInterval: DefaultPoolControlInterval,
+		BufferSize:      DefaultPoolBufferSize,
+		Logger:          logrus.New(),
+	}
+}
+
+// NewFuturesStreamPool pool of USD-M connections
+func NewFuturesStreamPool() *StreamPool {
+	return NewStreamPool(func() BinanceFutureSocket {
+		return &BinanceFuturesWebSocket{BaseUrl: baseNetWSURL, Logger: logrus.New()}
+	})
+}
+
+// NewCoinFuturesStreamPool pool of Coin-M connections
+func NewCoinFuturesStreamPool() *StreamPool {
+	return NewStreamPool(func() BinanceFutureSocket {
+		return &BinanceFuturesCoinWebSocket{BaseUrl: baseNetWSURLCoin, Logger: logrus.New()}
+	})
+}
+
+// PrepareLoggers loggers of shards opened afterwards are prepared as well.
+func (sp *StreamPool) PrepareLoggers() {
+	sp.Logger = logrus.New()
+	sp.Logger.Formatter = new(logrus.JSONFormatter)
+
+	poolLogs, err := os.OpenFile("logs/binance_stream_pool.log", os.O_CREATE|os.O_WRONLY, 0666)
+	if err == nil {
+		sp.Logger.SetOutput(poolLogs)
+	} else {
+		fmt.Println("Failed to log to file for stream pool, using default stderr")
+	}
+	sp.mu.Lock()
+	sp.prepareLoggers = true
+	sp.mu.Unlock()
+}
+
+// UseMainNet applies to shards opened afterwards.
+func (sp *StreamPool) UseMainNet() {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	sp.testNet = false
+}
+
+// UseTestNet applies to shards opened afterwards.
+func (sp *StreamPool) UseTestNet() {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	sp.testNet = true
+}
+
+// IncrementSubscribeIdCounter every shard counts its own request ids.
+func (sp *StreamPool) IncrementSubscribeIdCounter() {}
+
+// OpenWebSocketConnection prepares the merged feed, shards are opened when streams are added.
+func (sp *StreamPool) OpenWebSocketConnection() error {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	sp.open()
+	return nil
+}
+
+// open runs with the lock held
+func (sp *StreamPool) open() {
+	if sp.messages != nil && !sp.closed {
+		return
+	}
+	sp.messages = make(chan []byte, sp.BufferSize)
+	sp.done = make(chan struct{})
+	sp.closed = false
+}
+
+func (sp *StreamPool) OpenWebSocketConnectionWithUserStream(listenKey string) error {
+	return errPoolUserStream
+}
+
+// OpenCombinedStreamConnection subscribes to the streams, spread over the needed connections.
+func (sp *StreamPool) OpenCombinedStreamConnection(streams []string) error {
+	if err := sp.OpenWebSocketConnection(); err != nil {
+		return err
+	}
+	return sp.Subscribe(streams, DefaultStreamAckTimeout)
+}
+
+// newShard dials a new connection without the lock, other calls go on while it connects
+func (sp *StreamPool) newShard() (*poolShard, error) {
+	sp.mu.Lock()
+	testNet, prepareLoggers, combined := sp.testNet, sp.prepareLoggers, sp.combined
+	sp.mu.Unlock()
+
+	socket := sp.NewSocket()
+	if testNet {
+		socket.UseTestNet()
+	} else {
+		socket.UseMainNet()
+	}
+	managed := NewManagedWebSocket(socket)
+	if prepareLoggers {
+		managed.PrepareLoggers()
+	}
+	if err := managed.OpenWebSocketConnection(); err != nil {
+		return nil, err
+	}
+	shard := &poolShard{socket: managed, streams: make(map[string]bool)}
+	if combined != nil {
+		if err := sp.control(shard, func() error { return managed.SetCombinedProperty(*combined, 0) }); err != nil {
+			_ = managed.CloseConnection()
+			return nil, err
+		}
+	}
+
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	if sp.closed {
+		_ = managed.CloseConnection()
+		return nil, ErrConnectionClosed
+	}
+	sp.shards = append(sp.shards, shard)
+	go sp.read(shard, sp.messages, sp.done)
+	sp.Logger.Info("opened shard ", len(sp.shards))
+	return shard, nil
+}
+
+// control waits until the shard may receive the next control message
+func (sp *StreamPool) control(shard *poolShard, send func() error) error {
+	shard.controlMu.Lock()
+	defer shard.controlMu.Unlock()
+	if wait := time.Until(shard.lastControl.Add(sp.ControlInterval)); wait > 0 {
+		time.Sleep(wait)
+	}
+	err := send()
+	shard.lastControl = time.Now()
+	return err
+}
+
+func (sp *StreamPool) read(shard *poolShard, messages chan []byte, done chan struct{}) {
+	for {
+		_, message, err := shard.socket.ReadFromConnection()
+		if err != nil {
+			sp.mu.Lock()
+			closed := shard.closed
+			sp.mu.Unlock()
+			if !closed {
+				sp.Logger.Error("shard failed, moving its streams: ", err)
+				sp.replace(shard)
+			}
+			return
+		}
+		select {
+		case messages <- message:
+		case <-done:
+			return
+		}
+	}
+}
+
+// replace moves the streams of a shard which gave up reconnecting to the other shards
+func (sp *StreamPool) replace(failed *poolShard) {
+	sp.mu.Lock()
+	streams := sp.removeShard(failed)
+	sp.mu.Unlock()
+	if err := sp.Subscribe(streams, DefaultStreamAckTimeout); err != nil {
+		sp.Logger.Error("could not move streams of a failed shard: ", err)
+	}
+}
+
+// removeShard closes the shard and returns its streams, runs with the lock held
+func (sp *StreamPool) removeShard(shard *poolShard) []string {
+	shard.closed = true
+	_ = shard.socket.CloseConnection()
+	remaining := sp.shards[:0]
+	for _, existing := range sp.shards {
+		if existing != shard {
+			remaining = append(remaining, existing)
+		}
+	}
+	sp.shards = remaining
+	streams := make([]string, 0, len(shard.streams))
+	for stream := range shard.streams {
+		streams = append(streams, stream)
+	}
+	sort.Strings(streams)
+	return streams
+}
+
+// assign reserves a shard for every new stream, least loaded first. Streams which do not fit
+// wait for the shards dialed for them, outside the lock, and are then assigned again since
+// other calls may have used the new capacity meanwhile.
+func (sp *StreamPool) assign(streams []string) (map[*poolShard][]string, error) {
+	assigned := make(map[*poolShard][]string)
+	for {
+		unassigned := make([]string, 0)
+		sp.mu.Lock()
+		for _, stream := range streams {
+			if sp.shardOf(stream) != nil {
+				continue
+			}
+			var target *poolShard
+			for _, shard := range sp.shards {
+				if len(shard.streams) < sp.MaxStreams && (target == nil || len(shard.streams) < len(target.streams)) {
+					target = shard
+				}
+			}
+			if target == nil {
+				unassigned = append(unassigned, stream)
+				continue
+			}
+			target.streams[stream] = true
+			assigned[target] = append(assigned[target], stream)
+		}
+		sp.mu.Unlock()
+		if len(unassigned) == 0 {
+			return assigned, nil
+		}
+
+		for needed := (len(unassigned) + sp.MaxStreams - 1) / sp.MaxStreams; needed > 0; needed-- {
+			if _, err := sp.newShard(); err != nil {
+				return assigned, err
+			}
+		}
+		streams = unassigned
+	}
+}
+
+func (sp *StreamPool) shardOf(stream string) *poolShard {
+	for _, shard := range sp.shards {
+		if shard.streams[stream] {
+			return shard
+		}
+	}
+	return nil
+}
+
+// Subscribe adds streams by name, e.g. btcusdt@aggTrade, sent in batches per shard.
+// Timeout 0 does not wait for the acknowledgements.
+func (sp *StreamPool) Subscribe(streams []string, timeout time.Duration) error {
+	_, err := sp.subscribe(streams, timeout)
+	return err
+}
+
+// subscribe returns the streams which were sent successfully, per shard
+func (sp *StreamPool) subscribe(streams []string, timeout time.Duration) (map[*poolShard][]string, error) {
+	sp.mu.Lock()
+	sp.open()
+	sp.mu.Unlock()
+	assigned, err := sp.assign(streams)
+
+	subscribed := make(map[*poolShard][]string)
+	for shard, names := range assigned {
+		for _, batch := range batchStreams(names) {
+			sendErr := sp.control(shard, func() error {
+				_, err := shard.socket.SendStreamRequest(StreamMethodSubscribe, batch, timeout)
+				return err
+			})
+			sp.mu.Lock()
+			for _, name := range batch {
+				if sendErr != nil {
+					delete(shard.streams, name.(string))
+				} else {
+					shard.socket.track(managedSubscription{StreamType: name.(string)})
+					subscribed[shard] = append(subscribed[shard], name.(string))
+				}
+			}
+			sp.mu.Unlock()
+			if sendErr != nil && err == nil {
+				err = sendErr
+			}
+		}
+	}
+	return subscribed, err
+}
+
+// Unsubscribe removes streams by name and packs the rest into as few shards as needed.
+func (sp *StreamPool) Unsubscribe(streams []string, timeout time.Duration) error {
+	sp.mu.Lock()
+	assigned := make(map[*poolShard][]string)
+	for _, stream := range streams {
+		if shard := sp.shardOf(stream); shard != nil {
+			delete(shard.streams, stream)
+			assigned[shard] = append(assigned[shard], stream)
+		}
+	}
+	sp.mu.Unlock()
+
+	err := sp.unsubscribeFrom(assigned, timeout)
+	if rebalanceErr := sp.Rebalance(); rebalanceErr != nil && err == nil {
+		err = rebalanceErr
+	}
+	return err
+}
+
+// unsubscribeFrom sends the requests of streams already removed from the shards
+func (sp *StreamPool) unsubscribeFrom(assigned map[*poolShard][]string, timeout time.Duration) error {
+	var err error
+	for shard, names := range assigned {
+		for _, batch := range batchStreams(names) {
+			sendErr := sp.control(shard, func() error {
+				_, err := shard.socket.SendStreamRequest(StreamMethodUnsubscribe, batch, timeout)
+				return err
+			})
+			for _, name := range batch {
+				shard.socket.untrack(name.(string))
+			}
+			if sendErr != nil && err == nil {
+				err = sendErr
+			}
+		}
+	}
+	return err
+}
+
+func batchStreams(names []string) [][]interface{} {
+	batches := make([][]interface{}, 0, len(names)/poolRequestBatchSize+1)
+	for start := 0; start < len(names); start += poolRequestBatchSize {
+		end := start + poolRequestBatchSize
+		if end > len(names) {
+			end = len(names)
+		}
+		batch := make([]interface{}, 0, end-start)
+		for _, name := range names[start:end] {
+			batch = append(batch, name)
+		}
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// Rebalance closes shards which are not needed for the current number of streams, the least
+// loaded first. Their streams are subscribed on the remaining shards before the connection is
+// closed, so a few messages may arrive twice but none are missed. When moving fails the shard
+// stays and streams moved already are unsubscribed from their new shards again.
+func (sp *StreamPool) Rebalance() error {
+	for {
+		sp.mu.Lock()
+		total := 0
+		for _, shard := range sp.shards {
+			total += len(shard.streams)
+		}
+		needed := (total + sp.MaxStreams - 1) / sp.MaxStreams
+		if len(sp.shards) <= needed {
+			sp.mu.Unlock()
+			return nil
+		}
+		drained := sp.shards[0]
+		for _, shard := range sp.shards {
+			if len(shard.streams) < len(drained.streams) {
+				drained = shard
+			}
+		}
+		streams := make([]string, 0, len(drained.streams))
+		for stream := range drained.streams {
+			streams = append(streams, stream)
+		}
+		sort.Strings(streams)
+		// Hidden from assign while moving, the connection keeps delivering meanwhile
+		remaining := sp.shards[:0:0]
+		for _, shard := range sp.shards {
+			if shard != drained {
+				remaining = append(remaining, shard)
+			}
+		}
+		sp.shards = remaining
+		sp.mu.Unlock()
+
+		sp.Logger.Info("rebalancing, moving ", len(streams), " streams off a shard")
+		if moved, err := sp.subscribe(streams, DefaultStreamAckTimeout); err != nil {
+			// The drained shard keeps every stream, streams moved already would arrive twice
+			sp.mu.Lock()
+			for shard, names := range moved {
+				for _, name := range names {
+					delete(shard.streams, name)
+				}
+			}
+			sp.shards = append(sp.shards, drained)
+			sp.mu.Unlock()
+			if rollbackErr := sp.unsubscribeFrom(moved, DefaultStreamAckTimeout); rollbackErr != nil {
+				sp.Logger.Error("could not roll back a failed rebalance: ", rollbackErr)
+			}
+			return err
+		}
+		sp.mu.Lock()
+		drained.closed = true
+		sp.mu.Unlock()
+		_ = drained.socket.CloseConnection()
+	}
+}
+
+func (sp *StreamPool) SubscribeToStream(symbol, streamType string) error {
+	return sp.Subscribe([]string{StreamName(symbol, streamType)}, 0)
+}
+
+func (sp *StreamPool) SubscribeToStreamAndWait(symbol, streamType string, timeout time.Duration) error {
+	return sp.Subscribe([]string{StreamName(symbol, streamType)}, timeout)
+}
+
+func (sp *StreamPool) UnsubscribeFromStream(symbol, streamType string) error {
+	return sp.Unsubscribe([]string{StreamName(symbol, streamType)}, 0)
+}
+
+// ListSubscriptions asks every shard, the pool keeps reading meanwhile.
+func (sp *StreamPool) ListSubscriptions(timeout time.Duration) ([]string, error) {
+	names := make([]string, 0)
+	for _, shard := range sp.shardList() {
+		var shardNames []string
+		err := sp.control(shard, func() error {
+			var err error
+			shardNames, err = shard.socket.ListSubscriptions(timeout)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, shardNames...)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// SetCombinedProperty applies to every shard, shards opened later included.
+func (sp *StreamPool) SetCombinedProperty(combined bool, timeout time.Duration) error {
+	sp.mu.Lock()
+	sp.combined = &combined
+	sp.mu.Unlock()
+	for _, shard := range sp.shardList() {
+		err := sp.control(shard, func() error { return shard.socket.SetCombinedProperty(combined, timeout) })
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sp *StreamPool) GetCombinedProperty(timeout time.Duration) (bool, error) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	return sp.combined != nil && *sp.combined, nil
+}
+
+func (sp *StreamPool) SendStreamRequest(method string, params []interface{}, timeout time.Duration) (*models.StreamResponse, error) {
+	return nil, errPoolControlRequests
+}
+
+func (sp *StreamPool) SubscribeLiquidationStream(symbol string) error {
+	return sp.SubscribeToStream(symbol, liquidationStreamName)
+}
+
+func (sp *StreamPool) SubscribeBookTickerStream(symbol string) error {
+	return sp.SubscribeToStream(symbol, bookTickerSteamName)
+}
+
+func (sp *StreamPool) SubscribeSymbolTickerStream(symbol string) error {
+	return sp.SubscribeToStream(symbol, symbolTickerName)
+}
+
+func (sp *StreamPool) shardList() []*poolShard {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	return append([]*poolShard(nil), sp.shards...)
+}
+
+// Streams names of all pooled streams
+func (sp *StreamPool) Streams() []string {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	streams := make([]string, 0)
+	for _, shard := range sp.shards {
+		for stream := range shard.streams {
+			streams = append(streams, stream)
+		}
+	}
+	sort.Strings(streams)
+	return streams
+}
+
+// ShardCount number of open connections
+func (sp *StreamPool) ShardCount() int {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	return len(sp.shards)
+}
+
+// ReadFromConnection next message of any shard.
+func (sp *StreamPool) ReadFromConnection() (messageType int, p []byte, err error) {
+	sp.mu.Lock()
+	if sp.messages == nil {
+		sp.open()
+	}
+	messages, done := sp.messages, sp.done
+	sp.mu.Unlock()
+	select {
+	case message := <-messages:
+		return websocket.TextMessage, message, nil
+	case <-done:
+		return -1, nil, ErrConnectionClosed
+	}
+}
+
+// CloseConnection closes every shard, streams have to be subscribed again after reopening.
+func (sp *StreamPool) CloseConnection() error {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	if sp.closed || sp.done == nil {
+		return nil
+	}
+	sp.closed = true
+	close(sp.done)
+	var err error
+	for _, shard := range sp.shards {
+		shard.closed = true
+		if closeErr := shard.socket.CloseConnection(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	sp.shards = nil
+	return err
+}
@@ -0,0 +1,254 @@
+package go_binance
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultRecorderMaxFileSize = 100 * 1024 * 1024
+	DefaultRecorderBufferSize  = 4096
+
+	recordingExtension  = ".jsonl.gz"
+	recordingTimeFormat = "20060102-150405"
+)
+
+// recordedFrame one line of a recording, Time is the receive time in unix nanoseconds.
+// Data is base64 in the file, binary frames and invalid UTF-8 come back byte for byte.
+type recordedFrame struct {
+	Time int64  `json:"time"`
+	Type int    `json:"type"`
+	Data []byte `json:"data"`
+}
+
+// recorderRequest a frame to write, or with done a flush or close after the frames queued before
+type recorderRequest struct {
+	frame recordedFrame
+	done  chan error
+	close bool
+}
+
+// StreamRecorder writes every frame it is given to gzip compressed files of json lines, one
+// frame per line with its receive time. Set it as Recorder of BinanceFuturesWebSocket or
+// BinanceFuturesCoinWebSocket to record everything read from the connection.
+// Files are named <Prefix>-<start time>-<sequence>.jsonl.gz, so their names sort chronologically,
+// and a new file is started when MaxFileSize is reached or, with RotateHourly, every hour.
+// Frames are compressed and written on a goroutine of the recorder, the reading goroutine only
+// queues them. A full queue drops the frame, see Dropped.
+type StreamRecorder struct {
+	Directory string
+	Prefix    string
+	// MaxFileSize compressed bytes, checked after every frame, 0 does not rotate by size
+	MaxFileSize  int64
+	RotateHourly bool
+	// BufferSize frames queued for writing, read when the first frame is recorded
+	BufferSize int
+	Logger     *logrus.Logger
+
+	startOnce sync.Once
+	requests  chan recorderRequest
+	droppedMu sync.Mutex
+	dropped   int64
+	// mu guards the file state of the writer, Record never waits for it
+	mu       sync.Mutex
+	file     *os.File
+	writer   *gzip.Writer
+	counter  *countingWriter
+	opened   time.Time
+	sequence int
+}
+
+type countingWriter struct {
+	file    *os.File
+	written int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.file.Write(p)
+	cw.written += int64(n)
+	return n, err
+}
+
+func NewStreamRecorder(directory, prefix string) *StreamRecorder {
+	return &StreamRecorder{
+		Directory:    directory,
+		Prefix:       prefix,
+		MaxFileSize:  DefaultRecorderMaxFileSize,
+		RotateHourly: true,
+		BufferSize:   DefaultRecorderBufferSize,
+		Logger:       logrus.New(),
+	}
+}
+
+func (sr *StreamRecorder) PrepareLoggers() {
+	sr.Logger = logrus.New()
+	sr.Logger.Formatter = new(logrus.JSONFormatter)
+
+	recorderLogs, err := os.OpenFile("logs/binance_recorder.log", os.O_CREATE|os.O_WRONLY, 0666)
+	if err == nil {
+		sr.Logger.SetOutput(recorderLogs)
+	} else {
+		fmt.Println("Failed to log to file for stream recorder, using default stderr")
+	}
+}
+
+// Record queues a copy of the frame without waiting for the file, ErrRecorderFull when the
+// queue is full and the frame was dropped. Write failures are logged by the writer.
+func (sr *StreamRecorder) Record(messageType int, data []byte, received time.Time) error {
+	frame := recordedFrame{Time: received.UnixNano(), Type: messageType, Data: append([]byte(nil), data...)}
+	select {
+	case sr.start() <- recorderRequest{frame: frame}:
+		return nil
+	default:
+		sr.droppedMu.Lock()
+		sr.dropped++
+		sr.droppedMu.Unlock()
+		return ErrRecorderFull
+	}
+}
+
+// Dropped frames which did not fit into the queue
+func (sr *StreamRecorder) Dropped() int64 {
+	sr.droppedMu.Lock()
+	defer sr.droppedMu.Unlock()
+	return sr.dropped
+}
+
+// start the writer runs from the first request on
+func (sr *StreamRecorder) start() chan recorderRequest {
+	sr.startOnce.Do(func() {
+		size := sr.BufferSize
+		if size <= 0 {
+			size = DefaultRecorderBufferSize
+		}
+		sr.requests = make(chan recorderRequest, size)
+		go sr.run(sr.requests)
+	})
+	return sr.requests
+}
+
+func (sr *StreamRecorder) run(requests chan recorderRequest) {
+	for request := range requests {
+		sr.mu.Lock()
+		switch {
+		case request.done == nil:
+			sr.write(request.frame)
+		case request.close:
+			request.done <- sr.closeFile()
+		case sr.writer == nil:
+			request.done <- nil
+		default:
+			request.done <- sr.writer.Flush()
+		}
+		sr.mu.Unlock()
+	}
+}
+
+// write caller holds mu, the file is rotated first when needed
+func (sr *StreamRecorder) write(frame recordedFrame) {
+	received := time.Unix(0, frame.Time)
+	if sr.rotationDue(received) {
+		if err := sr.rotate(received); err != nil {
+			sr.Logger.Error("could not start recording file: ", err)
+			return
+		}
+	}
+	line, err := json.Marshal(frame)
+	if err != nil {
+		sr.Logger.Error("could not encode frame: ", err)
+		return
+	}
+	if _, err := sr.writer.Write(append(line, '\n')); err != nil {
+		sr.Logger.Error("could not record frame: ", err)
+	}
+}
+
+// request waits until the writer handled every frame queued before and then flushed or closed
+func (sr *StreamRecorder) request(finish bool) error {
+	done := make(chan error, 1)
+	sr.start() <- recorderRequest{done: done, close: finish}
+	return <-done
+}
+
+// rotationDue caller holds mu
+func (sr *StreamRecorder) rotationDue(received time.Time) bool {
+	if sr.file == nil {
+		return true
+	}
+	if sr.RotateHourly && !received.Truncate(time.Hour).Equal(sr.opened.Truncate(time.Hour)) {
+		return true
+	}
+	return sr.MaxFileSize > 0 && sr.counter.written >= sr.MaxFileSize
+}
+
+// rotate caller holds mu
+func (sr *StreamRecorder) rotate(now time.Time) error {
+	if err := sr.closeFile(); err != nil {
+		sr.Logger.Error("could not finish recording file: ", err)
+	}
+	if err := os.MkdirAll(sr.Directory, 0755); err != nil {
+		return err
+	}
+	sr.sequence++
+	name := fmt.Sprintf("%s-%s-%03d%s", sr.Prefix, now.UTC().Format(recordingTimeFormat), sr.sequence, recordingExtension)
+	file, err := os.OpenFile(filepath.Join(sr.Directory, name), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	sr.file, sr.opened = file, now
+	sr.counter = &countingWriter{file: file}
+	sr.writer = gzip.NewWriter(sr.counter)
+	sr.Logger.Info("recording to ", file.Name())
+	return nil
+}
+
+// closeFile caller holds mu
+func (sr *StreamRecorder) closeFile() error {
+	if sr.file == nil {
+		return nil
+	}
+	err := sr.writer.Close()
+	if closeErr := sr.file.Close(); err == nil {
+		err = closeErr
+	}
+	sr.file, sr.writer, sr.counter = nil, nil, nil
+	return err
+}
+
+// Path of the file being written, empty before the first frame.
+func (sr *StreamRecorder) Path() string {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	if sr.file == nil {
+		return ""
+	}
+	return sr.file.Name()
+}
+
+// Flush writes queued and buffered frames to the file, a crash loses frames after the last flush.
+// Files which were not closed can still be replayed up to the last flush.
+func (sr *StreamRecorder) Flush() error {
+	return sr.request(false)
+}
+
+// Close writes the queued frames and finishes the current file, the next frame starts a new one.
+func (sr *StreamRecorder) Close() error {
+	return sr.request(true)
+}
+
+// RecordedFiles recordings of the prefix in directory, oldest first, ready for NewStreamReplayer.
+func RecordedFiles(directory, prefix string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(directory, prefix+"-*"+recordingExtension))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
@@ -0,0 +1,262 @@
+package go_binance
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/redlon23/go-binance/models"
+	"github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// ReplayAsFastAsPossible frames are returned without waiting
+	ReplayAsFastAsPossible = 0
+	// ReplayOriginalSpeed frames are returned with the gaps they were received with
+	ReplayOriginalSpeed = 1
+)
+
+// StreamReplayer reads recordings of a StreamRecorder back through the BinanceFutureSocket
+// interface, so strategies and parsers run against a recorded session without network.
+// Frames are returned in recorded order with their original bytes and message type, only the
+// pacing depends on Speed: 1 is the original speed, 10 ten times faster and
+// ReplayAsFastAsPossible does not wait at all. ReadFromConnection returns io.EOF after the
+// last frame of the last file. Subscriptions and control requests are accepted and ignored,
+// the recording decides which streams are read.
+type StreamReplayer struct {
+	Files  []string
+	Speed  float64
+	Logger *logrus.Logger
+
+	mu        sync.Mutex
+	index     int
+	file      *os.File
+	reader    *bufio.Reader
+	started   time.Time
+	first     time.Time
+	frameTime time.Time
+	opened    bool
+	closed    bool
+	done      chan struct{}
+}
+
+// NewStreamReplayer files are replayed in the given order, see RecordedFiles.
+func NewStreamReplayer(speed float64, files ...string) *StreamReplayer {
+	return &StreamReplayer{
+		Files:  files,
+		Speed:  speed,
+		Logger: logrus.New(),
+	}
+}
+
+func (sr *StreamReplayer) PrepareLoggers() {
+	sr.Logger = logrus.New()
+	sr.Logger.Formatter = new(logrus.JSONFormatter)
+
+	replayLogs, err := os.OpenFile("logs/binance_replay.log", os.O_CREATE|os.O_WRONLY, 0666)
+	if err == nil {
+		sr.Logger.SetOutput(replayLogs)
+	} else {
+		fmt.Println("Failed to log to file for stream replayer, using default stderr")
+	}
+}
+
+func (sr *StreamReplayer) UseMainNet() {}
+
+func (sr *StreamReplayer) UseTestNet() {}
+
+func (sr *StreamReplayer) IncrementSubscribeIdCounter() {}
+
+// OpenWebSocketConnection starts the replay from the first frame of the first file.
+func (sr *StreamReplayer) OpenWebSocketConnection() error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	sr.open()
+	return nil
+}
+
+func (sr *StreamReplayer) OpenWebSocketConnectionWithUserStream(listenKey string) error {
+	return sr.OpenWebSocketConnection()
+}
+
+// OpenCombinedStreamConnection streams are ignored, record the combined connection to replay envelopes.
+func (sr *StreamReplayer) OpenCombinedStreamConnection(streams []string) error {
+	return sr.OpenWebSocketConnection()
+}
+
+// open caller holds mu
+func (sr *StreamReplayer) open() {
+	sr.closeFile()
+	if sr.opened && !sr.closed {
+		// A read still waiting for the previous replay gives up
+		close(sr.done)
+	}
+	sr.index, sr.started, sr.first, sr.frameTime = 0, time.Time{}, time.Time{}, time.Time{}
+	sr.opened, sr.closed, sr.done = true, false, make(chan struct{})
+}
+
+func (sr *StreamReplayer) SubscribeToStream(symbol, streamType string) error {
+	return nil
+}
+
+func (sr *StreamReplayer) SubscribeToStreamAndWait(symbol, streamType string, timeout time.Duration) error {
+	return nil
+}
+
+func (sr *StreamReplayer) UnsubscribeFromStream(symbol, streamType string) error {
+	return nil
+}
+
+func (sr *StreamReplayer) ListSubscriptions(timeout time.Duration) ([]string, error) {
+	return []string{}, nil
+}
+
+func (sr *StreamReplayer) SetCombinedProperty(combined bool, timeout time.Duration) error {
+	return nil
+}
+
+func (sr *StreamReplayer) GetCombinedProperty(timeout time.Duration) (bool, error) {
+	return false, nil
+}
+
+func (sr *StreamReplayer) SendStreamRequest(method string, params []interface{}, timeout time.Duration) (*models.StreamResponse, error) {
+	return &models.StreamResponse{}, nil
+}
+
+func (sr *StreamReplayer) SubscribeLiquidationStream(symbol string) error {
+	return nil
+}
+
+func (sr *StreamReplayer) SubscribeBookTickerStream(symbol string) error {
+	return nil
+}
+
+func (sr *StreamReplayer) SubscribeSymbolTickerStream(symbol string) error {
+	return nil
+}
+
+// ReadFromConnection returns the next recorded frame once it is due, opening the replay if needed.
+func (sr *StreamReplayer) ReadFromConnection() (messageType int, p []byte, err error) {
+	sr.mu.Lock()
+	if sr.closed {
+		sr.mu.Unlock()
+		return -1, nil, ErrConnectionClosed
+	}
+	if !sr.opened {
+		sr.open()
+	}
+	frame, err := sr.next()
+	if err != nil {
+		sr.mu.Unlock()
+		return -1, nil, err
+	}
+	received := time.Unix(0, frame.Time)
+	wait := sr.delay(received)
+	sr.frameTime = received
+	done := sr.done
+	sr.mu.Unlock()
+
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-done:
+			return -1, nil, ErrConnectionClosed
+		}
+	}
+	return frame.Type, frame.Data, nil
+}
+
+// next caller holds mu, moves on to the following file at the end of one
+func (sr *StreamReplayer) next() (recordedFrame, error) {
+	for {
+		if sr.reader == nil {
+			if sr.index >= len(sr.Files) {
+				return recordedFrame{}, io.EOF
+			}
+			path := sr.Files[sr.index]
+			sr.index++
+			if err := sr.openFile(path); err != nil {
+				sr.Logger.Error("could not open recording ", path, ": ", err)
+				return recordedFrame{}, err
+			}
+		}
+		line, err := sr.reader.ReadBytes('\n')
+		if err != nil {
+			// Files of a recorder which was not closed end without gzip footer
+			if !errors.Is(err, io.EOF) {
+				sr.Logger.Warn("recording ", sr.file.Name(), " ends early: ", err)
+			}
+			sr.closeFile()
+			continue
+		}
+		frame := recordedFrame{}
+		if err := json.Unmarshal(line, &frame); err != nil {
+			sr.Logger.Warn("skipping unreadable frame of ", sr.file.Name(), ": ", err)
+			continue
+		}
+		return frame, nil
+	}
+}
+
+// delay caller holds mu, time until the frame is due at Speed
+func (sr *StreamReplayer) delay(received time.Time) time.Duration {
+	if sr.Speed <= 0 {
+		return 0
+	}
+	if sr.first.IsZero() {
+		sr.first, sr.started = received, time.Now()
+		return 0
+	}
+	due := sr.started.Add(time.Duration(float64(received.Sub(sr.first)) / sr.Speed))
+	return time.Until(due)
+}
+
+// openFile caller holds mu
+func (sr *StreamReplayer) openFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	decompressed, err := gzip.NewReader(file)
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	sr.file, sr.reader = file, bufio.NewReader(decompressed)
+	return nil
+}
+
+// closeFile caller holds mu
+func (sr *StreamReplayer) closeFile() {
+	if sr.file != nil {
+		_ = sr.file.Close()
+	}
+	sr.file, sr.reader = nil, nil
+}
+
+// FrameTime receive time of the frame returned last, the recorded clock of the session.
+func (sr *StreamReplayer) FrameTime() time.Time {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sr.frameTime
+}
+
+// CloseConnection stops the replay, reads return ErrConnectionClosed until it is opened again.
+func (sr *StreamReplayer) CloseConnection() error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	if !sr.opened || sr.closed {
+		return nil
+	}
+	sr.closeFile()
+	close(sr.done)
+	sr.closed = true
+	return nil
+}
@@ -0,0 +1,115 @@
+package go_binance
+
+import (
+	"encoding/json"
+	"github.com/gorilla/websocket"
+	"github.com/redlon23/go-binance/models"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	StreamMethodSubscribe         = "SUBSCRIBE"
+	StreamMethodUnsubscribe       = "UNSUBSCRIBE"
+	StreamMethodListSubscriptions = "LIST_SUBSCRIPTIONS"
+	StreamMethodSetProperty       = "SET_PROPERTY"
+	StreamMethodGetProperty       = "GET_PROPERTY"
+
+	streamPropertyCombined = "combined"
+
+	DefaultStreamAckTimeout = 5 * time.Second
+)
+
+// streamRequestTracker gives every control request a unique id and hands responses read
+// from the connection to the waiting caller. Responses are only seen while the connection is read.
+type streamRequestTracker struct {
+	mu      sync.Mutex
+	writeMu sync.Mutex
+	pending map[int]chan models.StreamResponse
+}
+
+// send writes the request with the next id of counter, the returned channel receives the response.
+// Requests which are not awaited get no channel, their response is only returned by the read.
+func (srt *streamRequestTracker) send(connection *websocket.Conn, counter *int, method string,
+	params []interface{}, awaited bool) (int, chan models.StreamResponse, error) {
+	srt.mu.Lock()
+	if srt.pending == nil {
+		srt.pending = make(map[int]chan models.StreamResponse)
+	}
+	*counter++
+	id := *counter
+	var response chan models.StreamResponse
+	if awaited {
+		response = make(chan models.StreamResponse, 1)
+		srt.pending[id] = response
+	}
+	srt.mu.Unlock()
+
+	// gorilla/websocket supports a single concurrent writer
+	srt.writeMu.Lock()
+	err := connection.WriteJSON(models.StreamRequest{Method: method, Params: params, Id: id})
+	srt.writeMu.Unlock()
+	if err != nil {
+		srt.forget(id)
+		return id, nil, err
+	}
+	return id, response, nil
+}
+
+func (srt *streamRequestTracker) forget(id int) {
+	srt.mu.Lock()
+	defer srt.mu.Unlock()
+	delete(srt.pending, id)
+}
+
+// resolve passes a response message to its waiter, other messages are left alone
+func (srt *streamRequestTracker) resolve(message []byte) {
+	text := string(message)
+	if !strings.Contains(text, `"id"`) || !(strings.Contains(text, `"result"`) || strings.Contains(text, `"error"`)) {
+		return
+	}
+	response := models.StreamResponse{}
+	if err := json.Unmarshal(message, &response); err != nil {
+		return
+	}
+	srt.mu.Lock()
+	waiter, exists := srt.pending[response.Id]
+	delete(srt.pending, response.Id)
+	srt.mu.Unlock()
+	if exists {
+		waiter <- response
+	}
+}
+
+// wait timeout 0 returns right away without a response
+func (srt *streamRequestTracker) wait(id int, response chan models.StreamResponse, timeout time.Duration) (*models.StreamResponse, error) {
+	if timeout <= 0 {
+		return nil, nil
+	}
+	select {
+	case received := <-response:
+		if received.Error != nil {
+			return &received, &StreamRequestError{Id: id, Code: received.Error.Code, Message: received.Error.Message}
+		}
+		return &received, nil
+	case <-time.After(timeout):
+		srt.forget(id)
+		return nil, ErrStreamAckTimeout
+	}
+}
+
+func streamNames(result json.RawMessage) ([]string, error) {
+	names := make([]string, 0)
+	if len(result) == 0 || string(result) == "null" {
+		return names, nil
+	}
+	err := json.Unmarshal(result, &names)
+	return names, err
+}
+
+func streamProperty(result json.RawMessage) (bool, error) {
+	value := false
+	err := json.Unmarshal(result, &value)
+	return value, err
+}
@@ -0,0 +1,306 @@
+package go_binance
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	WatchdogEventStale = "STALE"
+	WatchdogEventLag   = "LAG"
+
+	DefaultWatchdogCheckInterval = time.Second
+	// Weight of the newest sample in the moving average latency
+	latencySmoothing = 0.1
+)
+
+// applyReadTimeout sets the first read deadline, every ping and every read moves it on.
+// A half open connection then fails the read instead of blocking it forever.
+func applyReadTimeout(connection *websocket.Conn, timeout time.Duration) {
+	if timeout <= 0 {
+		return
+	}
+	_ = connection.SetReadDeadline(time.Now().Add(timeout))
+	pong := connection.PingHandler()
+	connection.SetPingHandler(func(data string) error {
+		_ = connection.SetReadDeadline(time.Now().Add(timeout))
+		return pong(data)
+	})
+}
+
+// WatchdogEvent a stream was silent for longer than expected or its latency passed MaxLatency.
+type WatchdogEvent struct {
+	Type    string
+	Event   string
+	Symbol  string
+	Silence time.Duration
+	Latency time.Duration
+	Time    time.Time
+}
+
+// LatencyStats exchange to client latency of an event type, from E or T of the messages.
+// Clock differences between exchange and client are included, keep the clock synchronized.
+type LatencyStats struct {
+	Last    time.Duration
+	Average time.Duration
+	Max     time.Duration
+	Count   int64
+}
+
+type streamExpectation struct {
+	event    string
+	symbol   string
+	interval time.Duration
+	last     time.Time
+}
+
+// StreamWatchdog watches the messages read through it. A stream which stays silent longer than
+// its expected interval triggers Reconnect of the wrapped socket, e.g. a ManagedWebSocket.
+// Sockets without Reconnect only get the STALE event, closing them would end a
+// UserStreamManager or StreamPool for good. Latency is measured for every message with E or T.
+// Streams are identified by event type and symbol, an empty symbol matches every symbol.
+// Watching goes on across closes of the connection until Stop, so the watchdog can be
+// wrapped by a ManagedWebSocket as well.
+type StreamWatchdog struct {
+	BinanceFutureSocket
+	CheckInterval time.Duration
+	// A LAG event is emitted for messages later than this, 0 disables it
+	MaxLatency time.Duration
+	Logger     *logrus.Logger
+
+	mu           sync.Mutex
+	expectations []*streamExpectation
+	latencies    map[string]*LatencyStats
+	listeners    []func(WatchdogEvent)
+	stop         chan struct{}
+}
+
+func NewStreamWatchdog(socket BinanceFutureSocket) *StreamWatchdog {
+	return &StreamWatchdog{
+		BinanceFutureSocket: socket,
+		CheckInterval:       DefaultWatchdogCheckInterval,
+		Logger:              logrus.New(),
+		latencies:           make(map[string]*LatencyStats),
+	}
+}
+
+// PrepareLoggers prepares loggers of the wrapped socket as well.
+func (sw *StreamWatchdog) PrepareLoggers() {
+	sw.BinanceFutureSocket.PrepareLoggers()
+	sw.Logger = logrus.New()
+	sw.Logger.Formatter = new(logrus.JSONFormatter)
+
+	watchdogLogs, err := os.OpenFile("logs/binance_watchdog.log", os.O_CREATE|os.O_WRONLY, 0666)
+	if err == nil {
+		sw.Logger.SetOutput(watchdogLogs)
+	} else {
+		fmt.Println("Failed to log to file for stream watchdog, using default stderr")
+	}
+}
+
+func (sw *StreamWatchdog) Subscribe(listener func(WatchdogEvent)) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	sw.listeners = append(sw.listeners, listener)
+}
+
+func (sw *StreamWatchdog) emit(event WatchdogEvent) {
+	event.Time = time.Now()
+	sw.mu.Lock()
+	listeners := make([]func(WatchdogEvent), len(sw.listeners))
+	copy(listeners, sw.listeners)
+	sw.mu.Unlock()
+	for _, listener := range listeners {
+		listener(event)
+	}
+}
+
+// Expect a message of the event type and symbol at least every interval, e.g.
+// Expect(models.EventBookTicker, "BTCUSDT", 10*time.Second). The interval starts now.
+func (sw *StreamWatchdog) Expect(event, symbol string, interval time.Duration) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	for _, expectation := range sw.expectations {
+		if expectation.event == event && expectation.symbol == symbol {
+			expectation.interval, expectation.last = interval, time.Now()
+			return
+		}
+	}
+	sw.expectations = append(sw.expectations, &streamExpectation{event: event, symbol: symbol,
+		interval: interval, last: time.Now()})
+	if sw.stop == nil {
+		sw.stop = make(chan struct{})
+		go sw.check(sw.stop)
+	}
+}
+
+// Forget stops watching the event type and symbol, e.g. after unsubscribing.
+func (sw *StreamWatchdog) Forget(event, symbol string) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	remaining := sw.expectations[:0]
+	for _, expectation := range sw.expectations {
+		if expectation.event != event || expectation.symbol != symbol {
+			remaining = append(remaining, expectation)
+		}
+	}
+	sw.expectations = remaining
+}
+
+func (sw *StreamWatchdog) check(stop chan struct{}) {
+	ticker := time.NewTicker(sw.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			var stale []WatchdogEvent
+			sw.mu.Lock()
+			for _, expectation := range sw.expectations {
+				if silence := now.Sub(expectation.last); silence > expectation.interval {
+					stale = append(stale, WatchdogEvent{Type: WatchdogEventStale, Event: expectation.event,
+						Symbol: expectation.symbol, Silence: silence})
+				}
+			}
+			if len(stale) > 0 {
+				// Every stream gets a full interval on the new connection
+				for _, expectation := range sw.expectations {
+					expectation.last = now
+				}
+			}
+			sw.mu.Unlock()
+			if len(stale) == 0 {
+				continue
+			}
+			for _, event := range stale {
+				sw.Logger.Warn(event.Event, " ", event.Symbol, " silent for ", event.Silence, ", reconnecting")
+				sw.emit(event)
+			}
+			sw.reconnect()
+		}
+	}
+}
+
+func (sw *StreamWatchdog) reconnect() {
+	if reconnector, ok := sw.BinanceFutureSocket.(interface{ Reconnect(reason error) }); ok {
+		reconnector.Reconnect(ErrStreamStale)
+		return
+	}
+	sw.Logger.Warn("wrapped socket has no Reconnect, stale streams are only reported")
+}
+
+// ReadFromConnection records arrival and latency of every message before returning it.
+func (sw *StreamWatchdog) ReadFromConnection() (messageType int, p []byte, err error) {
+	messageType, p, err = sw.BinanceFutureSocket.ReadFromConnection()
+	if err == nil {
+		sw.observe(p, time.Now())
+	}
+	return messageType, p, err
+}
+
+type watchdogIdentity struct {
+	Event           string          `json:"e"`
+	EventTime       json.Number     `json:"E"`
+	Symbol          string          `json:"s"`
+	Side            json.RawMessage `json:"S"`
+	TransactionTime int64           `json:"T"`
+	Trade           json.RawMessage `json:"t"`
+}
+
+func (sw *StreamWatchdog) observe(message []byte, received time.Time) {
+	_, data, err := UnwrapCombinedMessage(message)
+	if err != nil {
+		return
+	}
+	identities := make([]watchdogIdentity, 0, 1)
+	if len(data) > 0 && data[0] == '[' {
+		_ = json.Unmarshal(data, &identities)
+	} else {
+		identity := watchdogIdentity{}
+		if json.Unmarshal(data, &identity) == nil {
+			identities = append(identities, identity)
+		}
+	}
+
+	var lagging []WatchdogEvent
+	sw.mu.Lock()
+	for _, identity := range identities {
+		if identity.Event == "" {
+			continue
+		}
+		for _, expectation := range sw.expectations {
+			if expectation.event == identity.Event && (expectation.symbol == "" || expectation.symbol == identity.Symbol) {
+				expectation.last = received
+			}
+		}
+		sent, _ := identity.EventTime.Int64()
+		if sent == 0 {
+			sent = identity.TransactionTime
+		}
+		if sent == 0 {
+			continue
+		}
+		latency := received.Sub(time.Unix(0, sent*int64(time.Millisecond)))
+		stats, exists := sw.latencies[identity.Event]
+		if !exists {
+			stats = &LatencyStats{Average: latency}
+			sw.latencies[identity.Event] = stats
+		}
+		stats.Last, stats.Count = latency, stats.Count+1
+		stats.Average += time.Duration(latencySmoothing * float64(latency-stats.Average))
+		if latency > stats.Max {
+			stats.Max = latency
+		}
+		if sw.MaxLatency > 0 && latency > sw.MaxLatency {
+			lagging = append(lagging, WatchdogEvent{Type: WatchdogEventLag, Event: identity.Event,
+				Symbol: identity.Symbol, Latency: latency})
+		}
+	}
+	sw.mu.Unlock()
+	for _, event := range lagging {
+		sw.emit(event)
+	}
+}
+
+// Latency of an event type, false before its first message with E or T.
+func (sw *StreamWatchdog) Latency(event string) (LatencyStats, bool) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	stats, exists := sw.latencies[event]
+	if !exists {
+		return LatencyStats{}, false
+	}
+	return *stats, true
+}
+
+// Latencies of every event type seen so far.
+func (sw *StreamWatchdog) Latencies() map[string]LatencyStats {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	latencies := make(map[string]LatencyStats, len(sw.latencies))
+	for event, stats := range sw.latencies {
+		latencies[event] = *stats
+	}
+	return latencies
+}
+
+// CloseConnection closes the wrapped socket, watching goes on for the next connection.
+func (sw *StreamWatchdog) CloseConnection() error {
+	return sw.BinanceFutureSocket.CloseConnection()
+}
+
+// Stop ends watching, the next Expect starts it again.
+func (sw *StreamWatchdog) Stop() {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	if sw.stop != nil {
+		close(sw.stop)
+		sw.stop = nil
+	}
+}
@@ -0,0 +1,135 @@
+package go_binance
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/redlon23/go-binance/models"
+	"math"
+	"strconv"
+)
+
+const (
+	filterTypePrice         = "PRICE_FILTER"
+	filterTypeLotSize       = "LOT_SIZE"
+	filterTypeMarketLotSize = "MARKET_LOT_SIZE"
+	filterTypeMinNotional   = "MIN_NOTIONAL"
+	filterTypePercentPrice  = "PERCENT_PRICE"
+)
+
+// SymbolFilters trading rules of a symbol from exchange information.
+// Zero values mean the filter is not present for the symbol.
+type SymbolFilters struct {
+	Symbol            string
+	TickSize          float64
+	MinPrice          float64
+	MaxPrice          float64
+	StepSize          float64
+	MinQuantity       float64
+	MaxQuantity       float64
+	MarketStepSize    float64
+	MarketMinQuantity float64
+	MarketMaxQuantity float64
+	MinNotional       float64
+	MultiplierUp      float64
+	MultiplierDown    float64
+}
+
+func filterValue(filter map[string]interface{}, key string) float64 {
+	switch value := filter[key].(type) {
+	case string:
+		parsed, _ := strconv.ParseFloat(value, 64)
+		return parsed
+	case float64:
+		return value
+	}
+	return 0
+}
+
+func ParseSymbolFilters(information models.ExchangeSymbolInformation) SymbolFilters {
+	filters := SymbolFilters{Symbol: information.Symbol}
+	for _, filter := range information.Filters {
+		switch filter["filterType"] {
+		case filterTypePrice:
+			filters.TickSize = filterValue(filter, "tickSize")
+			filters.MinPrice = filterValue(filter, "minPrice")
+			filters.MaxPrice = filterValue(filter, "maxPrice")
+		case filterTypeLotSize:
+			filters.StepSize = filterValue(filter, "stepSize")
+			filters.MinQuantity = filterValue(filter, "minQty")
+			filters.MaxQuantity = filterValue(filter, "maxQty")
+		case filterTypeMarketLotSize:
+			filters.MarketStepSize = filterValue(filter, "stepSize")
+			filters.MarketMinQuantity = filterValue(filter, "minQty")
+			filters.MarketMaxQuantity = filterValue(filter, "maxQty")
+		case filterTypeMinNotional:
+			filters.MinNotional = filterValue(filter, "notional")
+		case filterTypePercentPrice:
+			filters.MultiplierUp = filterValue(filter, "multiplierUp")
+			filters.MultiplierDown = filterValue(filter, "multiplierDown")
+		}
+	}
+	return filters
+}
+
+// LoadSymbolFilters reads exchange information once and returns filters of every symbol.
+func LoadSymbolFilters(api BinanceFutures) (map[string]SymbolFilters, error) {
+	data, err := api.GetExchangeInformation()
+	if err != nil {
+		return nil, err
+	}
+	information := new(models.ExchangeInformation)
+	if err := json.Unmarshal(data, information); err != nil {
+		return nil, err
+	}
+	filters := make(map[string]SymbolFilters, len(information.Symbols))
+	for _, symbol := range information.Symbols {
+		filters[symbol.Symbol] = ParseSymbolFilters(symbol)
+	}
+	return filters, nil
+}
+
+// NormalizePrice rounds price down to the tick size.
+func (sf SymbolFilters) NormalizePrice(price float64) float64 {
+	return RoundDownToStep(price, sf.TickSize)
+}
+
+// NormalizeQuantity rounds quantity down to the step size and caps it at the maximum quantity.
+// Market orders use MARKET_LOT_SIZE when the symbol has it.
+func (sf SymbolFilters) NormalizeQuantity(quantity float64, market bool) float64 {
+	step, maxQuantity := sf.StepSize, sf.MaxQuantity
+	if market && sf.MarketStepSize > 0 {
+		step, maxQuantity = sf.MarketStepSize, sf.MarketMaxQuantity
+	}
+	if maxQuantity > 0 {
+		quantity = math.Min(quantity, maxQuantity)
+	}
+	return RoundDownToStep(quantity, step)
+}
+
+// Validate checks quantity and price against the filters, price 0 skips price related checks.
+// Reduce only orders are exempt from the minimum notional rule on binance.
+func (sf SymbolFilters) Validate(quantity, price float64, market, reduceOnly bool) error {
+	minQuantity, maxQuantity := sf.MinQuantity, sf.MaxQuantity
+	if market && sf.MarketStepSize > 0 {
+		minQuantity, maxQuantity = sf.MarketMinQuantity, sf.MarketMaxQuantity
+	}
+	if quantity < minQuantity {
+		return fmt.Errorf("%s quantity %v is below minimum %v", sf.Symbol, quantity, minQuantity)
+	}
+	if maxQuantity > 0 && quantity > maxQuantity {
+		return fmt.Errorf("%s quantity %v is above maximum %v", sf.Symbol, quantity, maxQuantity)
+	}
+	if price == 0 {
+		return nil
+	}
+	if !market && price < sf.MinPrice {
+		return fmt.Errorf("%s price %v is below minimum %v", sf.Symbol, price, sf.MinPrice)
+	}
+	if !market && sf.MaxPrice > 0 && price > sf.MaxPrice {
+		return fmt.Errorf("%s price %v is above maximum %v", sf.Symbol, price, sf.MaxPrice)
+	}
+	if !reduceOnly && sf.MinNotional > 0 && quantity*price < sf.MinNotional {
+		return fmt.Errorf("%s notional %v is below minimum %v", sf.Symbol, quantity*price, sf.MinNotional)
+	}
+	return nil
+}
@@ -0,0 +1,553 @@
+package go_binance
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/redlon23/go-binance/models"
+	"github.com/sirupsen/logrus"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	LedgerEntryFill    = "FILL"
+	LedgerEntryFunding = "FUNDING"
+
+	DefaultLedgerMarginAsset = "USDT"
+	ledgerDayLayout          = "2006-01-02"
+	// tradeListWindow longest startTime to endTime span GetTradeList accepts, in milliseconds
+	tradeListWindow = 7 * 24 * int64(time.Hour/time.Millisecond)
+)
+
+// LedgerEntry one line of the ledger file. Fills carry exchange reported realized pnl,
+// funding entries only Funding and Asset.
+type LedgerEntry struct {
+	Kind            string  `json:"kind"`
+	Time            int64   `json:"time"`
+	Symbol          string  `json:"symbol"`
+	Tag             string  `json:"tag"`
+	OrderId         int64   `json:"orderId,omitempty"`
+	TradeId         int64   `json:"tradeId,omitempty"`
+	Side            string  `json:"side,omitempty"`
+	Price           float64 `json:"price,omitempty"`
+	Quantity        float64 `json:"quantity,omitempty"`
+	RealizedPnl     float64 `json:"realizedPnl,omitempty"`
+	Commission      float64 `json:"commission,omitempty"`
+	CommissionAsset string  `json:"commissionAsset,omitempty"`
+	Funding         float64 `json:"funding,omitempty"`
+	Asset           string  `json:"asset"`
+	Maker           bool    `json:"maker,omitempty"`
+}
+
+// LedgerPosition net position of one strategy tag on one symbol, CostBasis is the average entry price.
+type LedgerPosition struct {
+	Symbol      string
+	Tag         string
+	Quantity    float64
+	CostBasis   float64
+	RealizedPnl float64
+}
+
+// LedgerSummary commissions and funding are kept per asset, BNB fees are not converted.
+type LedgerSummary struct {
+	Fills       int
+	Volume      float64
+	RealizedPnl map[string]float64
+	Commissions map[string]float64
+	Funding     map[string]float64
+}
+
+func newLedgerSummary() LedgerSummary {
+	return LedgerSummary{
+		RealizedPnl: make(map[string]float64),
+		Commissions: make(map[string]float64),
+		Funding:     make(map[string]float64),
+	}
+}
+
+func (ls *LedgerSummary) add(entry LedgerEntry) {
+	switch entry.Kind {
+	case LedgerEntryFill:
+		ls.Fills++
+		ls.Volume += entry.Price * entry.Quantity
+		ls.RealizedPnl[entry.Asset] += entry.RealizedPnl
+		ls.Commissions[entry.CommissionAsset] += entry.Commission
+	case LedgerEntryFunding:
+		ls.Funding[entry.Asset] += entry.Funding
+	}
+}
+
+// LedgerReconciliation ledger total against income history of one income type and asset.
+// Commissions are compared as income, so both sides are negative for paid fees.
+type LedgerReconciliation struct {
+	IncomeType string
+	Asset      string
+	Ledger     float64
+	Exchange   float64
+	Difference float64
+}
+
+// TradeLedger records fills and funding payments from the user stream and GetTradeList.
+// Fills are deduplicated by symbol and trade id, so both sources can be used together.
+// Strategy tags come from TagOrder or TagResolver, which gets the client order id of stream fills.
+// Funding is paid per account, it is recorded untagged and every funding update is recorded as it arrives.
+// Realized pnl is in the margin asset of the symbol, see MarginAssets.
+type TradeLedger struct {
+	TagResolver func(clientOrderId string) string
+	// Symbol to margin asset, DefaultLedgerMarginAsset when missing. BTC for BTCUSD_PERP on Coin-M.
+	MarginAssets map[string]string
+	Logger       *logrus.Logger
+
+	mu        sync.RWMutex
+	entries   []LedgerEntry
+	seen      map[string]bool
+	orderTags map[int64]string
+	positions map[string]*LedgerPosition
+	file      *os.File
+}
+
+// NewTradeLedger replays the ledger file when it exists and appends new entries to it.
+// Empty path keeps the ledger in memory only.
+func NewTradeLedger(path string) (*TradeLedger, error) {
+	tl := &TradeLedger{
+		MarginAssets: make(map[string]string),
+		Logger:       logrus.New(),
+		seen:         make(map[string]bool),
+		orderTags:    make(map[int64]string),
+		positions:    make(map[string]*LedgerPosition),
+	}
+	if path == "" {
+		return tl, nil
+	}
+	if err := tl.replay(path); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return nil, err
+	}
+	tl.file = file
+	return tl, nil
+}
+
+func (tl *TradeLedger) PrepareLoggers() {
+	tl.Logger = logrus.New()
+	tl.Logger.Formatter = new(logrus.JSONFormatter)
+
+	ledgerLogs, err := os.OpenFile("logs/binance_ledger.log", os.O_CREATE|os.O_WRONLY, 0666)
+	if err == nil {
+		tl.Logger.SetOutput(ledgerLogs)
+	} else {
+		fmt.Println("Failed to log to file for trade ledger, using default stderr")
+	}
+}
+
+func (tl *TradeLedger) replay(path string) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		entry := LedgerEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return err
+		}
+		tl.apply(entry)
+	}
+	return scanner.Err()
+}
+
+func (tl *TradeLedger) Close() error {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	if tl.file == nil {
+		return nil
+	}
+	err := tl.file.Close()
+	tl.file = nil
+	return err
+}
+
+// TagOrder assigns a strategy tag to an order, fills of the order recorded later get the tag.
+func (tl *TradeLedger) TagOrder(orderId int64, tag string) {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	tl.orderTags[orderId] = tag
+}
+
+func (tl *TradeLedger) marginAsset(symbol string) string {
+	if asset, exists := tl.MarginAssets[symbol]; exists {
+		return asset
+	}
+	return DefaultLedgerMarginAsset
+}
+
+// entryKey empty for funding, payments of several positions or assets can share a time and symbol
+func entryKey(entry LedgerEntry) string {
+	if entry.Kind == LedgerEntryFill {
+		return fmt.Sprintf("%s-%s-%d", entry.Kind, entry.Symbol, entry.TradeId)
+	}
+	return ""
+}
+
+func ledgerPositionKey(symbol, tag string) string {
+	return symbol + "_" + tag
+}
+
+// apply adds the entry to memory only, returns false for duplicates
+func (tl *TradeLedger) apply(entry LedgerEntry) bool {
+	if key := entryKey(entry); key != "" {
+		if tl.seen[key] {
+			return false
+		}
+		tl.seen[key] = true
+	}
+	tl.entries = append(tl.entries, entry)
+	if entry.OrderId != 0 && entry.Tag != "" {
+		tl.orderTags[entry.OrderId] = entry.Tag
+	}
+	if entry.Kind != LedgerEntryFill {
+		return true
+	}
+
+	positionKey := ledgerPositionKey(entry.Symbol, entry.Tag)
+	position, exists := tl.positions[positionKey]
+	if !exists {
+		position = &LedgerPosition{Symbol: entry.Symbol, Tag: entry.Tag}
+		tl.positions[positionKey] = position
+	}
+	position.RealizedPnl += entry.RealizedPnl
+	signed := entry.Quantity
+	if entry.Side == SideSell {
+		signed = -entry.Quantity
+	}
+	switch {
+	case position.Quantity == 0 || (position.Quantity > 0) == (signed > 0):
+		total := position.Quantity + signed
+		position.CostBasis = (position.CostBasis*math.Abs(position.Quantity) + entry.Price*entry.Quantity) / math.Abs(total)
+		position.Quantity = total
+	case math.Abs(signed) > math.Abs(position.Quantity):
+		// Flipped, the rest is a new position at fill price
+		position.Quantity += signed
+		position.CostBasis = entry.Price
+	default:
+		position.Quantity += signed
+		if math.Abs(position.Quantity) < 1e-12 {
+			position.Quantity, position.CostBasis = 0, 0
+		}
+	}
+	return true
+}
+
+// record applies the entry and appends it to the file, lock must be held
+func (tl *TradeLedger) record(entry LedgerEntry) error {
+	if !tl.apply(entry) {
+		return nil
+	}
+	tl.Logger.Info("ledger ", entry.Kind, " ", entry.Symbol, " ", entry.Tag, " pnl ", entry.RealizedPnl,
+		" commission ", entry.Commission, " ", entry.CommissionAsset, " funding ", entry.Funding)
+	if tl.file == nil {
+		return nil
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = tl.file.Write(append(data, '\n'))
+	return err
+}
+
+func (tl *TradeLedger) tagFor(orderId int64, clientOrderId string) string {
+	if tag, exists := tl.orderTags[orderId]; exists {
+		return tag
+	}
+	if tl.TagResolver != nil && clientOrderId != "" {
+		return tl.TagResolver(clientOrderId)
+	}
+	return ""
+}
+
+// HandleMessage records TRADE executions of ORDER_TRADE_UPDATE and FUNDING_FEE account updates.
+func (tl *TradeLedger) HandleMessage(message []byte) error {
+	meta, err := ParseMetaInformation(message)
+	if err != nil {
+		return err
+	}
+	switch meta.Event {
+	case models.EventOrder:
+		update := new(models.StreamOrderUpdate)
+		if err := json.Unmarshal(message, update); err != nil {
+			return err
+		}
+		return tl.HandleOrderUpdate(update.OrderInformation)
+	case models.EventAccount:
+		update := new(models.StreamAccountUpdate)
+		if err := json.Unmarshal(message, update); err != nil {
+			return err
+		}
+		return tl.HandleAccountUpdate(update)
+	}
+	return nil
+}
+
+func (tl *TradeLedger) HandleOrderUpdate(order models.StreamOrder) error {
+	if order.ExecutionType != executionTypeTrade || order.LastFilledQuantity == 0 {
+		return nil
+	}
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	return tl.record(LedgerEntry{
+		Kind:            LedgerEntryFill,
+		Time:            order.TradeTime,
+		Symbol:          order.Symbol,
+		Tag:             tl.tagFor(order.OrderId, order.ClientId),
+		OrderId:         order.OrderId,
+		TradeId:         order.TradeId,
+		Side:            order.Side,
+		Price:           order.LastFilledPrice,
+		Quantity:        order.LastFilledQuantity,
+		RealizedPnl:     order.RealizedProfit,
+		Commission:      order.Commission,
+		CommissionAsset: order.CommissionAsset,
+		Asset:           tl.marginAsset(order.Symbol),
+		Maker:           order.IsMaker,
+	})
+}
+
+// HandleAccountUpdate funding is attributed to the symbol only when the update carries exactly one position.
+func (tl *TradeLedger) HandleAccountUpdate(update *models.StreamAccountUpdate) error {
+	if update.UpdateData.Reason != models.ReasonFunding {
+		return nil
+	}
+	symbol := ""
+	if len(update.UpdateData.Positions) == 1 {
+		symbol = update.UpdateData.Positions[0].Symbol
+	}
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	for _, balance := range update.UpdateData.Balances {
+		if balance.BalanceChange == 0 {
+			continue
+		}
+		err := tl.record(LedgerEntry{
+			Kind:    LedgerEntryFunding,
+			Time:    update.TransactionTime,
+			Symbol:  symbol,
+			Funding: balance.BalanceChange,
+			Asset:   balance.Asset,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadTrades records fills from GetTradeList between startTime and endTime, endTime 0 means up to now.
+// Binance answers at most limit trades per call for at most seven days, so the range is walked in
+// seven day windows and every window is paged by time from the last trade seen. Fills recorded
+// already, from the stream or the overlap of two pages, are skipped. limit 0 uses MaxTradeListLimit.
+func (tl *TradeLedger) LoadTrades(api BinanceFutures, symbol string, startTime, endTime int64, limit int) error {
+	if startTime <= 0 {
+		return ErrStartTimeRequired
+	}
+	if endTime <= 0 {
+		endTime = time.Now().UnixNano() / int64(time.Millisecond)
+	}
+	if limit <= 0 || limit > MaxTradeListLimit {
+		limit = MaxTradeListLimit
+	}
+	for startTime <= endTime {
+		windowEnd := startTime + tradeListWindow - 1
+		if windowEnd > endTime {
+			windowEnd = endTime
+		}
+		data, err := api.GetTradeList(symbol, strconv.FormatInt(startTime, 10), strconv.FormatInt(windowEnd, 10), strconv.Itoa(limit))
+		if err != nil {
+			return err
+		}
+		trades := make(models.AccountTrades, 0)
+		if err := json.Unmarshal(data, &trades); err != nil {
+			return err
+		}
+		if err := tl.recordTrades(trades); err != nil {
+			return err
+		}
+		if len(trades) < limit {
+			startTime = windowEnd + 1
+			continue
+		}
+
+		last := trades[len(trades)-1].Time
+		if trades[0].Time == last {
+			// Whole page shares one timestamp, moving on is the only way out
+			startTime = last + 1
+			continue
+		}
+		startTime = last
+	}
+	return nil
+}
+
+func (tl *TradeLedger) recordTrades(trades models.AccountTrades) error {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	for _, trade := range trades {
+		err := tl.record(LedgerEntry{
+			Kind:            LedgerEntryFill,
+			Time:            trade.Time,
+			Symbol:          trade.Symbol,
+			Tag:             tl.tagFor(trade.OrderId, ""),
+			OrderId:         trade.OrderId,
+			TradeId:         trade.Id,
+			Side:            trade.Side,
+			Price:           trade.Price,
+			Quantity:        trade.Quantity,
+			RealizedPnl:     trade.RealizedPnl,
+			Commission:      trade.Commission,
+			CommissionAsset: trade.CommissionAsset,
+			Asset:           tl.marginAsset(trade.Symbol),
+			Maker:           trade.Maker,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tl *TradeLedger) Entries() []LedgerEntry {
+	tl.mu.RLock()
+	defer tl.mu.RUnlock()
+	entries := make([]LedgerEntry, len(tl.entries))
+	copy(entries, tl.entries)
+	return entries
+}
+
+func (tl *TradeLedger) Position(symbol, tag string) (LedgerPosition, bool) {
+	tl.mu.RLock()
+	defer tl.mu.RUnlock()
+	position, exists := tl.positions[ledgerPositionKey(symbol, tag)]
+	if !exists {
+		return LedgerPosition{}, false
+	}
+	return *position, true
+}
+
+func (tl *TradeLedger) Positions() []LedgerPosition {
+	tl.mu.RLock()
+	defer tl.mu.RUnlock()
+	positions := make([]LedgerPosition, 0, len(tl.positions))
+	for _, position := range tl.positions {
+		positions = append(positions, *position)
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		return ledgerPositionKey(positions[i].Symbol, positions[i].Tag) < ledgerPositionKey(positions[j].Symbol, positions[j].Tag)
+	})
+	return positions
+}
+
+// Summary running totals, empty symbol or tag matches everything.
+// Funding is untagged, a tag filter leaves it out.
+func (tl *TradeLedger) Summary(symbol, tag string) LedgerSummary {
+	tl.mu.RLock()
+	defer tl.mu.RUnlock()
+	summary := newLedgerSummary()
+	for _, entry := range tl.entries {
+		if (symbol == "" || entry.Symbol == symbol) && (tag == "" || entry.Tag == tag) {
+			summary.add(entry)
+		}
+	}
+	return summary
+}
+
+// DailySummaries totals per UTC day, keyed as 2006-01-02.
+func (tl *TradeLedger) DailySummaries(symbol, tag string) map[string]LedgerSummary {
+	tl.mu.RLock()
+	defer tl.mu.RUnlock()
+	days := make(map[string]LedgerSummary)
+	for _, entry := range tl.entries {
+		if (symbol != "" && entry.Symbol != symbol) || (tag != "" && entry.Tag != tag) {
+			continue
+		}
+		day := time.Unix(0, entry.Time*int64(time.Millisecond)).UTC().Format(ledgerDayLayout)
+		summary, exists := days[day]
+		if !exists {
+			summary = newLedgerSummary()
+		}
+		summary.add(entry)
+		days[day] = summary
+	}
+	return days
+}
+
+// Reconcile compares realized pnl, commission and funding of the ledger between startTime and endTime
+// with income history. Differences point to fills or funding missed by the ledger, e.g. while disconnected.
+func (tl *TradeLedger) Reconcile(api BinanceFutures, startTime, endTime int64) ([]LedgerReconciliation, error) {
+	if endTime == 0 {
+		endTime = time.Now().UnixNano() / int64(time.Millisecond)
+	}
+	totals := make(map[string]*LedgerReconciliation)
+	total := func(incomeType, asset string) *LedgerReconciliation {
+		key := incomeType + "_" + asset
+		if _, exists := totals[key]; !exists {
+			totals[key] = &LedgerReconciliation{IncomeType: incomeType, Asset: asset}
+		}
+		return totals[key]
+	}
+
+	for _, incomeType := range []string{IncomeTypeRealizedPnl, IncomeTypeCommission, IncomeTypeFundingFee} {
+		history, err := GetAllIncomeHistory(api, "", incomeType, startTime, endTime)
+		if err != nil {
+			return nil, err
+		}
+		for _, income := range history {
+			total(income.IncomeType, income.Asset).Exchange += income.Income
+		}
+	}
+
+	tl.mu.RLock()
+	for _, entry := range tl.entries {
+		if entry.Time < startTime || entry.Time > endTime {
+			continue
+		}
+		switch entry.Kind {
+		case LedgerEntryFill:
+			if entry.RealizedPnl != 0 {
+				total(IncomeTypeRealizedPnl, entry.Asset).Ledger += entry.RealizedPnl
+			}
+			if entry.Commission != 0 {
+				total(IncomeTypeCommission, entry.CommissionAsset).Ledger -= entry.Commission
+			}
+		case LedgerEntryFunding:
+			total(IncomeTypeFundingFee, entry.Asset).Ledger += entry.Funding
+		}
+	}
+	tl.mu.RUnlock()
+
+	reconciliations := make([]LedgerReconciliation, 0, len(totals))
+	for _, reconciliation := range totals {
+		reconciliation.Difference = reconciliation.Exchange - reconciliation.Ledger
+		reconciliations = append(reconciliations, *reconciliation)
+	}
+	sort.Slice(reconciliations, func(i, j int) bool {
+		if reconciliations[i].IncomeType != reconciliations[j].IncomeType {
+			return reconciliations[i].IncomeType < reconciliations[j].IncomeType
+		}
+		return reconciliations[i].Asset < reconciliations[j].Asset
+	})
+	return reconciliations, nil
+}
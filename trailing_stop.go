@@ -0,0 +1,445 @@
+package go_binance
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/redlon23/go-binance/models"
+	"github.com/sirupsen/logrus"
+	"math"
+	"os"
+	"sync"
+)
+
+const (
+	TrailAbsolute = "ABSOLUTE"
+	TrailPercent  = "PERCENT"
+	TrailAtr      = "ATR"
+
+	// Engine sends a reduce only market order when the stop is crossed
+	TrailActionMarket = "MARKET"
+	// Engine keeps a resting STOP_MARKET order on the exchange and replaces it as the stop moves
+	TrailActionStopMarket = "STOP_MARKET"
+
+	TrailStateWaiting   = "WAITING"
+	TrailStateTrailing  = "TRAILING"
+	TrailStateTriggered = "TRIGGERED"
+	TrailStateCanceled  = "CANCELED"
+	TrailStateFailed    = "FAILED"
+)
+
+// TrailingStopConfig PositionSide is the side of the position being protected, SideBuy for long.
+// Distance is a price difference for TrailAbsolute, a percentage (1 = 1%) for TrailPercent
+// and a multiplier of the symbol ATR for TrailAtr.
+// ActivationPrice 0 starts trailing immediately.
+// For TrailActionStopMarket the resting order is only replaced when the stop moved at least AmendThreshold.
+type TrailingStopConfig struct {
+	Symbol          string
+	PositionSide    string
+	Quantity        float64
+	Mode            string
+	Distance        float64
+	ActivationPrice float64
+	Action          string
+	TickSize        float64
+	AmendThreshold  float64
+}
+
+// TrailingStop state of a single stop, returned as a copy for monitoring.
+type TrailingStop struct {
+	Id            int64
+	Config        TrailingStopConfig
+	State         string
+	LastPrice     float64
+	HighWaterMark float64
+	StopPrice     float64
+	// Stop price of the resting order on the exchange, TrailActionStopMarket only
+	OrderStopPrice float64
+	Order          *ManagedOrder
+	// Last error, placements which failed are tried again with the next price
+	Err error
+	// Orders replaced by a newer stop whose cancel was not confirmed
+	replaced []*replacedStopOrder
+}
+
+type replacedStopOrder struct {
+	order *ManagedOrder
+	// Binance answered the cancel with -2011 or -2013, its update tells whether it triggered
+	notOpen bool
+}
+
+func (ts TrailingStop) isLong() bool {
+	return ts.Config.PositionSide == SideBuy
+}
+
+func (ts TrailingStop) exitSide() string {
+	if ts.isLong() {
+		return SideSell
+	}
+	return SideBuy
+}
+
+// TrailingStopEngine client side trailing stops following mark price or book ticker streams.
+// Feed price messages and ORDER_TRADE_UPDATE events through HandleMessage.
+type TrailingStopEngine struct {
+	Api    BinanceFutures
+	Logger *logrus.Logger
+
+	mu      sync.Mutex
+	idCount int64
+	stops   map[int64]*TrailingStop
+	atr     map[string]float64
+}
+
+func NewTrailingStopEngine(api BinanceFutures) *TrailingStopEngine {
+	return &TrailingStopEngine{
+		Api:    api,
+		Logger: logrus.New(),
+		stops:  make(map[int64]*TrailingStop),
+		atr:    make(map[string]float64),
+	}
+}
+
+func (tse *TrailingStopEngine) PrepareLoggers() {
+	tse.Logger = logrus.New()
+	tse.Logger.Formatter = new(logrus.JSONFormatter)
+
+	trailLogs, err := os.OpenFile("logs/binance_trailing_stop.log", os.O_CREATE|os.O_WRONLY, 0666)
+	if err == nil {
+		tse.Logger.SetOutput(trailLogs)
+	} else {
+		fmt.Println("Failed to log to file for trailing stops, using default stderr")
+	}
+}
+
+// CalculateAtr average true range of the last period frames.
+func CalculateAtr(klines models.Klines, period int) float64 {
+	if period <= 0 || len(klines) < period+1 {
+		return 0
+	}
+	total := 0.0
+	for i := len(klines) - period; i < len(klines); i++ {
+		previousClose := klines[i-1].Close
+		trueRange := math.Max(klines[i].High-klines[i].Low,
+			math.Max(math.Abs(klines[i].High-previousClose), math.Abs(klines[i].Low-previousClose)))
+		total += trueRange
+	}
+	return total / float64(period)
+}
+
+// SetAtr overrides the ATR used for TrailAtr stops of the symbol.
+func (tse *TrailingStopEngine) SetAtr(symbol string, atr float64) {
+	tse.mu.Lock()
+	defer tse.mu.Unlock()
+	tse.atr[symbol] = atr
+}
+
+// UpdateAtr fetches klines and recalculates the ATR of the symbol, call it once per interval.
+func (tse *TrailingStopEngine) UpdateAtr(symbol, interval string, period int) (float64, error) {
+	data, err := tse.Api.GetKlines(symbol, interval, period+1)
+	if err != nil {
+		return 0, err
+	}
+	klines := make(models.Klines, 0)
+	if err := json.Unmarshal(data, &klines); err != nil {
+		return 0, err
+	}
+	atr := CalculateAtr(klines, period)
+	tse.SetAtr(symbol, atr)
+	return atr, nil
+}
+
+// Add starts tracking a stop, price updates move it from then on.
+func (tse *TrailingStopEngine) Add(config TrailingStopConfig) (TrailingStop, error) {
+	if config.Mode == TrailAtr {
+		tse.mu.Lock()
+		_, exists := tse.atr[config.Symbol]
+		tse.mu.Unlock()
+		if !exists {
+			return TrailingStop{}, fmt.Errorf("no ATR for %s, call UpdateAtr or SetAtr first", config.Symbol)
+		}
+	}
+	if config.Action == "" {
+		config.Action = TrailActionMarket
+	}
+	tse.mu.Lock()
+	defer tse.mu.Unlock()
+	tse.idCount++
+	stop := &TrailingStop{Id: tse.idCount, Config: config, State: TrailStateWaiting}
+	tse.stops[stop.Id] = stop
+	tse.Logger.Info("trailing stop ", stop.Id, " added for ", config.Symbol)
+	return tse.snapshot(stop), nil
+}
+
+// Cancel stops tracking, a resting stop order is cancelled.
+func (tse *TrailingStopEngine) Cancel(id int64) error {
+	tse.mu.Lock()
+	defer tse.mu.Unlock()
+	stop, exists := tse.stops[id]
+	if !exists {
+		return fmt.Errorf("trailing stop %d does not exist", id)
+	}
+	if stop.Order != nil && stop.Order.IsOpen() {
+		if _, err := tse.Api.CancelSingleOrder(stop.Order.Symbol, stop.Order.ClientOrderId, stop.Order.OrderId); err != nil {
+			return err
+		}
+	}
+	stop.State = TrailStateCanceled
+	return nil
+}
+
+func (tse *TrailingStopEngine) Get(id int64) (TrailingStop, bool) {
+	tse.mu.Lock()
+	defer tse.mu.Unlock()
+	stop, exists := tse.stops[id]
+	if !exists {
+		return TrailingStop{}, false
+	}
+	return tse.snapshot(stop), true
+}
+
+func (tse *TrailingStopEngine) Stops() []TrailingStop {
+	tse.mu.Lock()
+	defer tse.mu.Unlock()
+	stops := make([]TrailingStop, 0, len(tse.stops))
+	for _, stop := range tse.stops {
+		stops = append(stops, tse.snapshot(stop))
+	}
+	return stops
+}
+
+func (tse *TrailingStopEngine) snapshot(stop *TrailingStop) TrailingStop {
+	copied := *stop
+	copied.replaced = nil
+	if stop.Order != nil {
+		order := *stop.Order
+		copied.Order = &order
+	}
+	return copied
+}
+
+// HandleMessage accepts markPriceUpdate, bookTicker and ORDER_TRADE_UPDATE messages.
+// For book ticker updates long stops follow the best bid and short stops the best ask.
+func (tse *TrailingStopEngine) HandleMessage(message []byte) error {
+	meta, err := ParseMetaInformation(message)
+	if err != nil {
+		return err
+	}
+	switch meta.Event {
+	case models.EventMarkPrice:
+		update := new(models.MarkPriceUpdate)
+		if err := json.Unmarshal(message, update); err != nil {
+			return err
+		}
+		tse.OnPrice(update.Symbol, update.MarkPrice, update.MarkPrice)
+	case models.EventBookTicker:
+		update := new(models.BookTicker)
+		if err := json.Unmarshal(message, update); err != nil {
+			return err
+		}
+		tse.OnPrice(update.Symbol, update.BestBidPrice, update.BestAskPrice)
+	case models.EventOrder:
+		update := new(models.StreamOrderUpdate)
+		if err := json.Unmarshal(message, update); err != nil {
+			return err
+		}
+		tse.HandleOrderUpdate(*update)
+	}
+	return nil
+}
+
+// OnPrice longPrice is used by long stops, shortPrice by short stops.
+func (tse *TrailingStopEngine) OnPrice(symbol string, longPrice, shortPrice float64) {
+	tse.mu.Lock()
+	defer tse.mu.Unlock()
+	for _, stop := range tse.stops {
+		if stop.Config.Symbol != symbol ||
+			(stop.State != TrailStateWaiting && stop.State != TrailStateTrailing) {
+			continue
+		}
+		if stop.isLong() {
+			tse.move(stop, longPrice)
+		} else {
+			tse.move(stop, shortPrice)
+		}
+	}
+}
+
+func (tse *TrailingStopEngine) distance(stop *TrailingStop, reference float64) float64 {
+	switch stop.Config.Mode {
+	case TrailPercent:
+		return reference * stop.Config.Distance / 100
+	case TrailAtr:
+		return tse.atr[stop.Config.Symbol] * stop.Config.Distance
+	}
+	return stop.Config.Distance
+}
+
+func (tse *TrailingStopEngine) move(stop *TrailingStop, price float64) {
+	if price == 0 {
+		return
+	}
+	stop.LastPrice = price
+	long := stop.isLong()
+	if stop.State == TrailStateWaiting {
+		activation := stop.Config.ActivationPrice
+		if activation != 0 && ((long && price < activation) || (!long && price > activation)) {
+			return
+		}
+		stop.State = TrailStateTrailing
+		stop.HighWaterMark = price
+		tse.Logger.Info("trailing stop ", stop.Id, " activated at ", price)
+	}
+
+	if (long && price > stop.HighWaterMark) || (!long && price < stop.HighWaterMark) {
+		stop.HighWaterMark = price
+	}
+	var candidate float64
+	if long {
+		candidate = RoundDownToStep(stop.HighWaterMark-tse.distance(stop, stop.HighWaterMark), stop.Config.TickSize)
+	} else {
+		candidate = RoundUpToStep(stop.HighWaterMark+tse.distance(stop, stop.HighWaterMark), stop.Config.TickSize)
+	}
+	// Stop only moves in favour of the position
+	if stop.StopPrice == 0 || (long && candidate > stop.StopPrice) || (!long && candidate < stop.StopPrice) {
+		stop.StopPrice = candidate
+	}
+
+	if stop.Config.Action == TrailActionStopMarket {
+		tse.amend(stop)
+		return
+	}
+	if (long && price <= stop.StopPrice) || (!long && price >= stop.StopPrice) {
+		tse.trigger(stop)
+	}
+}
+
+func (tse *TrailingStopEngine) trigger(stop *TrailingStop) {
+	data, err := tse.Api.PlaceMarketOrder(stop.Config.Symbol, stop.exitSide(), stop.Config.Quantity, true)
+	if err != nil {
+		tse.retryLater(stop, err)
+		return
+	}
+	response := new(models.OrderResponse)
+	if err := json.Unmarshal(data, response); err != nil {
+		tse.fail(stop, err)
+		return
+	}
+	stop.Order = managedOrderFromResponse(*response)
+	stop.State = TrailStateTriggered
+	tse.Logger.Info("trailing stop ", stop.Id, " triggered at ", stop.LastPrice, " stop ", stop.StopPrice)
+}
+
+// amend replaces the resting stop market order, binance does not modify stop orders in place.
+// The new order is placed before the old one is cancelled, so the position always has a stop.
+func (tse *TrailingStopEngine) amend(stop *TrailingStop) {
+	tse.cancelReplaced(stop)
+	resting := stop.Order != nil && stop.Order.IsOpen()
+	if resting &&
+		math.Abs(stop.StopPrice-stop.OrderStopPrice) < math.Max(stop.Config.AmendThreshold, stop.Config.TickSize/2) {
+		return
+	}
+	data, err := tse.Api.PlaceStopMarketOrder(stop.Config.Symbol, stop.exitSide(), stop.StopPrice, stop.Config.Quantity)
+	if err != nil {
+		tse.retryLater(stop, err)
+		return
+	}
+	response := new(models.OrderResponse)
+	if err := json.Unmarshal(data, response); err != nil {
+		tse.fail(stop, err)
+		return
+	}
+	if resting {
+		stop.replaced = append(stop.replaced, &replacedStopOrder{order: stop.Order})
+	}
+	stop.Order = managedOrderFromResponse(*response)
+	stop.OrderStopPrice = stop.StopPrice
+	stop.Err = nil
+	tse.Logger.Info("trailing stop ", stop.Id, " order moved to ", stop.StopPrice)
+	tse.cancelReplaced(stop)
+}
+
+// cancelReplaced cancels replaced orders, failed cancels are tried again on the next amend.
+// Orders which are not open anymore are kept until their update arrives, a fill triggers the stop.
+func (tse *TrailingStopEngine) cancelReplaced(stop *TrailingStop) {
+	remaining := stop.replaced[:0]
+	for _, replaced := range stop.replaced {
+		if replaced.notOpen {
+			remaining = append(remaining, replaced)
+			continue
+		}
+		order := replaced.order
+		_, err := tse.Api.CancelSingleOrder(order.Symbol, order.ClientOrderId, order.OrderId)
+		switch {
+		case err == nil:
+			continue
+		case isOrderNotOpen(err):
+			tse.Logger.Warn("trailing stop ", stop.Id, " replaced order ", order.ClientOrderId, " is not open anymore")
+			replaced.notOpen = true
+		default:
+			tse.Logger.Error("trailing stop ", stop.Id, " could not cancel replaced order ", order.ClientOrderId, " ", err)
+		}
+		remaining = append(remaining, replaced)
+	}
+	stop.replaced = remaining
+}
+
+// HandleOrderUpdate marks TrailActionStopMarket stops as triggered once their order fills,
+// a replaced order which triggered before its cancel counts as well.
+func (tse *TrailingStopEngine) HandleOrderUpdate(update models.StreamOrderUpdate) {
+	tse.mu.Lock()
+	defer tse.mu.Unlock()
+	order := managedOrderFromUpdate(update)
+	for _, stop := range tse.stops {
+		if stop.Order != nil && stop.Order.ClientOrderId == order.ClientOrderId && isNewerOrderState(stop.Order, order) {
+			order.Acknowledged = true
+			*stop.Order = *order
+			if order.Status == models.OrderStatusFilled && stop.State == TrailStateTrailing {
+				stop.State = TrailStateTriggered
+				tse.Logger.Info("trailing stop ", stop.Id, " stop order filled")
+			}
+		}
+		tse.applyReplaced(stop, order)
+	}
+}
+
+func (tse *TrailingStopEngine) applyReplaced(stop *TrailingStop, order *ManagedOrder) {
+	remaining := stop.replaced[:0]
+	for _, replaced := range stop.replaced {
+		if replaced.order.ClientOrderId != order.ClientOrderId {
+			remaining = append(remaining, replaced)
+			continue
+		}
+		if !isNewerOrderState(replaced.order, order) {
+			remaining = append(remaining, replaced)
+			continue
+		}
+		*replaced.order = *order
+		if order.IsOpen() {
+			remaining = append(remaining, replaced)
+			continue
+		}
+		if order.Status == models.OrderStatusFilled && stop.State == TrailStateTrailing {
+			stop.State = TrailStateTriggered
+			tse.Logger.Info("trailing stop ", stop.Id, " replaced stop order filled before its cancel")
+			if stop.Order != nil && stop.Order.IsOpen() {
+				_, err := tse.Api.CancelSingleOrder(stop.Order.Symbol, stop.Order.ClientOrderId, stop.Order.OrderId)
+				if err != nil && !isOrderNotOpen(err) {
+					tse.Logger.Error("trailing stop ", stop.Id, " could not cancel stop order ", err)
+				}
+			}
+		}
+	}
+	stop.replaced = remaining
+}
+
+// retryLater keeps the stop trailing, the next price update places the order again
+func (tse *TrailingStopEngine) retryLater(stop *TrailingStop, err error) {
+	tse.Logger.Error("trailing stop ", stop.Id, " order failed, retrying with the next price ", err)
+	stop.Err = err
+}
+
+func (tse *TrailingStopEngine) fail(stop *TrailingStop, err error) {
+	tse.Logger.Error("trailing stop ", stop.Id, " failed ", err)
+	stop.Err = err
+	stop.State = TrailStateFailed
+}
@@ -0,0 +1,309 @@
+package go_binance
+
+import (
+	"errors"
+	"fmt"
+	"github.com/redlon23/go-binance/models"
+	"github.com/sirupsen/logrus"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	UserStreamEventConnect   = "CONNECT"
+	UserStreamEventGap       = "GAP"
+	UserStreamEventKeepAlive = "KEEPALIVE"
+
+	// Listen keys expire 60 minutes after the last keepalive
+	DefaultUserStreamKeepAlive = 30 * time.Minute
+)
+
+// UserStreamEvent lifecycle event of a UserStreamManager. A GAP covers From until To, events of
+// that period were missed and orders, positions and balances should be reconciled over REST.
+// Err is the cause of the gap or the failed keepalive, nil for a successful keepalive.
+type UserStreamEvent struct {
+	Type      string
+	Time      time.Time
+	ListenKey string
+	From      time.Time
+	To        time.Time
+	Err       error
+}
+
+// UserStreamManager owns the listen key of a user data stream: it is created on Start, kept
+// alive every KeepAliveInterval and closed with DeleteUserStream by CloseConnection.
+// A listenKeyExpired event, a rejected keepalive or a failing read get a new key and redial,
+// then a GAP event is emitted. Wrap a plain socket, reconnects are handled here.
+type UserStreamManager struct {
+	BinanceFutureSocket
+	Api               BinanceFutures
+	KeepAliveInterval time.Duration
+	MinReconnectWait  time.Duration
+	MaxReconnectWait  time.Duration
+	// 0 retries forever
+	MaxAttempts int
+	Logger      *logrus.Logger
+
+	// connMu orders dials and closes of the wrapped socket by Start, the reader and the keepalive
+	connMu      sync.Mutex
+	mu          sync.Mutex
+	listenKey   string
+	lastMessage time.Time
+	closed      bool
+	stop        chan struct{}
+	generation  int
+	listeners   []func(UserStreamEvent)
+}
+
+func NewUserStreamManager(api BinanceFutures, socket BinanceFutureSocket) *UserStreamManager {
+	return &UserStreamManager{
+		BinanceFutureSocket: socket,
+		Api:                 api,
+		KeepAliveInterval:   DefaultUserStreamKeepAlive,
+		MinReconnectWait:    DefaultReconnectMinWait,
+		MaxReconnectWait:    DefaultReconnectMaxWait,
+		Logger:              logrus.New(),
+	}
+}
+
+// PrepareLoggers prepares loggers of the wrapped socket as well.
+func (usm *UserStreamManager) PrepareLoggers() {
+	usm.BinanceFutureSocket.PrepareLoggers()
+	usm.Logger = logrus.New()
+	usm.Logger.Formatter = new(logrus.JSONFormatter)
+
+	userStreamLogs, err := os.OpenFile("logs/binance_user_stream.log", os.O_CREATE|os.O_WRONLY, 0666)
+	if err == nil {
+		usm.Logger.SetOutput(userStreamLogs)
+	} else {
+		fmt.Println("Failed to log to file for user stream manager, using default stderr")
+	}
+}
+
+// Subscribe registers a listener for lifecycle events. GAP is emitted on the reading goroutine,
+// KEEPALIVE on the keepalive goroutine.
+func (usm *UserStreamManager) Subscribe(listener func(UserStreamEvent)) {
+	usm.mu.Lock()
+	defer usm.mu.Unlock()
+	usm.listeners = append(usm.listeners, listener)
+}
+
+func (usm *UserStreamManager) emit(event UserStreamEvent) {
+	event.Time = time.Now()
+	usm.mu.Lock()
+	listeners := make([]func(UserStreamEvent), len(usm.listeners))
+	copy(listeners, usm.listeners)
+	usm.mu.Unlock()
+	for _, listener := range listeners {
+		listener(event)
+	}
+}
+
+func (usm *UserStreamManager) ListenKey() string {
+	usm.mu.Lock()
+	defer usm.mu.Unlock()
+	return usm.listenKey
+}
+
+// Start creates a listen key, opens the user stream and starts the keepalive.
+// Calling it again replaces the running stream, its keepalive stops and its connection is closed.
+func (usm *UserStreamManager) Start() error {
+	usm.mu.Lock()
+	running := usm.stop != nil && !usm.closed
+	if running {
+		close(usm.stop)
+		usm.stop = nil
+	}
+	usm.mu.Unlock()
+
+	listenKey, err := usm.newListenKey()
+	if err != nil {
+		return err
+	}
+	usm.connMu.Lock()
+	if running {
+		_ = usm.BinanceFutureSocket.CloseConnection()
+	}
+	if err := usm.BinanceFutureSocket.OpenWebSocketConnectionWithUserStream(listenKey); err != nil {
+		usm.connMu.Unlock()
+		return err
+	}
+	usm.mu.Lock()
+	usm.listenKey, usm.lastMessage, usm.closed = listenKey, time.Now(), false
+	usm.generation++
+	usm.stop = make(chan struct{})
+	stop := usm.stop
+	usm.mu.Unlock()
+	usm.connMu.Unlock()
+	go usm.keepAlive(stop)
+	usm.emit(UserStreamEvent{Type: UserStreamEventConnect, ListenKey: listenKey})
+	return nil
+}
+
+// OpenWebSocketConnectionWithUserStream the given key is ignored, the manager creates its own.
+func (usm *UserStreamManager) OpenWebSocketConnectionWithUserStream(listenKey string) error {
+	return usm.Start()
+}
+
+func (usm *UserStreamManager) newListenKey() (string, error) {
+	data, err := usm.Api.GetUserStreamKey()
+	if err != nil {
+		return "", err
+	}
+	listenKey := GetKey(data)
+	if listenKey == "" {
+		return "", errors.New("no listen key in response: " + string(data))
+	}
+	return listenKey, nil
+}
+
+func (usm *UserStreamManager) keepAlive(stop chan struct{}) {
+	ticker := time.NewTicker(usm.KeepAliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		usm.mu.Lock()
+		listenKey, generation := usm.listenKey, usm.generation
+		usm.mu.Unlock()
+		_, err := usm.Api.UpdateKeepAliveUserStream()
+		usm.emit(UserStreamEvent{Type: UserStreamEventKeepAlive, ListenKey: listenKey, Err: err})
+		if err == nil {
+			continue
+		}
+		var requestError *RequestError
+		if errors.As(err, &requestError) && requestError.Message.Code == ListenKeyDoesNotExist {
+			// Closing makes the blocked read recover with a new key
+			usm.Logger.Warn("listen key does not exist anymore, renewing: ", err)
+			usm.closeGeneration(generation)
+		} else {
+			usm.Logger.Error("keepalive failed, retrying on the next tick: ", err)
+		}
+	}
+}
+
+// closeGeneration closes the connection unless it was redialed or closed since generation
+func (usm *UserStreamManager) closeGeneration(generation int) {
+	usm.connMu.Lock()
+	defer usm.connMu.Unlock()
+	usm.mu.Lock()
+	current := usm.generation == generation && !usm.closed
+	usm.mu.Unlock()
+	if current {
+		_ = usm.BinanceFutureSocket.CloseConnection()
+	}
+}
+
+// ReadFromConnection recovers from listenKeyExpired and read errors before returning.
+// The listenKeyExpired message itself is returned, the next read is on the new connection.
+func (usm *UserStreamManager) ReadFromConnection() (messageType int, p []byte, err error) {
+	for {
+		usm.mu.Lock()
+		generation := usm.generation
+		usm.mu.Unlock()
+		messageType, p, err = usm.BinanceFutureSocket.ReadFromConnection()
+		usm.mu.Lock()
+		closed, from := usm.closed, usm.lastMessage
+		if err == nil {
+			usm.lastMessage = time.Now()
+		}
+		usm.mu.Unlock()
+		if closed {
+			return messageType, p, err
+		}
+		if err != nil {
+			usm.Logger.Warn("user stream read failed, reconnecting: ", err)
+			if recoverErr := usm.recover(from, err, generation); recoverErr != nil {
+				return -1, nil, recoverErr
+			}
+			continue
+		}
+		if meta, metaErr := ParseMetaInformation(p); metaErr == nil && meta.Event == models.EventListenKeyExpired {
+			usm.Logger.Warn("listen key expired, renewing")
+			if recoverErr := usm.recover(from, errors.New("listen key expired"), generation); recoverErr != nil {
+				return -1, nil, recoverErr
+			}
+		}
+		return messageType, p, nil
+	}
+}
+
+// recover gets a new listen key and redials with backoff. A key which is still valid is
+// returned again by binance, so the same call covers expiry and plain disconnects.
+// A connection dialed by Start since generation is kept and read from instead.
+func (usm *UserStreamManager) recover(from time.Time, cause error, generation int) error {
+	dropped := false
+	for attempt := 1; usm.MaxAttempts == 0 || attempt <= usm.MaxAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(reconnectBackoff(usm.MinReconnectWait, usm.MaxReconnectWait, attempt))
+		}
+		listenKey, err := usm.newListenKey()
+		if err != nil {
+			usm.Logger.Error("could not get a listen key, attempt ", attempt, ": ", err)
+		}
+
+		usm.connMu.Lock()
+		usm.mu.Lock()
+		closed, redialed := usm.closed, usm.generation != generation
+		usm.mu.Unlock()
+		if closed || redialed {
+			usm.connMu.Unlock()
+			if closed {
+				return ErrConnectionClosed
+			}
+			return nil
+		}
+		if !dropped {
+			_ = usm.BinanceFutureSocket.CloseConnection()
+			dropped = true
+		}
+		if err != nil {
+			usm.connMu.Unlock()
+			continue
+		}
+		if err := usm.BinanceFutureSocket.OpenWebSocketConnectionWithUserStream(listenKey); err != nil {
+			usm.connMu.Unlock()
+			usm.Logger.Error("user stream reconnect attempt ", attempt, " failed: ", err)
+			continue
+		}
+		now := time.Now()
+		usm.mu.Lock()
+		usm.listenKey, usm.lastMessage = listenKey, now
+		usm.generation++
+		usm.mu.Unlock()
+		usm.connMu.Unlock()
+		usm.Logger.Info("user stream reconnected after ", attempt, " attempts")
+		usm.emit(UserStreamEvent{Type: UserStreamEventGap, ListenKey: listenKey, From: from, To: now, Err: cause})
+		return nil
+	}
+	return fmt.Errorf("user stream could not reconnect after %d attempts", usm.MaxAttempts)
+}
+
+// CloseConnection stops the keepalive, closes the connection and deletes the listen key.
+func (usm *UserStreamManager) CloseConnection() error {
+	usm.mu.Lock()
+	if usm.closed {
+		usm.mu.Unlock()
+		return nil
+	}
+	usm.closed = true
+	if usm.stop != nil {
+		close(usm.stop)
+	}
+	usm.mu.Unlock()
+
+	usm.connMu.Lock()
+	err := usm.BinanceFutureSocket.CloseConnection()
+	usm.connMu.Unlock()
+	if _, deleteErr := usm.Api.DeleteUserStream(); deleteErr != nil {
+		usm.Logger.Error("could not delete listen key: ", deleteErr)
+		if err == nil {
+			err = deleteErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,361 @@
+package go_binance
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"github.com/redlon23/go-binance/models"
+	"github.com/sirupsen/logrus"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	wsApiMainNetURL = "wss://ws-fapi.binance.com/ws-fapi/v1"
+	wsApiTestNetURL = "wss://testnet.binancefuture.com/ws-fapi/v1"
+
+	WsApiMethodOrderPlace      = "order.place"
+	WsApiMethodOrderModify     = "order.modify"
+	WsApiMethodOrderCancel     = "order.cancel"
+	WsApiMethodOrderStatus     = "order.status"
+	WsApiMethodAccountStatus   = "v2/account.status"
+	WsApiMethodAccountBalance  = "v2/account.balance"
+	WsApiMethodAccountPosition = "v2/account.position"
+
+	DefaultWsApiTimeout = 10 * time.Second
+)
+
+// BinanceFuturesWsApi places, modifies, cancels and queries orders over the websocket api, which
+// saves the connection setup of every REST call. Requests are signed like doSignedRequest and
+// answered with the same result as the REST endpoint, so both clients fit OrderTransport.
+// Orders are not risk checked, wrap the client with RiskGuard.GuardTransport.
+// The connection is dialed on the first request and again on the first request after it dropped.
+type BinanceFuturesWsApi struct {
+	BaseUrl   string
+	PublicKey string
+	SecretKey string
+	Logger    *logrus.Logger
+	// OrderMode one of OrderModeLive, OrderModeTest or OrderModeDryRun. There is no test order
+	// method on the websocket api, test orders are signed and answered like dry run orders
+	OrderMode string
+	// ClientOrderIds see BinanceFuturesApi, the constructor installs one tagged DefaultClientOrderIdTag
+	ClientOrderIds *ClientOrderIdGenerator
+	// Timeout for a response, the order status is unknown afterwards
+	Timeout time.Duration
+
+	mu         sync.Mutex
+	writeMu    sync.Mutex
+	connection *websocket.Conn
+	idCounter  int
+	pending    map[int]chan models.WsApiResponse
+	rateLimits []models.RateLimit
+}
+
+func NewBinanceFuturesWsApi() *BinanceFuturesWsApi {
+	return &BinanceFuturesWsApi{
+		BaseUrl:        wsApiMainNetURL,
+		Logger:         logrus.New(),
+		ClientOrderIds: NewClientOrderIdGenerator(DefaultClientOrderIdTag),
+		Timeout:        DefaultWsApiTimeout,
+		pending:        make(map[int]chan models.WsApiResponse),
+	}
+}
+
+func (bfwa *BinanceFuturesWsApi) PrepareLoggers() {
+	bfwa.Logger = logrus.New()
+	bfwa.Logger.Formatter = new(logrus.JSONFormatter)
+
+	wsApiLogs, err := os.OpenFile("logs/binance_ws_api.log", os.O_CREATE|os.O_WRONLY, 0666)
+	if err == nil {
+		bfwa.Logger.SetOutput(wsApiLogs)
+	} else {
+		fmt.Println("Failed to log to file for binance websocket api, using default stderr")
+	}
+}
+
+func (bfwa *BinanceFuturesWsApi) SetApiKeys(public, secret string) {
+	bfwa.PublicKey = public
+	bfwa.SecretKey = secret
+}
+
+// SetOrderMode unknown modes are rejected and the current mode is kept.
+func (bfwa *BinanceFuturesWsApi) SetOrderMode(mode string) error {
+	if err := ValidateOrderMode(mode); err != nil {
+		return err
+	}
+	bfwa.OrderMode = mode
+	return nil
+}
+
+// isLive order, modify and cancel requests are sent
+func (bfwa *BinanceFuturesWsApi) isLive() bool {
+	return isLiveOrderMode(bfwa.OrderMode)
+}
+
+func (bfwa *BinanceFuturesWsApi) UseMainNet() {
+	bfwa.BaseUrl = wsApiMainNetURL
+}
+
+func (bfwa *BinanceFuturesWsApi) UseTestNet() {
+	bfwa.BaseUrl = wsApiTestNetURL
+}
+
+// Connect dials the websocket api, requests connect on their own when there is no connection.
+func (bfwa *BinanceFuturesWsApi) Connect() error {
+	bfwa.mu.Lock()
+	defer bfwa.mu.Unlock()
+	_, err := bfwa.connect()
+	return err
+}
+
+// connect caller holds mu
+func (bfwa *BinanceFuturesWsApi) connect() (*websocket.Conn, error) {
+	if bfwa.connection != nil {
+		return bfwa.connection, nil
+	}
+	connection, _, err := websocket.DefaultDialer.Dial(bfwa.BaseUrl, nil)
+	if err != nil {
+		bfwa.Logger.Error("Default Dialer, had an error during initial dial, ", err)
+		return nil, err
+	}
+	// Binance pings every few minutes, the default handler answers with a pong
+	connection.SetPingHandler(nil)
+	if bfwa.pending == nil {
+		bfwa.pending = make(map[int]chan models.WsApiResponse)
+	}
+	bfwa.connection = connection
+	go bfwa.read(connection)
+	return connection, nil
+}
+
+// read hands responses to their waiting requests until the connection fails
+func (bfwa *BinanceFuturesWsApi) read(connection *websocket.Conn) {
+	for {
+		_, message, err := connection.ReadMessage()
+		if err != nil {
+			bfwa.disconnected(connection, err)
+			return
+		}
+		response := models.WsApiResponse{}
+		if err := json.Unmarshal(message, &response); err != nil {
+			bfwa.Logger.Warn("unexpected websocket api message ", string(message))
+			continue
+		}
+		bfwa.mu.Lock()
+		if len(response.RateLimits) > 0 {
+			bfwa.rateLimits = response.RateLimits
+		}
+		waiter, exists := bfwa.pending[response.Id]
+		delete(bfwa.pending, response.Id)
+		bfwa.mu.Unlock()
+		if exists {
+			waiter <- response
+		}
+	}
+}
+
+// disconnected fails every waiting request, their status is unknown
+func (bfwa *BinanceFuturesWsApi) disconnected(connection *websocket.Conn, err error) {
+	bfwa.mu.Lock()
+	defer bfwa.mu.Unlock()
+	if bfwa.connection != connection {
+		return
+	}
+	if err != ErrConnectionClosed {
+		bfwa.Logger.Warn("websocket api connection lost: ", err)
+	}
+	_ = connection.Close()
+	bfwa.connection = nil
+	for id, waiter := range bfwa.pending {
+		close(waiter)
+		delete(bfwa.pending, id)
+	}
+}
+
+// RateLimits usage reported by the latest response, request weight and order counts.
+func (bfwa *BinanceFuturesWsApi) RateLimits() []models.RateLimit {
+	bfwa.mu.Lock()
+	defer bfwa.mu.Unlock()
+	rateLimits := make([]models.RateLimit, len(bfwa.rateLimits))
+	copy(rateLimits, bfwa.rateLimits)
+	return rateLimits
+}
+
+// Close closes the connection, waiting requests fail with ErrWsApiDisconnected.
+func (bfwa *BinanceFuturesWsApi) Close() error {
+	bfwa.mu.Lock()
+	connection := bfwa.connection
+	bfwa.mu.Unlock()
+	if connection != nil {
+		bfwa.disconnected(connection, ErrConnectionClosed)
+	}
+	return nil
+}
+
+// signParameters adds apiKey and timestamp, the signature covers every other parameter sorted by name
+func (bfwa *BinanceFuturesWsApi) signParameters(parameters url.Values) string {
+	parameters.Set("apiKey", bfwa.PublicKey)
+	parameters.Set("timestamp", requestTimestamp())
+	signature, _ := sha256Signature(bfwa.SecretKey, parameters.Encode())
+	return signature
+}
+
+func (bfwa *BinanceFuturesWsApi) doSignedRequest(method string, parameters url.Values) ([]byte, error) {
+	signature := bfwa.signParameters(parameters)
+	params := make(map[string]string, len(parameters)+1)
+	for key := range parameters {
+		params[key] = parameters.Get(key)
+	}
+	params["signature"] = signature
+
+	bfwa.mu.Lock()
+	connection, err := bfwa.connect()
+	if err != nil {
+		bfwa.mu.Unlock()
+		return nil, err
+	}
+	bfwa.idCounter++
+	id := bfwa.idCounter
+	waiter := make(chan models.WsApiResponse, 1)
+	bfwa.pending[id] = waiter
+	bfwa.mu.Unlock()
+
+	// gorilla/websocket supports a single concurrent writer
+	bfwa.writeMu.Lock()
+	err = connection.WriteJSON(models.WsApiRequest{Id: id, Method: method, Params: params})
+	bfwa.writeMu.Unlock()
+	if err != nil {
+		bfwa.forget(id)
+		bfwa.Logger.Error("Connectivity error while sending ", method, " ", err)
+		return nil, err
+	}
+
+	var response models.WsApiResponse
+	select {
+	case received, ok := <-waiter:
+		if !ok {
+			return nil, ErrWsApiDisconnected
+		}
+		response = received
+	case <-time.After(bfwa.Timeout):
+		bfwa.forget(id)
+		bfwa.Logger.Error(method, " ", ErrWsApiTimeout)
+		return nil, ErrWsApiTimeout
+	}
+	// Log rate limit for debug purposes, rejected requests report it as well
+	bfwa.Logger.Println(method+", rate limits used: ", response.RateLimits)
+
+	if response.Status != 200 || response.Error != nil {
+		bem := BinanceErrorMessage{}
+		if response.Error != nil {
+			bem = BinanceErrorMessage{Code: response.Error.Code, Message: response.Error.Message}
+		}
+		err = &RequestError{
+			StatusCode: response.Status,
+			UrlUsed:    bfwa.BaseUrl + " " + method,
+			Message:    bem,
+		}
+		bfwa.Logger.Error(method, err.Error())
+		return nil, err
+	}
+	return response.Result, nil
+}
+
+func (bfwa *BinanceFuturesWsApi) forget(id int) {
+	bfwa.mu.Lock()
+	defer bfwa.mu.Unlock()
+	delete(bfwa.pending, id)
+}
+
+// placeOrder same OrderMode and ClientOrderIds handling as the REST placeOrder
+func (bfwa *BinanceFuturesWsApi) placeOrder(parameters url.Values) ([]byte, error) {
+	if bfwa.ClientOrderIds != nil && parameters.Get("newClientOrderId") == "" {
+		parameters.Set("newClientOrderId", bfwa.ClientOrderIds.Next())
+	}
+	if !bfwa.isLive() {
+		signature := bfwa.signParameters(parameters)
+		bfwa.Logger.Info(bfwa.OrderMode, " order ", parameters.Encode(), " signature ", signature)
+		return syntheticOrderResponse(parameters)
+	}
+	send := func(parameters url.Values) ([]byte, error) {
+		return bfwa.doSignedRequest(WsApiMethodOrderPlace, parameters)
+	}
+	if bfwa.ClientOrderIds == nil {
+		return send(parameters)
+	}
+	query := func(symbol, clientOrderId string) ([]byte, error) {
+		return bfwa.QueryOrder(symbol, clientOrderId, 0)
+	}
+	return placeIdempotent(bfwa.Logger, parameters, send, query)
+}
+
+func (bfwa *BinanceFuturesWsApi) PlaceLimitOrder(symbol, side string, price, qty float64, reduceOnly bool) ([]byte, error) {
+	return bfwa.placeOrder(limitOrderParameters(symbol, side, GoodTillCancel, price, qty, reduceOnly))
+}
+
+func (bfwa *BinanceFuturesWsApi) PlacePostOnlyLimitOrder(symbol, side string, price, qty float64, reduceOnly bool) ([]byte, error) {
+	return bfwa.placeOrder(limitOrderParameters(symbol, side, GoodTillCrossing, price, qty, reduceOnly))
+}
+
+func (bfwa *BinanceFuturesWsApi) PlaceMarketOrder(symbol, side string, qty float64, reduceOnly bool) ([]byte, error) {
+	return bfwa.placeOrder(marketOrderParameters(symbol, side, qty, reduceOnly))
+}
+
+// PlaceStopMarketOrder see BinanceFuturesApi.PlaceStopMarketOrder
+func (bfwa *BinanceFuturesWsApi) PlaceStopMarketOrder(symbol, side string, stopPrice, qty float64) ([]byte, error) {
+	return bfwa.placeOrder(triggerOrderParameters(symbol, side, OrderTypeStopMarket, stopPrice, qty))
+}
+
+// PlaceTakeProfitMarketOrder reduce only, use the opposite side of your position.
+func (bfwa *BinanceFuturesWsApi) PlaceTakeProfitMarketOrder(symbol, side string, stopPrice, qty float64) ([]byte, error) {
+	return bfwa.placeOrder(triggerOrderParameters(symbol, side, OrderTypeTakeProfitMarket, stopPrice, qty))
+}
+
+// PlaceClosePositionOrder orderType is either STOP_MARKET or TAKE_PROFIT_MARKET.
+func (bfwa *BinanceFuturesWsApi) PlaceClosePositionOrder(symbol, side, orderType string, stopPrice float64) ([]byte, error) {
+	return bfwa.placeOrder(closePositionParameters(symbol, side, orderType, stopPrice))
+}
+
+// QueryOrder either orderId or origClientOrderId must be sent, pass 0 or empty string to skip one.
+// It is sent in every OrderMode, orders of test and dry run modes are unknown to binance.
+func (bfwa *BinanceFuturesWsApi) QueryOrder(symbol, origClientOrderId string, orderId int64) ([]byte, error) {
+	return bfwa.doSignedRequest(WsApiMethodOrderStatus, orderReferenceParameters(symbol, origClientOrderId, orderId))
+}
+
+// ModifyOrder see BinanceFuturesApi.ModifyOrder
+func (bfwa *BinanceFuturesWsApi) ModifyOrder(symbol, side, origClientOrderId string, orderId int64, price, qty float64) ([]byte, error) {
+	if !bfwa.isLive() {
+		bfwa.Logger.Info(bfwa.OrderMode, " modify ", symbol, " ", origClientOrderId, " ", orderId)
+		return syntheticModifyResponse(symbol, side, origClientOrderId, orderId, price, qty)
+	}
+	return bfwa.doSignedRequest(WsApiMethodOrderModify, modifyOrderParameters(symbol, side, origClientOrderId, orderId, price, qty))
+}
+
+// CancelSingleOrder either orderId or origClientOrderId must be sent, test and dry run modes
+// return a synthetic CANCELED response.
+func (bfwa *BinanceFuturesWsApi) CancelSingleOrder(symbol, origClientOrderId string, orderId int64) ([]byte, error) {
+	if !bfwa.isLive() {
+		bfwa.Logger.Info(bfwa.OrderMode, " cancel ", symbol, " ", origClientOrderId, " ", orderId)
+		return syntheticCancelResponse(symbol, origClientOrderId, orderId)
+	}
+	return bfwa.doSignedRequest(WsApiMethodOrderCancel, orderReferenceParameters(symbol, origClientOrderId, orderId))
+}
+
+func (bfwa *BinanceFuturesWsApi) GetAccountBalance() ([]byte, error) {
+	return bfwa.doSignedRequest(WsApiMethodAccountBalance, url.Values{})
+}
+
+func (bfwa *BinanceFuturesWsApi) GetAccountInformation() ([]byte, error) {
+	return bfwa.doSignedRequest(WsApiMethodAccountStatus, url.Values{})
+}
+
+// GetPositionInformation empty symbol returns positions of every symbol
+func (bfwa *BinanceFuturesWsApi) GetPositionInformation(symbol string) ([]byte, error) {
+	parameters := url.Values{}
+	if symbol != "" {
+		parameters.Add("symbol", symbol)
+	}
+	return bfwa.doSignedRequest(WsApiMethodAccountPosition, parameters)
+}